@@ -0,0 +1,63 @@
+package async
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+)
+
+// PanicError is returned in place of a recovered panic
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (pe *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", pe.Value)
+}
+
+var (
+	hookLock  sync.RWMutex
+	panicHook func(pe *PanicError)
+)
+
+// SetPanicHook registers a function called for every recovered panic,
+// usually to increment a metric or report to an error tracker
+func SetPanicHook(fn func(pe *PanicError)) {
+	hookLock.Lock()
+	defer hookLock.Unlock()
+	panicHook = fn
+}
+
+// Safe runs fn in the current goroutine turning a panic into a *PanicError
+func Safe(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe := &PanicError{Value: r, Stack: debug.Stack()}
+			log.Printf("Recovered %s\n%s", pe.Error(), pe.Stack)
+
+			hookLock.RLock()
+			hook := panicHook
+			hookLock.RUnlock()
+			if hook != nil {
+				hook(pe)
+			}
+
+			err = pe
+		}
+	}()
+
+	return fn()
+}
+
+// Go runs fn in a new goroutine with panic recovery. The returned channel
+// receives the result of fn (or the *PanicError) and is closed afterwards.
+func Go(fn func() error) <-chan error {
+	result := make(chan error, 1)
+	go func() {
+		defer close(result)
+		result <- Safe(fn)
+	}()
+	return result
+}
@@ -0,0 +1,114 @@
+package async
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+)
+
+type RestartPolicy int
+
+// DEFAULT_RESTART_BACKOFF is the wait between restarts of a Spec without Backoff, so a
+// function that returns at once doesn't spin
+const DEFAULT_RESTART_BACKOFF = time.Second
+
+const (
+	// RestartNever runs the function once
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts the function when it returns an error or panics
+	RestartOnFailure
+	// RestartAlways restarts the function whenever it returns, until the group is canceled
+	RestartAlways
+)
+
+type Spec struct {
+	Name        string
+	Policy      RestartPolicy
+	MaxRestarts int           // 0 means unlimited
+	Backoff     time.Duration // wait between restarts, DEFAULT_RESTART_BACKOFF when 0
+}
+
+// Group is an errgroup with panic recovery: the first error cancels the
+// context of the group and is returned by Wait
+type Group struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	clock   clock.Clock
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel, clock: clock.New()}, ctx
+}
+
+// SetClock replaces the Clock used for restart backoff, mainly for tests
+func (g *Group) SetClock(c clock.Clock) {
+	g.clock = c
+}
+
+// Go runs fn once in the group
+func (g *Group) Go(fn func(ctx context.Context) error) {
+	g.Supervise(Spec{Policy: RestartNever}, fn)
+}
+
+// Supervise runs fn in the group restarting it according to the Spec. When the
+// restarts are exhausted the last error fails the group.
+func (g *Group) Supervise(spec Spec, fn func(ctx context.Context) error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		backoff := spec.Backoff
+		if backoff <= 0 {
+			backoff = DEFAULT_RESTART_BACKOFF
+		}
+
+		restarts := 0
+		for {
+			err := Safe(func() error { return fn(g.ctx) })
+
+			if g.ctx.Err() != nil {
+				return
+			}
+
+			restart := spec.Policy == RestartAlways || (spec.Policy == RestartOnFailure && err != nil)
+			if !restart || (spec.MaxRestarts > 0 && restarts >= spec.MaxRestarts) {
+				if err != nil {
+					g.fail(err)
+				}
+				return
+			}
+
+			restarts++
+			if err != nil {
+				log.Printf("Restarting %s (%d) after error: %s", spec.Name, restarts, err.Error())
+			}
+
+			select {
+			case <-g.clock.After(backoff):
+			case <-g.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until every function returns and returns the first error
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
+
+func (g *Group) fail(err error) {
+	g.errOnce.Do(func() {
+		g.err = err
+		g.cancel()
+	})
+}
@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/config"
+	"github.com/faelp22/go-commons-libs/core/redact"
+)
+
+const (
+	DEFAULT_AUDIT_BUFFER_SIZE    = 100
+	DEFAULT_AUDIT_FLUSH_INTERVAL = 5 // seconds
+
+	// maxPendingBuffers bounds, in buffers, the events kept for a failing sink
+	maxPendingBuffers = 10
+)
+
+// Event is a single audit record describing who did what to which resource
+type Event struct {
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Actor     string            `json:"actor"`
+	Action    string            `json:"action"`
+	Resource  string            `json:"resource"`
+	Before    json.RawMessage   `json:"before,omitempty"`
+	After     json.RawMessage   `json:"after,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// Snapshot encodes v for Event.Before and Event.After with its personal data redacted
+// by the default Redactor, following the redact struct tags
+func Snapshot(ctx context.Context, v interface{}) (json.RawMessage, error) {
+	return redact.JSON(ctx, v)
+}
+
+// Sink persists a batch of audit events somewhere (database, broker, file...)
+type Sink interface {
+	Write(ctx context.Context, events []Event) error
+}
+
+type AuditInterface interface {
+	// Record buffers an event, filling ID and Timestamp when empty.
+	// The buffer is flushed when it is full or on each flush interval.
+	Record(ev Event)
+	// Flush writes every buffered event to all sinks. The events a sink fails to
+	// write are kept for it and retried on the next Flush.
+	Flush(ctx context.Context) error
+	// Close stops the background flusher and flushes what is still buffered.
+	// It must be called on shutdown to guarantee no event is lost, events
+	// recorded after Close are dropped and logged.
+	Close(ctx context.Context) error
+}
+
+type auditor struct {
+	sinks      []Sink
+	clock      clock.Clock
+	buffer     []Event
+	pending    [][]Event // events not written yet, by sink
+	bufferSize int
+	modifyLock sync.Mutex
+	flushLock  sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+	closeOnce  sync.Once
+	closed     atomic.Bool
+}
+
+func New(conf *config.Config, sinks ...Sink) AuditInterface {
+	return NewWithClock(conf, clock.New(), sinks...)
+}
+
+// NewWithClock is like New but drives the flush interval and event timestamps with the given Clock
+func NewWithClock(conf *config.Config, c clock.Clock, sinks ...Sink) AuditInterface {
+	if conf.AuditConfig == nil {
+		conf.AuditConfig = &config.AuditConfig{}
+	}
+
+	SRV_AUDIT_BUFFER_SIZE := os.Getenv("SRV_AUDIT_BUFFER_SIZE")
+	if SRV_AUDIT_BUFFER_SIZE != "" {
+		conf.AUDIT_BUFFER_SIZE, _ = strconv.Atoi(SRV_AUDIT_BUFFER_SIZE)
+	}
+	if conf.AUDIT_BUFFER_SIZE <= 0 {
+		conf.AUDIT_BUFFER_SIZE = DEFAULT_AUDIT_BUFFER_SIZE
+	}
+
+	SRV_AUDIT_FLUSH_INTERVAL := os.Getenv("SRV_AUDIT_FLUSH_INTERVAL")
+	if SRV_AUDIT_FLUSH_INTERVAL != "" {
+		conf.AUDIT_FLUSH_INTERVAL, _ = strconv.Atoi(SRV_AUDIT_FLUSH_INTERVAL)
+	}
+	if conf.AUDIT_FLUSH_INTERVAL <= 0 {
+		conf.AUDIT_FLUSH_INTERVAL = DEFAULT_AUDIT_FLUSH_INTERVAL
+	}
+
+	a := &auditor{
+		sinks:      sinks,
+		clock:      c,
+		bufferSize: conf.AUDIT_BUFFER_SIZE,
+		buffer:     make([]Event, 0, conf.AUDIT_BUFFER_SIZE),
+		pending:    make([][]Event, len(sinks)),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	go a.run(time.Duration(conf.AUDIT_FLUSH_INTERVAL) * time.Second)
+
+	return a
+}
+
+func (a *auditor) run(interval time.Duration) {
+	ticker := a.clock.NewTicker(interval)
+	defer ticker.Stop()
+	defer close(a.done)
+
+	for {
+		select {
+		case <-ticker.C():
+			if err := a.Flush(context.Background()); err != nil {
+				log.Println("Erro to flush audit events:", err.Error())
+			}
+		case <-a.stop:
+			return
+		}
+	}
+}
+
+func (a *auditor) Record(ev Event) {
+	if ev.ID == "" {
+		ev.ID = newID()
+	}
+	if ev.Timestamp.IsZero() {
+		ev.Timestamp = a.clock.Now().UTC()
+	}
+
+	// checked under the lock Close takes before its final Flush, so an accepted event
+	// is always flushed
+	a.modifyLock.Lock()
+	if a.closed.Load() {
+		a.modifyLock.Unlock()
+		log.Println("Erro to record audit event, auditor closed, dropping event", ev.ID, ev.Action, ev.Resource)
+		return
+	}
+	a.buffer = append(a.buffer, ev)
+	full := len(a.buffer) >= a.bufferSize
+	a.modifyLock.Unlock()
+
+	if full {
+		async.Go(func() error {
+			if err := a.Flush(context.Background()); err != nil {
+				log.Println("Erro to flush audit events:", err.Error())
+			}
+			return nil
+		})
+	}
+}
+
+func (a *auditor) Flush(ctx context.Context) error {
+	a.flushLock.Lock()
+	defer a.flushLock.Unlock()
+
+	a.modifyLock.Lock()
+	events := a.buffer
+	a.buffer = make([]Event, 0, a.bufferSize)
+	a.modifyLock.Unlock()
+
+	var errs []error
+	for i, sink := range a.sinks {
+		batch := append(a.pending[i], events...)
+		if len(batch) == 0 {
+			continue
+		}
+
+		if err := sink.Write(ctx, batch); err != nil {
+			errs = append(errs, err)
+			// kept for the next Flush, up to a bound so a sink down for long can't exhaust memory
+			if limit := a.bufferSize * maxPendingBuffers; len(batch) > limit {
+				log.Printf("Erro to write audit events, dropping %d events of sink %d", len(batch)-limit, i)
+				batch = batch[len(batch)-limit:]
+			}
+			a.pending[i] = batch
+			continue
+		}
+		a.pending[i] = nil
+	}
+
+	return errors.Join(errs...)
+}
+
+func (a *auditor) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() {
+		a.modifyLock.Lock()
+		a.closed.Store(true)
+		a.modifyLock.Unlock()
+		close(a.stop)
+		<-a.done
+	})
+
+	return a.Flush(ctx)
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 10)
+	}
+	return hex.EncodeToString(b)
+}
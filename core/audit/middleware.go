@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/faelp22/go-commons-libs/core/redact"
+	"github.com/gorilla/mux"
+)
+
+const maxCapturedBody = 64 << 10 // 64KB
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	sr.status = code
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// Middleware records an audit event for every mutating request (POST, PUT, PATCH and DELETE).
+// The actor is resolved by the actor function, usually from an authentication header or context.
+// JSON request bodies up to 64KB are stored as the After state of the event, redacted by
+// the paths of the types registered with redact.Register.
+func Middleware(a AuditInterface, actor func(r *http.Request) string) mux.MiddlewareFunc {
+	return MiddlewareRedacting(a, actor, nil)
+}
+
+// MiddlewareRedacting is Middleware redacting fields of the request bodies with the
+// default Redactor, ex: {"customer.document": redact.Hash, "password": redact.Drop}
+func MiddlewareRedacting(a AuditInterface, actor func(r *http.Request) string, fields map[string]redact.Strategy) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+			default:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var body []byte
+			if r.Body != nil && r.ContentLength <= maxCapturedBody {
+				// chunked bodies (ContentLength -1) may be larger, the handler still gets all of it
+				body, _ = io.ReadAll(io.LimitReader(r.Body, maxCapturedBody))
+				r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			ev := Event{
+				Action:   r.Method,
+				Resource: r.URL.Path,
+				Metadata: map[string]string{
+					"status":      strconv.Itoa(rec.status),
+					"remote_addr": r.RemoteAddr,
+				},
+			}
+
+			if actor != nil {
+				ev.Actor = actor(r)
+			}
+
+			if len(body) > 0 && json.Valid(body) {
+				ev.After = redactBody(r.Context(), body, fields)
+			}
+
+			a.Record(ev)
+		})
+	}
+}
+
+// redactBody returns body with fields and the paths registered with redact.Register
+// redacted, bodies that can't be redacted aren't stored
+func redactBody(ctx context.Context, body []byte, fields map[string]redact.Strategy) json.RawMessage {
+	if len(fields) == 0 && !redact.Default().Registered() {
+		return json.RawMessage(body)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil
+	}
+	if err := redact.Default().Fields(ctx, doc, fields); err != nil {
+		return nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+// PGSQLSink writes audit events into a Postgres table with the columns
+// (id, ts, actor, action, resource, before, after, metadata)
+type PGSQLSink struct {
+	DB    *sql.DB
+	Table string
+}
+
+func (s *PGSQLSink) Write(ctx context.Context, events []Event) error {
+	table := s.Table
+	if table == "" {
+		table = "audit_events"
+	}
+
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		log.Println("Erro to begin audit transaction in PGSQL")
+		return err
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, ts, actor, action, resource, before, after, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`, table)
+
+	for _, ev := range events {
+		metadata, err := json.Marshal(ev.Metadata)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, query,
+			ev.ID, ev.Timestamp, ev.Actor, ev.Action, ev.Resource,
+			nullJSON(ev.Before), nullJSON(ev.After), metadata,
+		); err != nil {
+			log.Println("Erro to insert audit event in PGSQL")
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func nullJSON(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	return []byte(raw)
+}
+
+// RabbitMQSink publishes every audit event as a JSON message
+type RabbitMQSink struct {
+	Rabbit   rabbitmq.RabbitInterface
+	Producer *rabbitmq.ProducerConfig
+}
+
+func (s *RabbitMQSink) Write(ctx context.Context, events []Event) error {
+	var errs []error
+	for _, ev := range events {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		msg := &rabbitmq.Message{
+			Data:        data,
+			ContentType: "application/json",
+		}
+
+		if err := s.Rabbit.Producer(ctx, s.Producer, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+// Package bandwidth limits the throughput of readers and writers with token buckets,
+// so batch transfers don't saturate the network of a pod. A transfer can share a
+// global Limiter with other transfers and have its own:
+//
+//	global := bandwidth.NewLimiter(50 << 20) // 50MB/s for the process
+//	r = bandwidth.NewReader(ctx, r, global, bandwidth.NewLimiter(10<<20))
+package bandwidth
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+)
+
+// Limiter is a token bucket of bytes per second. The burst is one second of traffic.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	clock  clock.Clock
+}
+
+// NewLimiter returns a Limiter of bytesPerSec, <= 0 is unlimited
+func NewLimiter(bytesPerSec int64) *Limiter {
+	l := &Limiter{clock: clock.New()}
+	l.SetRate(bytesPerSec)
+	return l
+}
+
+func (l *Limiter) SetClock(c clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = c
+	l.last = time.Time{}
+}
+
+// SetRate changes the rate at runtime
+func (l *Limiter) SetRate(bytesPerSec int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = float64(bytesPerSec)
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+}
+
+// burst is the biggest amount a single Wait may ask for
+func (l *Limiter) burst() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+	if l.rate < 1 {
+		return 1
+	}
+	return int(l.rate)
+}
+
+// Wait blocks until n bytes can pass or ctx is done. n must not exceed the burst.
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if l.rate <= 0 {
+		l.mu.Unlock()
+		return nil
+	}
+
+	now := l.clock.Now()
+	if l.last.IsZero() {
+		l.tokens = l.rate
+	} else {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.rate {
+			l.tokens = l.rate
+		}
+	}
+	l.last = now
+
+	// tokens go negative, reserving them for this caller
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	rate := l.rate
+	c := l.clock
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	select {
+	case <-c.After(time.Duration(deficit / rate * float64(time.Second))):
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens += float64(n)
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// chunk is the biggest read or write allowed by every limiter
+func chunk(limiters []*Limiter, n int) int {
+	for _, l := range limiters {
+		if b := l.burst(); b > 0 && b < n {
+			n = b
+		}
+	}
+	return n
+}
+
+func wait(ctx context.Context, limiters []*Limiter, n int) error {
+	for _, l := range limiters {
+		if err := l.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type reader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*Limiter
+}
+
+// NewReader returns a Reader throttled by every limiter. Reads fail once ctx is done.
+func NewReader(ctx context.Context, r io.Reader, limiters ...*Limiter) io.Reader {
+	return &reader{ctx: ctx, r: r, limiters: limiters}
+}
+
+func (lr *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return lr.r.Read(p)
+	}
+
+	n, err := lr.r.Read(p[:chunk(lr.limiters, len(p))])
+	if n > 0 {
+		if werr := wait(lr.ctx, lr.limiters, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	ctx      context.Context
+	w        io.Writer
+	limiters []*Limiter
+}
+
+// NewWriter returns a Writer throttled by every limiter. Writes fail once ctx is done.
+func NewWriter(ctx context.Context, w io.Writer, limiters ...*Limiter) io.Writer {
+	return &writer{ctx: ctx, w: w, limiters: limiters}
+}
+
+func (lw *writer) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := chunk(lw.limiters, len(p))
+		if err := wait(lw.ctx, lw.limiters, n); err != nil {
+			return written, err
+		}
+
+		n, err := lw.w.Write(p[:n])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
@@ -0,0 +1,103 @@
+// Package brdoc validates, normalizes and formats Brazilian documents and contacts:
+// CPF, CNPJ (numeric and the alphanumeric format), CEP and phones in E.164.
+//
+//	brdoc.ValidCPF("529.982.247-25")    // true
+//	brdoc.FormatCNPJ("11222333000181")  // "11.222.333/0001-81"
+//	brdoc.NormalizePhone("(11) 98765-4321") // "+5511987654321", nil
+package brdoc
+
+import (
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Clean removes the punctuation of a document, keeping digits and letters upper cased
+func Clean(doc string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(doc) {
+		if (r >= '0' && r <= '9') || (r >= 'A' && r <= 'Z') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func onlyDigits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ValidCPF checks the length and the check digits of a CPF, with or without punctuation
+func ValidCPF(cpf string) bool {
+	cpf = Clean(cpf)
+	if len(cpf) != 11 || onlyDigits(cpf) != cpf || repeated(cpf) {
+		return false
+	}
+	return checkDigit(cpf[:9], 10) == cpf[9] && checkDigit(cpf[:10], 11) == cpf[10]
+}
+
+// FormatCPF returns 000.000.000-00 or an error of kind Invalid
+func FormatCPF(cpf string) (string, error) {
+	if !ValidCPF(cpf) {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid CPF")
+	}
+	cpf = Clean(cpf)
+	return cpf[:3] + "." + cpf[3:6] + "." + cpf[6:9] + "-" + cpf[9:], nil
+}
+
+// ValidCNPJ checks the length and the check digits of a CNPJ. The root and branch may
+// be alphanumeric (format adopted in 2026), the check digits are always numeric.
+func ValidCNPJ(cnpj string) bool {
+	cnpj = Clean(cnpj)
+	if len(cnpj) != 14 || onlyDigits(cnpj[12:]) != cnpj[12:] || repeated(cnpj) {
+		return false
+	}
+
+	weights := []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	return cnpjDigit(cnpj[:12], weights[1:]) == cnpj[12] && cnpjDigit(cnpj[:13], weights) == cnpj[13]
+}
+
+// FormatCNPJ returns 00.000.000/0000-00 or an error of kind Invalid
+func FormatCNPJ(cnpj string) (string, error) {
+	if !ValidCNPJ(cnpj) {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid CNPJ")
+	}
+	cnpj = Clean(cnpj)
+	return cnpj[:2] + "." + cnpj[2:5] + "." + cnpj[5:8] + "/" + cnpj[8:12] + "-" + cnpj[12:], nil
+}
+
+// checkDigit is the CPF modulo 11 digit of digits, with weights from weight down to 2
+func checkDigit(digits string, weight int) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		sum += int(digits[i]-'0') * (weight - i)
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+// cnpjDigit computes a CNPJ check digit, letters are worth their ASCII code minus 48
+func cnpjDigit(chars string, weights []int) byte {
+	sum := 0
+	for i := 0; i < len(chars); i++ {
+		sum += int(chars[i]-'0') * weights[i]
+	}
+	rest := sum % 11
+	if rest < 2 {
+		return '0'
+	}
+	return byte('0' + 11 - rest)
+}
+
+func repeated(s string) bool {
+	return strings.Count(s, s[:1]) == len(s)
+}
@@ -0,0 +1,174 @@
+package brdoc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	COUNTRY_CODE       = "55"
+	DEFAULT_VIACEP_URL = "https://viacep.com.br/ws"
+)
+
+// valid area codes (DDD)
+var ddds = map[string]bool{
+	"11": true, "12": true, "13": true, "14": true, "15": true, "16": true, "17": true, "18": true, "19": true,
+	"21": true, "22": true, "24": true, "27": true, "28": true,
+	"31": true, "32": true, "33": true, "34": true, "35": true, "37": true, "38": true,
+	"41": true, "42": true, "43": true, "44": true, "45": true, "46": true, "47": true, "48": true, "49": true,
+	"51": true, "53": true, "54": true, "55": true,
+	"61": true, "62": true, "63": true, "64": true, "65": true, "66": true, "67": true, "68": true, "69": true,
+	"71": true, "73": true, "74": true, "75": true, "77": true, "79": true,
+	"81": true, "82": true, "83": true, "84": true, "85": true, "86": true, "87": true, "88": true, "89": true,
+	"91": true, "92": true, "93": true, "94": true, "95": true, "96": true, "97": true, "98": true, "99": true,
+}
+
+// NormalizePhone returns a Brazilian phone in E.164, ex: "+5511987654321". It accepts
+// punctuation, the country code, a leading trunk 0 and carrier codes ("0 21 11 ...").
+func NormalizePhone(phone string) (string, error) {
+	digits := onlyDigits(phone)
+
+	switch {
+	case strings.HasPrefix(strings.TrimSpace(phone), "+"):
+		if !strings.HasPrefix(digits, COUNTRY_CODE) {
+			return "", cerrors.New(cerrors.Invalid, "brdoc: not a Brazilian phone")
+		}
+		digits = digits[len(COUNTRY_CODE):]
+	case strings.HasPrefix(digits, "0"):
+		digits = digits[1:]
+		if len(digits) == 12 || len(digits) == 13 {
+			// carrier code + DDD + number
+			digits = digits[2:]
+		}
+	case (len(digits) == 12 || len(digits) == 13) && strings.HasPrefix(digits, COUNTRY_CODE):
+		digits = digits[len(COUNTRY_CODE):]
+	}
+
+	if len(digits) != 10 && len(digits) != 11 {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid phone length")
+	}
+	if !ddds[digits[:2]] {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid area code "+digits[:2])
+	}
+	// mobiles have 9 digits starting with 9, landlines 8 digits starting with 2 to 5
+	number := digits[2:]
+	if (len(number) == 9 && number[0] != '9') || (len(number) == 8 && (number[0] < '2' || number[0] > '5')) {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid phone number")
+	}
+
+	return "+" + COUNTRY_CODE + digits, nil
+}
+
+// IsMobile reports whether an E.164 Brazilian phone is a mobile number
+func IsMobile(e164 string) bool {
+	return len(e164) == 14 && e164[5] == '9'
+}
+
+// FormatPhone returns an E.164 Brazilian phone as "(11) 98765-4321"
+func FormatPhone(e164 string) (string, error) {
+	normalized, err := NormalizePhone(e164)
+	if err != nil {
+		return "", err
+	}
+	d := normalized[3:]
+	return "(" + d[:2] + ") " + d[2:len(d)-4] + "-" + d[len(d)-4:], nil
+}
+
+// ValidCEP checks that a CEP has 8 digits
+func ValidCEP(cep string) bool {
+	cep = onlyDigits(cep)
+	return len(cep) == 8 && !repeated(cep)
+}
+
+// FormatCEP returns 00000-000 or an error of kind Invalid
+func FormatCEP(cep string) (string, error) {
+	if !ValidCEP(cep) {
+		return "", cerrors.New(cerrors.Invalid, "brdoc: invalid CEP")
+	}
+	cep = onlyDigits(cep)
+	return cep[:5] + "-" + cep[5:], nil
+}
+
+type Address struct {
+	CEP          string `json:"cep"`
+	Street       string `json:"street"`
+	Complement   string `json:"complement,omitempty"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"` // UF, ex: "SP"
+	IBGE         string `json:"ibge,omitempty"`
+}
+
+// CEPClient resolves a CEP to its Address. Unknown CEPs return an error of kind NotFound.
+type CEPClient interface {
+	Lookup(ctx context.Context, cep string) (*Address, error)
+}
+
+type viacep struct {
+	baseURL string
+	http    *http.Client
+}
+
+// NewViaCEP returns a CEPClient using the ViaCEP API, at DEFAULT_VIACEP_URL when baseURL is empty
+func NewViaCEP(baseURL string) CEPClient {
+	if baseURL == "" {
+		baseURL = DEFAULT_VIACEP_URL
+	}
+	return &viacep{baseURL: strings.TrimSuffix(baseURL, "/"), http: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (vc *viacep) Lookup(ctx context.Context, cep string) (*Address, error) {
+	if !ValidCEP(cep) {
+		return nil, cerrors.New(cerrors.Invalid, "brdoc: invalid CEP")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, vc.baseURL+"/"+onlyDigits(cep)+"/json/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := vc.http.Do(req)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "brdoc.Lookup", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+		return nil, cerrors.New(cerrors.Unavailable, "brdoc: viacep returned "+resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, cerrors.New(cerrors.Invalid, "brdoc: viacep returned "+resp.Status)
+	}
+
+	var body struct {
+		CEP         string      `json:"cep"`
+		Logradouro  string      `json:"logradouro"`
+		Complemento string      `json:"complemento"`
+		Bairro      string      `json:"bairro"`
+		Localidade  string      `json:"localidade"`
+		UF          string      `json:"uf"`
+		IBGE        string      `json:"ibge"`
+		Erro        interface{} `json:"erro"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "brdoc.Lookup", err)
+	}
+	if body.Erro != nil {
+		return nil, cerrors.New(cerrors.NotFound, "brdoc: CEP not found "+cep)
+	}
+
+	return &Address{
+		CEP:          body.CEP,
+		Street:       body.Logradouro,
+		Complement:   body.Complemento,
+		Neighborhood: body.Bairro,
+		City:         body.Localidade,
+		State:        body.UF,
+		IBGE:         body.IBGE,
+	}, nil
+}
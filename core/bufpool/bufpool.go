@@ -0,0 +1,49 @@
+// Package bufpool provides sync.Pool backed buffers for the encoding and
+// transfer paths, cutting per operation allocations in high throughput services.
+package bufpool
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"sync"
+)
+
+// MAX_POOLED_SIZE keeps unusually large buffers out of the pool so a single
+// big payload doesn't pin its memory forever
+const MAX_POOLED_SIZE = 4 << 20 // 4MB
+
+var buffers = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// Get returns an empty buffer from the pool
+func Get() *bytes.Buffer {
+	return buffers.Get().(*bytes.Buffer)
+}
+
+// Put resets b and returns it to the pool. b must not be used afterwards.
+func Put(b *bytes.Buffer) {
+	if b == nil || b.Cap() > MAX_POOLED_SIZE {
+		return
+	}
+	b.Reset()
+	buffers.Put(b)
+}
+
+var gzipWriters = sync.Pool{
+	New: func() interface{} { return gzip.NewWriter(io.Discard) },
+}
+
+// GetGzipWriter returns a pooled gzip.Writer writing to w
+func GetGzipWriter(w io.Writer) *gzip.Writer {
+	gz := gzipWriters.Get().(*gzip.Writer)
+	gz.Reset(w)
+	return gz
+}
+
+// PutGzipWriter returns gz to the pool, it must already be closed
+func PutGzipWriter(gz *gzip.Writer) {
+	gz.Reset(io.Discard)
+	gzipWriters.Put(gz)
+}
@@ -0,0 +1,39 @@
+package clock
+
+import "time"
+
+// Clock abstracts the time functions used by the library so time dependent
+// behavior (reconnect waits, flush intervals, schedulers) can be driven by a Fake in tests
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTicker(d time.Duration) Ticker
+	Sleep(d time.Duration)
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+type realClock struct{}
+
+// New returns a Clock backed by the time package
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{t: time.NewTicker(d)}
+}
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt *realTicker) C() <-chan time.Time { return rt.t.C }
+func (rt *realTicker) Stop()               { rt.t.Stop() }
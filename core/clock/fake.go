@@ -0,0 +1,136 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when Advance or Set is called
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*waiter
+}
+
+type waiter struct {
+	until  time.Time
+	period time.Duration
+	ch     chan time.Time
+}
+
+func NewFake(start time.Time) *Fake {
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.addWaiter(d, 0).ch
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	return &fakeTicker{f: f, w: f.addWaiter(d, d)}
+}
+
+// Advance moves the clock forward firing every timer and ticker that expires
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(f.now.Add(d))
+}
+
+// Set moves the clock to t firing every timer and ticker that expires
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.setLocked(t)
+}
+
+// BlockUntil waits until n goroutines are waiting on After, Sleep or a Ticker.
+// Use it before Advance to avoid racing with the code under test.
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+func (f *Fake) addWaiter(d, period time.Duration) *waiter {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w := &waiter{
+		until:  f.now.Add(d),
+		period: period,
+		ch:     make(chan time.Time, 1),
+	}
+
+	if d <= 0 && period == 0 {
+		w.ch <- f.now
+		return w
+	}
+
+	f.waiters = append(f.waiters, w)
+	f.cond.Broadcast()
+	return w
+}
+
+func (f *Fake) removeWaiter(w *waiter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, item := range f.waiters {
+		if item == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *Fake) setLocked(t time.Time) {
+	f.now = t
+
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.until.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+
+		select {
+		case w.ch <- t:
+		default: // like time.Ticker, slow receivers lose ticks
+		}
+
+		if w.period > 0 {
+			for !w.until.After(t) {
+				w.until = w.until.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (ft *fakeTicker) C() <-chan time.Time { return ft.w.ch }
+func (ft *fakeTicker) Stop()               { ft.f.removeWaiter(ft.w) }
@@ -13,10 +13,28 @@ type Config struct {
 	*RedisDBConfig
 	*PGSQLConfig
 	*RMQConfig
+	*AuditConfig
+	*FactoryConfig
+	*LifecycleConfig
+	*SchemaRegistryConfig
+	*CryptoConfig
+	*DeadlineConfig
+	*AzureConfig
+	*KeyVaultConfig
+	*CosmosConfig
+	*SearchConfig
+	*SSHConfig
+	*RedactConfig
 }
 
 type HttpConfig struct {
-	PORT string `json:"port"`
+	PORT                 string `json:"port"`
+	HTTP_CORS_ORIGINS    string `json:"http_cors_origins"`    // comma separated, "*" allows any
+	HTTP_MAX_BODY_SIZE   int64  `json:"http_max_body_size"`   // bytes
+	HTTP_REQUEST_TIMEOUT int    `json:"http_request_timeout"` // seconds
+	HTTP_IP_ALLOWLIST    string `json:"http_ip_allowlist"`    // comma separated IPs or CIDRs
+	HTTP_TRUSTED_PROXIES string `json:"http_trusted_proxies"` // comma separated IPs or CIDRs whose X-Forwarded-For is trusted
+	HTTP_MAINTENANCE     bool   `json:"http_maintenance"`
 }
 
 type MongoDBConfig struct {
@@ -45,9 +63,86 @@ type PGSQLConfig struct {
 	DB_SET_MAX_OPEN_CONNS     int    `json:"db_set_max_open_conns"`
 	DB_SET_MAX_IDLE_CONNS     int    `json:"db_set_max_idle_conns"`
 	DB_SET_CONN_MAX_LIFE_TIME int    `json:"db_set_conn_max_life_time"`
+	DB_REPLICA_HOSTS          string `json:"db_replica_hosts"`          // comma separated host[:port]
+	DB_REPLICA_MAX_LAG        int    `json:"db_replica_max_lag"`        // seconds
+	DB_REPLICA_CHECK_INTERVAL int    `json:"db_replica_check_interval"` // seconds
 }
 
 type RMQConfig struct {
 	RMQ_URI                  string `json:"rmq_uri"`
 	RMQ_MAXX_RECONNECT_TIMES int    `json:"rmq_maxx_reconnect_times"`
+	RMQ_MANAGEMENT_URL       string `json:"rmq_management_url"`
+	RMQ_MANAGEMENT_USER      string `json:"rmq_management_user"`
+	RMQ_MANAGEMENT_PASS      string `json:"-"`
+}
+
+type AuditConfig struct {
+	AUDIT_BUFFER_SIZE    int `json:"audit_buffer_size"`
+	AUDIT_FLUSH_INTERVAL int `json:"audit_flush_interval"`
+}
+
+type FactoryConfig struct {
+	FACTORY_STORAGE   string `json:"factory_storage"`
+	FACTORY_MESSAGING string `json:"factory_messaging"`
+	FACTORY_CACHE     string `json:"factory_cache"`
+}
+
+type LifecycleConfig struct {
+	SHUTDOWN_PHASE_TIMEOUT int  `json:"shutdown_phase_timeout"`
+	LAZY_CONNECT           bool `json:"lazy_connect"`
+}
+
+type SchemaRegistryConfig struct {
+	SCHEMA_REGISTRY_URL  string `json:"schema_registry_url"`
+	SCHEMA_REGISTRY_USER string `json:"schema_registry_user"`
+	SCHEMA_REGISTRY_PASS string `json:"-"`
+}
+
+type CryptoConfig struct {
+	CRYPTO_KEYS       string `json:"-"`                 // comma separated "id:base64key", 32 byte keys
+	CRYPTO_ACTIVE_KEY string `json:"crypto_active_key"` // id of the key used to encrypt, defaults to the first
+}
+
+type DeadlineConfig struct {
+	DEADLINE_PUBLISH  int `json:"deadline_publish"`  // seconds
+	DEADLINE_CONSUME  int `json:"deadline_consume"`  // seconds
+	DEADLINE_UPLOAD   int `json:"deadline_upload"`   // seconds
+	DEADLINE_DOWNLOAD int `json:"deadline_download"` // seconds
+}
+
+type AzureConfig struct {
+	AZURE_TENANT_ID     string `json:"azure_tenant_id"`
+	AZURE_CLIENT_ID     string `json:"azure_client_id"`
+	AZURE_CLIENT_SECRET string `json:"-"` // empty uses the managed identity
+}
+
+type KeyVaultConfig struct {
+	KEYVAULT_URL       string `json:"keyvault_url"`       // ex: https://myvault.vault.azure.net
+	KEYVAULT_CACHE_TTL int    `json:"keyvault_cache_ttl"` // seconds
+	KEYVAULT_CACHE_MAX int    `json:"keyvault_cache_max"` // secrets
+}
+
+type CosmosConfig struct {
+	COSMOS_ENDPOINT    string `json:"cosmos_endpoint"` // ex: https://myaccount.documents.azure.com
+	COSMOS_KEY         string `json:"-"`
+	COSMOS_DATABASE    string `json:"cosmos_database"`
+	COSMOS_MAX_RETRIES int    `json:"cosmos_max_retries"` // retries of throttled (429) requests
+}
+
+type SearchConfig struct {
+	SEARCH_ENDPOINT string `json:"search_endpoint"` // ex: https://myservice.search.windows.net
+	SEARCH_API_KEY  string `json:"-"`
+}
+
+type RedactConfig struct {
+	REDACT_HASH_KEY string `json:"-"` // HMAC key of the hash strategy
+}
+
+type SSHConfig struct {
+	SSH_USER        string `json:"ssh_user"`
+	SSH_KEY_FILE    string `json:"ssh_key_file"`
+	SSH_PASSPHRASE  string `json:"-"`
+	SSH_PASSWORD    string `json:"-"`
+	SSH_KNOWN_HOSTS string `json:"ssh_known_hosts"` // known_hosts file, required
+	SSH_TIMEOUT     int    `json:"ssh_timeout"`     // dial timeout in seconds
 }
@@ -0,0 +1,168 @@
+// Package crypto encrypts data persisted locally by the library (publisher spool,
+// kv caches...) with AES-256-GCM. Every key has an id written in the ciphertext,
+// so keys can be rotated while data encrypted with the previous ones is still read.
+//
+//	c, err := crypto.New(conf) // SRV_CRYPTO_KEYS="k2:base64...,k1:base64..."
+//	sp, err := spool.OpenEncrypted(path, c)
+//	store = kv.NewEncrypted(store, c)
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	KEY_SIZE = 32 // AES-256
+	version  = 1
+)
+
+type Cipher interface {
+	// Encrypt seals plaintext with the active key. aad is authenticated but not
+	// encrypted, use it to bind the ciphertext to its key or path.
+	Encrypt(plaintext, aad []byte) ([]byte, error)
+	// Decrypt opens data sealed by Encrypt with any of the known keys and the same aad.
+	// It returns an error of kind cerrors.Invalid for tampered data or unknown keys.
+	Decrypt(data, aad []byte) ([]byte, error)
+}
+
+type aes_gcm struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// New returns an AES-GCM Cipher configured by SRV_CRYPTO_KEYS and SRV_CRYPTO_ACTIVE_KEY
+func New(conf *config.Config) (Cipher, error) {
+	if conf.CryptoConfig == nil {
+		conf.CryptoConfig = &config.CryptoConfig{}
+	}
+
+	SRV_CRYPTO_KEYS := os.Getenv("SRV_CRYPTO_KEYS")
+	if SRV_CRYPTO_KEYS != "" {
+		conf.CRYPTO_KEYS = SRV_CRYPTO_KEYS
+	}
+
+	SRV_CRYPTO_ACTIVE_KEY := os.Getenv("SRV_CRYPTO_ACTIVE_KEY")
+	if SRV_CRYPTO_ACTIVE_KEY != "" {
+		conf.CRYPTO_ACTIVE_KEY = SRV_CRYPTO_ACTIVE_KEY
+	}
+
+	keys, first, err := ParseKeys(conf.CRYPTO_KEYS)
+	if err != nil {
+		return nil, err
+	}
+	if conf.CRYPTO_ACTIVE_KEY == "" {
+		conf.CRYPTO_ACTIVE_KEY = first
+	}
+
+	return NewAESGCM(keys, conf.CRYPTO_ACTIVE_KEY)
+}
+
+// ParseKeys parses "id:base64key" pairs separated by commas, returning the keys and the first id
+func ParseKeys(s string) (map[string][]byte, string, error) {
+	keys := map[string][]byte{}
+	first := ""
+
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok || id == "" {
+			return nil, "", cerrors.New(cerrors.Invalid, "crypto: keys must be id:base64key")
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", cerrors.Wrap(cerrors.Invalid, "crypto: key "+id, err)
+		}
+
+		keys[id] = key
+		if first == "" {
+			first = id
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, "", cerrors.New(cerrors.Invalid, "crypto: SRV_CRYPTO_KEYS is required")
+	}
+	return keys, first, nil
+}
+
+// NewAESGCM returns a Cipher encrypting with keys[active] and decrypting with any of keys
+func NewAESGCM(keys map[string][]byte, active string) (Cipher, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, cerrors.New(cerrors.Invalid, "crypto: unknown active key "+active)
+	}
+
+	c := &aes_gcm{active: active, aeads: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if len(id) > 255 {
+			return nil, cerrors.New(cerrors.Invalid, "crypto: key id too long")
+		}
+		if len(key) != KEY_SIZE {
+			return nil, cerrors.New(cerrors.Invalid, "crypto: key "+id+" must have 32 bytes")
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "crypto: key "+id, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "crypto: key "+id, err)
+		}
+		c.aeads[id] = aead
+	}
+
+	return c, nil
+}
+
+// Encrypt returns version | len(key id) | key id | nonce | sealed data
+func (c *aes_gcm) Encrypt(plaintext, aad []byte) ([]byte, error) {
+	aead := c.aeads[c.active]
+
+	header := make([]byte, 0, 2+len(c.active)+aead.NonceSize())
+	header = append(header, version, byte(len(c.active)))
+	header = append(header, c.active...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "crypto.Encrypt", err)
+	}
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, plaintext, aad), nil
+}
+
+func (c *aes_gcm) Decrypt(data, aad []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != version || len(data) < 2+int(data[1]) {
+		return nil, cerrors.New(cerrors.Invalid, "crypto: malformed ciphertext")
+	}
+
+	id := string(data[2 : 2+int(data[1])])
+	aead, ok := c.aeads[id]
+	if !ok {
+		return nil, cerrors.New(cerrors.Invalid, "crypto: unknown key "+id)
+	}
+
+	data = data[2+len(id):]
+	if len(data) < aead.NonceSize() {
+		return nil, cerrors.New(cerrors.Invalid, "crypto: malformed ciphertext")
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], aad)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "crypto.Decrypt", err)
+	}
+	return plaintext, nil
+}
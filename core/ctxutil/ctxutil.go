@@ -0,0 +1,116 @@
+// Package ctxutil carries request scoped values (request ID, correlation ID,
+// tenant and principal) in a context.Context and propagates them through HTTP
+// and AMQP headers and log lines.
+package ctxutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP headers
+const (
+	HEADER_REQUEST_ID     = "X-Request-ID"
+	HEADER_CORRELATION_ID = "X-Correlation-ID"
+	HEADER_TENANT         = "X-Tenant-ID"
+)
+
+// AMQP headers
+const (
+	AMQP_REQUEST_ID     = "x-request-id"
+	AMQP_CORRELATION_ID = "x-correlation-id"
+	AMQP_TENANT         = "x-tenant"
+)
+
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+type (
+	requestIDKey     struct{}
+	correlationIDKey struct{}
+	tenantKey        struct{}
+	principalKey     struct{}
+)
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+func CorrelationID(ctx context.Context) string {
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+func Tenant(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// GetPrincipal returns the authenticated principal or nil
+func GetPrincipal(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// Fields returns the non empty values of ctx as log fields
+func Fields(ctx context.Context) map[string]string {
+	fields := map[string]string{}
+	if v := RequestID(ctx); v != "" {
+		fields["request_id"] = v
+	}
+	if v := CorrelationID(ctx); v != "" {
+		fields["correlation_id"] = v
+	}
+	if v := Tenant(ctx); v != "" {
+		fields["tenant"] = v
+	}
+	if p := GetPrincipal(ctx); p != nil && p.Subject != "" {
+		fields["principal"] = p.Subject
+	}
+	return fields
+}
+
+// LogPrefix formats Fields as "key=value " pairs in a stable order
+func LogPrefix(ctx context.Context) string {
+	fields := Fields(ctx)
+
+	var sb strings.Builder
+	for _, key := range []string{"request_id", "correlation_id", "tenant", "principal"} {
+		if v, ok := fields[key]; ok {
+			sb.WriteString(key + "=" + v + " ")
+		}
+	}
+	return sb.String()
+}
+
+// NewID returns a random 128 bits hex identifier
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,112 @@
+package ctxutil
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware populates the context from the incoming headers, generating a
+// request ID when missing (echoed back in the response). The correlation ID
+// defaults to the request ID.
+func Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := FromHTTPHeaders(r.Context(), r.Header)
+			if RequestID(ctx) == "" {
+				ctx = WithRequestID(ctx, NewID())
+			}
+			if CorrelationID(ctx) == "" {
+				ctx = WithCorrelationID(ctx, RequestID(ctx))
+			}
+
+			w.Header().Set(HEADER_REQUEST_ID, RequestID(ctx))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromHTTPHeaders copies the propagated headers present in h into ctx
+func FromHTTPHeaders(ctx context.Context, h http.Header) context.Context {
+	if v := h.Get(HEADER_REQUEST_ID); v != "" {
+		ctx = WithRequestID(ctx, v)
+	}
+	if v := h.Get(HEADER_CORRELATION_ID); v != "" {
+		ctx = WithCorrelationID(ctx, v)
+	}
+	if v := h.Get(HEADER_TENANT); v != "" {
+		ctx = WithTenant(ctx, v)
+	}
+	return ctx
+}
+
+// InjectHTTP sets the propagated headers from ctx into h
+func InjectHTTP(ctx context.Context, h http.Header) {
+	if v := RequestID(ctx); v != "" {
+		h.Set(HEADER_REQUEST_ID, v)
+	}
+	if v := CorrelationID(ctx); v != "" {
+		h.Set(HEADER_CORRELATION_ID, v)
+	}
+	if v := Tenant(ctx); v != "" {
+		h.Set(HEADER_TENANT, v)
+	}
+}
+
+// Transport is an http.RoundTripper injecting the propagated headers in outgoing requests
+type Transport struct {
+	Base http.RoundTripper // http.DefaultTransport when nil
+}
+
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	r = r.Clone(r.Context())
+	InjectHTTP(r.Context(), r.Header)
+	return base.RoundTrip(r)
+}
+
+// AMQPHeaders returns the propagated values of ctx as AMQP headers
+func AMQPHeaders(ctx context.Context) map[string]interface{} {
+	headers := map[string]interface{}{}
+	if v := RequestID(ctx); v != "" {
+		headers[AMQP_REQUEST_ID] = v
+	}
+	if v := CorrelationID(ctx); v != "" {
+		headers[AMQP_CORRELATION_ID] = v
+	}
+	if v := Tenant(ctx); v != "" {
+		headers[AMQP_TENANT] = v
+	}
+	return headers
+}
+
+// FromAMQPHeaders copies the propagated AMQP headers present in headers into ctx
+func FromAMQPHeaders(ctx context.Context, headers map[string]interface{}) context.Context {
+	if v, ok := headers[AMQP_REQUEST_ID].(string); ok && v != "" {
+		ctx = WithRequestID(ctx, v)
+	}
+	if v, ok := headers[AMQP_CORRELATION_ID].(string); ok && v != "" {
+		ctx = WithCorrelationID(ctx, v)
+	}
+	if v, ok := headers[AMQP_TENANT].(string); ok && v != "" {
+		ctx = WithTenant(ctx, v)
+	}
+	return ctx
+}
+
+// Printf logs with the standard logger prefixing the request values of ctx
+func Printf(ctx context.Context, format string, v ...interface{}) {
+	// the prefix holds client supplied ids, it must not be parsed as a format
+	log.Printf("%s"+format, append([]interface{}{LogPrefix(ctx)}, v...)...)
+}
+
+// Println logs with the standard logger prefixing the request values of ctx
+func Println(ctx context.Context, v ...interface{}) {
+	log.Println(append([]interface{}{LogPrefix(ctx)}, v...)...)
+}
@@ -0,0 +1,102 @@
+// Package deadline holds the default timeout of each operation class. Adapters apply
+// it when the caller's context has no deadline, so a forgotten timeout at a call
+// site doesn't leave a publish or an upload hanging forever.
+//
+//	deadline.Configure(conf)
+//
+//	ctx, cancel := deadline.Apply(ctx, deadline.Publish)
+//	defer cancel()
+package deadline
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+)
+
+type Class string
+
+const (
+	Publish  Class = "publish"  // rabbitmq Producer
+	Consume  Class = "consume"  // messaging handlers
+	Upload   Class = "upload"   // blob and claim-check uploads
+	Download Class = "download" // blob and claim-check downloads
+)
+
+const (
+	DEFAULT_PUBLISH  = 10  // seconds
+	DEFAULT_CONSUME  = 300 // seconds
+	DEFAULT_UPLOAD   = 600 // seconds
+	DEFAULT_DOWNLOAD = 600 // seconds
+)
+
+var (
+	lock     sync.RWMutex
+	defaults = map[Class]time.Duration{
+		Publish:  DEFAULT_PUBLISH * time.Second,
+		Consume:  DEFAULT_CONSUME * time.Second,
+		Upload:   DEFAULT_UPLOAD * time.Second,
+		Download: DEFAULT_DOWNLOAD * time.Second,
+	}
+)
+
+// Configure loads the defaults from SRV_DEADLINE_PUBLISH, SRV_DEADLINE_CONSUME,
+// SRV_DEADLINE_UPLOAD and SRV_DEADLINE_DOWNLOAD. A negative value disables the class.
+func Configure(conf *config.Config) {
+	if conf.DeadlineConfig == nil {
+		conf.DeadlineConfig = &config.DeadlineConfig{}
+	}
+
+	load := func(env string, value *int, def int) {
+		if v := os.Getenv(env); v != "" {
+			*value, _ = strconv.Atoi(v)
+		}
+		if *value == 0 {
+			*value = def
+		}
+	}
+	load("SRV_DEADLINE_PUBLISH", &conf.DEADLINE_PUBLISH, DEFAULT_PUBLISH)
+	load("SRV_DEADLINE_CONSUME", &conf.DEADLINE_CONSUME, DEFAULT_CONSUME)
+	load("SRV_DEADLINE_UPLOAD", &conf.DEADLINE_UPLOAD, DEFAULT_UPLOAD)
+	load("SRV_DEADLINE_DOWNLOAD", &conf.DEADLINE_DOWNLOAD, DEFAULT_DOWNLOAD)
+
+	Set(Publish, time.Duration(conf.DEADLINE_PUBLISH)*time.Second)
+	Set(Consume, time.Duration(conf.DEADLINE_CONSUME)*time.Second)
+	Set(Upload, time.Duration(conf.DEADLINE_UPLOAD)*time.Second)
+	Set(Download, time.Duration(conf.DEADLINE_DOWNLOAD)*time.Second)
+}
+
+// Set changes the default of class, d <= 0 disables it
+func Set(class Class, d time.Duration) {
+	lock.Lock()
+	defer lock.Unlock()
+	defaults[class] = d
+}
+
+// Get returns the default of class, 0 when disabled
+func Get(class Class) time.Duration {
+	lock.RLock()
+	defer lock.RUnlock()
+	if d := defaults[class]; d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Apply returns ctx bounded by the default of class when ctx has no deadline yet.
+// The CancelFunc must always be called.
+func Apply(ctx context.Context, class Class) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	d := Get(class)
+	if d == 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,183 @@
+// Package debounce has time-window helpers to turn bursts of events into fewer actions:
+//
+//   - Debouncer runs a function once the events stop for a quiet period
+//   - Throttle allows an action at most once per interval
+//   - Coalescer collects keys and flushes them together once per window
+//
+// For example, coalescing many "blob changed" events into one rebuild per 10 seconds:
+//
+//	c := debounce.NewCoalescer(10*time.Second, func(paths []string) { rebuild(paths) })
+//	c.Start(ctx)
+//	c.Add(path)
+package debounce
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+)
+
+// Debouncer runs fn after Trigger stops being called for Wait. When MaxWait > 0
+// fn also runs after MaxWait of continuous triggers, so it is never postponed forever.
+type Debouncer struct {
+	Wait    time.Duration
+	MaxWait time.Duration
+
+	fn      func()
+	trigger chan struct{}
+	clock   clock.Clock
+}
+
+func NewDebouncer(wait time.Duration, fn func()) *Debouncer {
+	return &Debouncer{Wait: wait, fn: fn, trigger: make(chan struct{}, 1), clock: clock.New()}
+}
+
+func (d *Debouncer) SetClock(c clock.Clock) {
+	d.clock = c
+}
+
+// Trigger records an event, it never blocks
+func (d *Debouncer) Trigger() {
+	select {
+	case d.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the Debouncer until ctx is done. A pending run is dropped on ctx done.
+func (d *Debouncer) Start(ctx context.Context) {
+	async.Go(func() error {
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-d.trigger:
+			}
+
+			first := d.clock.Now()
+			for pending := true; pending; {
+				wait := d.Wait
+				if d.MaxWait > 0 {
+					if left := d.MaxWait - d.clock.Now().Sub(first); left < wait {
+						wait = left
+					}
+				}
+
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-d.trigger:
+				case <-d.clock.After(wait):
+					pending = false
+				}
+			}
+
+			async.Safe(func() error { d.fn(); return nil })
+		}
+	})
+}
+
+// Throttle allows an action at most once per Interval
+type Throttle struct {
+	Interval time.Duration
+
+	mu    sync.Mutex
+	last  time.Time
+	clock clock.Clock
+}
+
+func NewThrottle(interval time.Duration) *Throttle {
+	return &Throttle{Interval: interval, clock: clock.New()}
+}
+
+func (t *Throttle) SetClock(c clock.Clock) {
+	t.clock = c
+}
+
+// Allow reports whether the action can run now, and if so starts a new interval
+func (t *Throttle) Allow() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.clock.Now()
+	if !t.last.IsZero() && now.Sub(t.last) < t.Interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// Do runs fn when Allow, returning whether it ran
+func (t *Throttle) Do(fn func()) bool {
+	if !t.Allow() {
+		return false
+	}
+	fn()
+	return true
+}
+
+// Coalescer collects keys and calls fn once per Window with the distinct keys added
+// during it, in insertion order. Windows without keys don't call fn.
+type Coalescer[K comparable] struct {
+	Window time.Duration
+
+	mu    sync.Mutex
+	seen  map[K]struct{}
+	keys  []K
+	fn    func(keys []K)
+	clock clock.Clock
+}
+
+func NewCoalescer[K comparable](window time.Duration, fn func(keys []K)) *Coalescer[K] {
+	return &Coalescer[K]{Window: window, fn: fn, seen: map[K]struct{}{}, clock: clock.New()}
+}
+
+func (c *Coalescer[K]) SetClock(cl clock.Clock) {
+	c.clock = cl
+}
+
+// Add records key for the current window
+func (c *Coalescer[K]) Add(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.seen[key]; ok {
+		return
+	}
+	c.seen[key] = struct{}{}
+	c.keys = append(c.keys, key)
+}
+
+// Flush calls fn with the pending keys right away
+func (c *Coalescer[K]) Flush() {
+	c.mu.Lock()
+	keys := c.keys
+	c.keys = nil
+	c.seen = map[K]struct{}{}
+	c.mu.Unlock()
+
+	if len(keys) > 0 {
+		async.Safe(func() error { c.fn(keys); return nil })
+	}
+}
+
+// Start flushes every Window until ctx is done, then flushes the last keys
+func (c *Coalescer[K]) Start(ctx context.Context) {
+	async.Go(func() error {
+		ticker := c.clock.NewTicker(c.Window)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				c.Flush()
+				return nil
+			case <-ticker.C():
+				c.Flush()
+			}
+		}
+	})
+}
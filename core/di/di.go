@@ -0,0 +1,242 @@
+// Package di is a small dependency injection container. Services register
+// constructors by name, resolve them lazily (dependencies first) and start/stop
+// them in dependency order.
+//
+//	di.Provide(c, "rabbitmq", func(c *di.Container) (rabbitmq.RabbitInterface, error) {
+//		return rabbitmq.New(conf).Connect()
+//	})
+//
+//	rbm, err := di.Resolve[rabbitmq.RabbitInterface](c, "rabbitmq")
+package di
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Starter, Stopper and Closer are detected on resolved instances, so adapters with
+// Start/Stop or Close methods don't need explicit hooks
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+// Closer is the stop of the adapters closed with a context, ex: rabbitmq.RabbitInterface
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Hook registers lifecycle functions for an instance
+type Hook[T any] struct {
+	OnStart func(ctx context.Context, instance T) error
+	OnStop  func(ctx context.Context, instance T) error
+}
+
+type provider struct {
+	build    func(c *Container) (interface{}, error)
+	start    func(ctx context.Context, instance interface{}) error
+	stop     func(ctx context.Context, instance interface{}) error
+	instance interface{}
+	built    bool
+	building chan struct{} // closed when the build in progress ends
+}
+
+// Container is shared by the constructors, each one gets a view of it carrying the
+// names being resolved by its call so cycles are told apart from concurrent resolves
+type Container struct {
+	*container
+	stack []string
+}
+
+type container struct {
+	mu        sync.Mutex
+	providers map[string]*provider
+	order     []string // names in the order they were built, dependencies first
+	started   []string
+}
+
+func New() *Container {
+	return &Container{
+		container: &container{providers: map[string]*provider{}},
+	}
+}
+
+// Provide registers the constructor of name. Registering the same name twice replaces it.
+func Provide[T any](c *Container, name string, ctor func(c *Container) (T, error), hooks ...Hook[T]) {
+	p := &provider{
+		build: func(c *Container) (interface{}, error) { return ctor(c) },
+	}
+
+	for _, hook := range hooks {
+		hook := hook
+		if hook.OnStart != nil {
+			p.start = func(ctx context.Context, instance interface{}) error { return hook.OnStart(ctx, instance.(T)) }
+		}
+		if hook.OnStop != nil {
+			p.stop = func(ctx context.Context, instance interface{}) error { return hook.OnStop(ctx, instance.(T)) }
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providers[name] = p
+}
+
+// Resolve builds (once) and returns the instance registered as name
+func Resolve[T any](c *Container, name string) (T, error) {
+	var zero T
+
+	instance, err := c.resolve(name)
+	if err != nil {
+		return zero, err
+	}
+
+	typed, ok := instance.(T)
+	if !ok {
+		return zero, cerrors.New(cerrors.Invalid, fmt.Sprintf("di: %s is %T, not %T", name, instance, zero))
+	}
+
+	return typed, nil
+}
+
+// MustResolve is like Resolve but panics on error, for use in main()
+func MustResolve[T any](c *Container, name string) T {
+	instance, err := Resolve[T](c, name)
+	if err != nil {
+		panic(err)
+	}
+	return instance
+}
+
+func (c *Container) resolve(name string) (interface{}, error) {
+	for _, resolving := range c.stack {
+		if resolving == name {
+			return nil, cerrors.New(cerrors.Conflict, "di: dependency cycle on "+name)
+		}
+	}
+
+	c.mu.Lock()
+	var p *provider
+	for {
+		var ok bool
+		p, ok = c.providers[name]
+		if !ok {
+			c.mu.Unlock()
+			return nil, cerrors.New(cerrors.NotFound, "di: no provider for "+name)
+		}
+		if p.built {
+			c.mu.Unlock()
+			return p.instance, nil
+		}
+		if p.building == nil {
+			break
+		}
+		// built by another goroutine, wait for it and look again
+		building := p.building
+		c.mu.Unlock()
+		<-building
+		c.mu.Lock()
+	}
+	p.building = make(chan struct{})
+	c.mu.Unlock()
+
+	// the lock is released so the constructor can resolve its own dependencies
+	view := &Container{container: c.container, stack: append(c.stack[:len(c.stack):len(c.stack)], name)}
+	instance, err := p.build(view)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	close(p.building)
+	p.building = nil
+
+	if err != nil {
+		return nil, fmt.Errorf("di: building %s: %w", name, err)
+	}
+
+	p.instance = instance
+	p.built = true
+	c.order = append(c.order, name)
+
+	return instance, nil
+}
+
+// Start builds every provider and starts them in dependency order.
+// If one fails, the ones already started are stopped.
+func (c *Container) Start(ctx context.Context) error {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.providers))
+	for name := range c.providers {
+		names = append(names, name)
+	}
+	c.mu.Unlock()
+
+	for _, name := range names {
+		if _, err := c.resolve(name); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	order := append([]string(nil), c.order...)
+	c.mu.Unlock()
+
+	for _, name := range order {
+		p := c.providers[name]
+
+		var err error
+		if p.start != nil {
+			err = p.start(ctx, p.instance)
+		} else if s, ok := p.instance.(Starter); ok {
+			err = s.Start(ctx)
+		}
+
+		if err != nil {
+			log.Println("Erro to start", name)
+			return errors.Join(fmt.Errorf("di: starting %s: %w", name, err), c.Stop(ctx))
+		}
+
+		c.mu.Lock()
+		c.started = append(c.started, name)
+		c.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Stop stops the started instances in reverse order, running every stop even if some fail
+func (c *Container) Stop(ctx context.Context) error {
+	c.mu.Lock()
+	started := c.started
+	c.started = nil
+	c.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		name := started[i]
+		p := c.providers[name]
+
+		var err error
+		if p.stop != nil {
+			err = p.stop(ctx, p.instance)
+		} else if s, ok := p.instance.(Stopper); ok {
+			err = s.Stop(ctx)
+		} else if s, ok := p.instance.(Closer); ok {
+			err = s.Close(ctx)
+		}
+
+		if err != nil {
+			log.Println("Erro to stop", name)
+			errs = append(errs, fmt.Errorf("di: stopping %s: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+// Package envelope defines the standard event envelope shared by the messaging
+// adapters, so events are interoperable across brokers.
+package envelope
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/bufpool"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const CONTENT_TYPE = "application/vnd.envelope+json"
+
+type Envelope struct {
+	ID            string          `json:"id"`
+	Type          string          `json:"type"`   // ex: "order.created"
+	Source        string          `json:"source"` // producing service
+	Tenant        string          `json:"tenant,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	CausationID   string          `json:"causation_id,omitempty"`
+	Timestamp     time.Time       `json:"timestamp"`
+	SchemaVersion string          `json:"schema_version,omitempty"`
+	Payload       json.RawMessage `json:"payload"`
+}
+
+// New creates an Envelope with a fresh ID and timestamp, encoding payload as JSON.
+// The event starts its own correlation chain.
+func New(eventType, source string, payload interface{}) (*Envelope, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "envelope.New", err)
+	}
+
+	id := NewID()
+	return &Envelope{
+		ID:            id,
+		Type:          eventType,
+		Source:        source,
+		CorrelationID: id,
+		Timestamp:     time.Now().UTC(),
+		Payload:       data,
+	}, nil
+}
+
+// CausedBy links the Envelope to the event that triggered it,
+// keeping the correlation ID of the whole chain
+func (e *Envelope) CausedBy(cause *Envelope) *Envelope {
+	e.CausationID = cause.ID
+	e.CorrelationID = cause.CorrelationID
+	if e.CorrelationID == "" {
+		e.CorrelationID = cause.ID
+	}
+	if e.Tenant == "" {
+		e.Tenant = cause.Tenant
+	}
+	return e
+}
+
+// Decode unmarshals the payload into v
+func (e *Envelope) Decode(v interface{}) error {
+	if err := json.Unmarshal(e.Payload, v); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "envelope.Decode", err)
+	}
+	return nil
+}
+
+func Marshal(e *Envelope) ([]byte, error) {
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+
+	if err := json.NewEncoder(buf).Encode(e); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "envelope.Marshal", err)
+	}
+
+	// the pooled buffer is reused, copy without the trailing newline of Encode
+	data := buf.Bytes()
+	return append([]byte(nil), data[:len(data)-1]...), nil
+}
+
+// Unmarshal parses an Envelope and checks the required fields
+func Unmarshal(data []byte) (*Envelope, error) {
+	e := &Envelope{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "envelope.Unmarshal", err)
+	}
+
+	if e.ID == "" || e.Type == "" {
+		return nil, cerrors.New(cerrors.Invalid, "envelope.Unmarshal: id and type are required")
+	}
+
+	return e, nil
+}
+
+// NewID returns a random 128 bits hex identifier
+func NewID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,153 @@
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kind classifies an error so callers can branch on it instead of matching strings
+type Kind int
+
+const (
+	Unknown Kind = iota
+	NotFound
+	Conflict
+	Unavailable
+	Invalid
+	Throttled
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not_found"
+	case Conflict:
+		return "conflict"
+	case Unavailable:
+		return "unavailable"
+	case Invalid:
+		return "invalid"
+	case Throttled:
+		return "throttled"
+	default:
+		return "unknown"
+	}
+}
+
+type Error struct {
+	Kind    Kind
+	Op      string // operation that failed, ex: "rabbitmq.Connect"
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" && e.Err != nil {
+		msg = e.Err.Error()
+	} else if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	if e.Op != "" {
+		return e.Op + ": " + msg
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error of the given kind
+func New(kind Kind, message string) error {
+	return &Error{Kind: kind, Message: message}
+}
+
+// Wrap annotates err with a kind and the operation that failed. It returns nil when err is nil.
+func Wrap(kind Kind, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Kind: kind, Op: op, Err: err}
+}
+
+// KindOf returns the Kind of the first *Error in the chain of err, or Unknown
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return Unknown
+}
+
+// Is reports whether err has the given kind
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// HTTPStatus maps the kind of err to an HTTP status code
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+
+	switch KindOf(err) {
+	case NotFound:
+		return http.StatusNotFound
+	case Conflict:
+		return http.StatusConflict
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Invalid:
+		return http.StatusBadRequest
+	case Throttled:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// FromHTTPStatus returns the Kind of an HTTP error status answered by a remote API.
+// Authentication failures are Invalid, retrying them doesn't help.
+func FromHTTPStatus(status int) Kind {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return NotFound
+	case status == http.StatusConflict || status == http.StatusPreconditionFailed:
+		return Conflict
+	case status == http.StatusTooManyRequests:
+		return Throttled
+	case status == http.StatusBadRequest || status == http.StatusUnprocessableEntity:
+		return Invalid
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return Invalid
+	case status >= 500:
+		return Unavailable
+	default:
+		return Unknown
+	}
+}
+
+// GRPCCode maps the kind of err to a canonical gRPC status code.
+// The values match google.golang.org/grpc/codes, convert with codes.Code(GRPCCode(err)).
+func GRPCCode(err error) uint32 {
+	if err == nil {
+		return 0 // OK
+	}
+
+	switch KindOf(err) {
+	case NotFound:
+		return 5 // NotFound
+	case Conflict:
+		return 6 // AlreadyExists
+	case Unavailable:
+		return 14 // Unavailable
+	case Invalid:
+		return 3 // InvalidArgument
+	case Throttled:
+		return 8 // ResourceExhausted
+	default:
+		return 2 // Unknown
+	}
+}
@@ -0,0 +1,31 @@
+// Package fault injects deterministic failures into the adapters so teams can
+// verify their retry and recovery behavior.
+//
+// Injection only happens in binaries built with the chaos tag:
+//
+//	go test -tags chaos ./...
+//
+// In regular builds Inject always returns nil and Set is ignored.
+package fault
+
+import (
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+var (
+	// ErrTransient is a retryable failure
+	ErrTransient = cerrors.New(cerrors.Unavailable, "fault: injected transient error")
+	// ErrDropConnection makes the adapter close its connection, as a network failure would
+	ErrDropConnection = cerrors.New(cerrors.Unavailable, "fault: injected connection drop")
+)
+
+// Rule describes the failure of an operation, ex: "rabbitmq.Producer".
+// Calls are counted per operation so the same sequence always fails the same way.
+type Rule struct {
+	Err   error         // returned by Inject, nil only delays
+	Delay time.Duration // wait before returning
+	After int           // let the first After calls pass
+	Times int           // fail at most Times calls, 0 means every call
+}
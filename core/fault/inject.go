@@ -0,0 +1,11 @@
+//go:build !chaos
+
+package fault
+
+const Enabled = false
+
+func Set(op string, rule Rule) {}
+
+func Reset() {}
+
+func Inject(op string) error { return nil }
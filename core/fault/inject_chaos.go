@@ -0,0 +1,60 @@
+//go:build chaos
+
+package fault
+
+import (
+	"sync"
+	"time"
+)
+
+const Enabled = true
+
+type state struct {
+	rule  Rule
+	calls int
+	fired int
+}
+
+var (
+	lock  sync.Mutex
+	rules = map[string]*state{}
+)
+
+// Set registers the Rule for an operation, replacing any previous one
+func Set(op string, rule Rule) {
+	lock.Lock()
+	defer lock.Unlock()
+	rules[op] = &state{rule: rule}
+}
+
+// Reset removes every Rule
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	rules = map[string]*state{}
+}
+
+// Inject is called by the adapters at the start of an operation
+func Inject(op string) error {
+	lock.Lock()
+	st, ok := rules[op]
+	if !ok {
+		lock.Unlock()
+		return nil
+	}
+
+	st.calls++
+	if st.calls <= st.rule.After || (st.rule.Times > 0 && st.fired >= st.rule.Times) {
+		lock.Unlock()
+		return nil
+	}
+	st.fired++
+	rule := st.rule
+	lock.Unlock()
+
+	if rule.Delay > 0 {
+		time.Sleep(rule.Delay)
+	}
+
+	return rule.Err
+}
@@ -0,0 +1,235 @@
+// Package fsm runs typed state machines whose current state is persisted per id,
+// for workflows such as orders and subscriptions:
+//
+//	m := fsm.New[OrderState, OrderEvent](fsm.Config{Name: "order", Store: store, Publisher: pub, Topic: "orders"}, Pending)
+//	m.Transition(fsm.Transition[OrderState, OrderEvent]{Event: Pay, From: []OrderState{Pending}, To: Paid})
+//	m.OnEnter(Paid, sendReceipt)
+//	change, err := m.Fire(ctx, orderID, Pay, payment)
+//
+// Every transition is saved with an optimistic version check and published as an
+// Envelope of type "{name}.{event}" whose payload is the Change.
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/pkg/messaging"
+)
+
+// Change describes a transition of the machine of one id
+type Change[S ~string, E ~string] struct {
+	Machine string      `json:"machine"`
+	ID      string      `json:"id"`
+	Event   E           `json:"event"`
+	From    S           `json:"from"`
+	To      S           `json:"to"`
+	Version int64       `json:"version"` // version of the saved state
+	At      time.Time   `json:"at"`
+	Data    interface{} `json:"data,omitempty"` // given to Fire
+}
+
+// Hook runs on a transition, a Guard returning an error prevents it
+type Hook[S ~string, E ~string] func(ctx context.Context, c Change[S, E]) error
+
+type Transition[S ~string, E ~string] struct {
+	Event E
+	From  []S // states the event is accepted in
+	To    S
+	Guard Hook[S, E] // optional
+}
+
+type Config struct {
+	Name      string // machine name, part of the store keys and event types, ex: "order"
+	Store     Store
+	Publisher messaging.Publisher // optional, transitions aren't published when nil
+	Topic     string
+	Source    string // Envelope source, ex: the service name
+}
+
+type Machine[S ~string, E ~string] struct {
+	conf         Config
+	initial      S
+	mu           sync.RWMutex
+	transitions  map[E][]Transition[S, E]
+	onEnter      map[S][]Hook[S, E]
+	onExit       map[S][]Hook[S, E]
+	onTransition []Hook[S, E]
+	clock        clock.Clock
+}
+
+// New returns a Machine whose ids without a saved state are in initial
+func New[S ~string, E ~string](conf Config, initial S) *Machine[S, E] {
+	return &Machine[S, E]{
+		conf:        conf,
+		initial:     initial,
+		transitions: map[E][]Transition[S, E]{},
+		onEnter:     map[S][]Hook[S, E]{},
+		onExit:      map[S][]Hook[S, E]{},
+		clock:       clock.New(),
+	}
+}
+
+func (m *Machine[S, E]) SetClock(c clock.Clock) {
+	m.clock = c
+}
+
+// Transition registers t, an event can have one transition per From state
+func (m *Machine[S, E]) Transition(t Transition[S, E]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transitions[t.Event] = append(m.transitions[t.Event], t)
+}
+
+// OnEnter runs h after a transition into state is saved and published
+func (m *Machine[S, E]) OnEnter(state S, h Hook[S, E]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEnter[state] = append(m.onEnter[state], h)
+}
+
+// OnExit runs h before a transition out of state is saved, an error prevents it
+func (m *Machine[S, E]) OnExit(state S, h Hook[S, E]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onExit[state] = append(m.onExit[state], h)
+}
+
+// OnTransition runs h after every saved transition, after the OnEnter hooks
+func (m *Machine[S, E]) OnTransition(h Hook[S, E]) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onTransition = append(m.onTransition, h)
+}
+
+// Next returns the state event leads to from state, without guards or persistence
+func (m *Machine[S, E]) Next(state S, event E) (S, bool) {
+	t, ok := m.find(state, event)
+	return t.To, ok
+}
+
+// Events returns the events accepted in state
+func (m *Machine[S, E]) Events(state S) []E {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []E
+	for event := range m.transitions {
+		if _, ok := m.findLocked(state, event); ok {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// State returns the current state of id and its version, 0 for an id never transitioned
+func (m *Machine[S, E]) State(ctx context.Context, id string) (S, int64, error) {
+	rec, err := m.conf.Store.Load(ctx, m.conf.Name, id)
+	if cerrors.Is(err, cerrors.NotFound) {
+		return m.initial, 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return S(rec.State), rec.Version, nil
+}
+
+// Fire applies event to id: checks the guard, runs the OnExit hooks, saves the new state,
+// publishes the Change and runs the OnEnter and OnTransition hooks. An event not accepted
+// in the current state returns an error of kind Conflict, as does a concurrent transition
+// of the same id. Errors after the save (hooks, publishing) are returned with the Change,
+// the state is already saved.
+func (m *Machine[S, E]) Fire(ctx context.Context, id string, event E, data interface{}) (change Change[S, E], err error) {
+	end := hooks.Begin(ctx, "fsm", "Fire", map[string]interface{}{
+		"machine": m.conf.Name,
+		"id":      id,
+		"event":   string(event),
+	})
+	defer func() { end(err) }()
+
+	from, version, err := m.State(ctx, id)
+	if err != nil {
+		return change, err
+	}
+
+	t, ok := m.find(from, event)
+	if !ok {
+		return change, cerrors.New(cerrors.Conflict, fmt.Sprintf("fsm: %s %s can't %s in state %s", m.conf.Name, id, event, from))
+	}
+
+	change = Change[S, E]{
+		Machine: m.conf.Name,
+		ID:      id,
+		Event:   event,
+		From:    from,
+		To:      t.To,
+		Version: version + 1,
+		At:      m.clock.Now().UTC(),
+		Data:    data,
+	}
+
+	if t.Guard != nil {
+		if err := t.Guard(ctx, change); err != nil {
+			return change, err
+		}
+	}
+
+	m.mu.RLock()
+	onExit, onEnter, onTransition := m.onExit[from], m.onEnter[t.To], m.onTransition
+	m.mu.RUnlock()
+
+	if err := run(ctx, onExit, change); err != nil {
+		return change, err
+	}
+
+	rec := Record{State: string(t.To), Version: change.Version, UpdatedAt: change.At}
+	if err := m.conf.Store.Save(ctx, m.conf.Name, id, rec, version); err != nil {
+		return change, err
+	}
+
+	// published before the hooks, so a failing hook doesn't lose the event of a saved state
+	var pubErr error
+	if m.conf.Publisher != nil {
+		_, pubErr = messaging.PublishEvent(ctx, m.conf.Publisher, m.conf.Topic, m.conf.Name+"."+string(event), m.conf.Source, change)
+	}
+
+	if err := run(ctx, onEnter, change); err != nil {
+		return change, errors.Join(pubErr, err)
+	}
+	if err := run(ctx, onTransition, change); err != nil {
+		return change, errors.Join(pubErr, err)
+	}
+	return change, pubErr
+}
+
+func (m *Machine[S, E]) find(state S, event E) (Transition[S, E], bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.findLocked(state, event)
+}
+
+func (m *Machine[S, E]) findLocked(state S, event E) (Transition[S, E], bool) {
+	for _, t := range m.transitions[event] {
+		for _, from := range t.From {
+			if from == state {
+				return t, true
+			}
+		}
+	}
+	return Transition[S, E]{}, false
+}
+
+func run[S ~string, E ~string](ctx context.Context, hs []Hook[S, E], c Change[S, E]) error {
+	for _, h := range hs {
+		if err := h(ctx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package fsm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/kv"
+)
+
+// Record is the saved state of one id
+type Record struct {
+	State     string    `json:"state"`
+	Version   int64     `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Store interface {
+	// Load returns the Record of id or an error of kind cerrors.NotFound
+	Load(ctx context.Context, machine, id string) (*Record, error)
+	// Save stores rec when the saved version is still expected (0 for a new id),
+	// otherwise it returns an error of kind cerrors.Conflict
+	Save(ctx context.Context, machine, id string, rec Record, expected int64) error
+}
+
+// KVStore keeps the Records as JSON under "{prefix}{machine}/{id}". The kv.Store has
+// no compare and swap, so the version check only protects against concurrent
+// transitions within one process; use PGSQLStore when several instances fire the same ids.
+type KVStore struct {
+	mu     sync.Mutex
+	store  kv.Store
+	prefix string
+}
+
+func NewKVStore(store kv.Store, prefix string) *KVStore {
+	return &KVStore{store: store, prefix: prefix}
+}
+
+func (ks *KVStore) Load(ctx context.Context, machine, id string) (*Record, error) {
+	data, err := ks.store.Get(ctx, ks.prefix+machine+"/"+id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rec Record
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "fsm.Load", err)
+	}
+	return &rec, nil
+}
+
+func (ks *KVStore) Save(ctx context.Context, machine, id string, rec Record, expected int64) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	current, err := ks.Load(ctx, machine, id)
+	switch {
+	case cerrors.Is(err, cerrors.NotFound):
+		if expected != 0 {
+			return conflict(machine, id)
+		}
+	case err != nil:
+		return err
+	case current.Version != expected:
+		return conflict(machine, id)
+	}
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return ks.store.Set(ctx, ks.prefix+machine+"/"+id, data, 0)
+}
+
+// PGSQLStore keeps the Records in a Postgres table with the columns
+// (machine, id, state, version, updated_at) and the primary key (machine, id)
+type PGSQLStore struct {
+	DB    *sql.DB
+	Table string
+}
+
+func (ps *PGSQLStore) table() string {
+	if ps.Table == "" {
+		return "fsm_states"
+	}
+	return ps.Table
+}
+
+func (ps *PGSQLStore) Load(ctx context.Context, machine, id string) (*Record, error) {
+	query := fmt.Sprintf(`SELECT state, version, updated_at FROM %s WHERE machine = $1 AND id = $2`, ps.table())
+
+	var rec Record
+	err := ps.DB.QueryRowContext(ctx, query, machine, id).Scan(&rec.State, &rec.Version, &rec.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, cerrors.New(cerrors.NotFound, "fsm: no state for "+machine+" "+id)
+	}
+	if err != nil {
+		log.Println("Erro to load fsm state in PGSQL")
+		return nil, err
+	}
+	return &rec, nil
+}
+
+func (ps *PGSQLStore) Save(ctx context.Context, machine, id string, rec Record, expected int64) error {
+	var (
+		result sql.Result
+		err    error
+	)
+	if expected == 0 {
+		query := fmt.Sprintf(`INSERT INTO %s (machine, id, state, version, updated_at)
+			VALUES ($1, $2, $3, $4, $5) ON CONFLICT (machine, id) DO NOTHING`, ps.table())
+		result, err = ps.DB.ExecContext(ctx, query, machine, id, rec.State, rec.Version, rec.UpdatedAt)
+	} else {
+		query := fmt.Sprintf(`UPDATE %s SET state = $3, version = $4, updated_at = $5
+			WHERE machine = $1 AND id = $2 AND version = $6`, ps.table())
+		result, err = ps.DB.ExecContext(ctx, query, machine, id, rec.State, rec.Version, rec.UpdatedAt, expected)
+	}
+	if err != nil {
+		log.Println("Erro to save fsm state in PGSQL")
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return conflict(machine, id)
+	}
+	return nil
+}
+
+func conflict(machine, id string) error {
+	return cerrors.New(cerrors.Conflict, "fsm: "+machine+" "+id+" was changed concurrently")
+}
@@ -0,0 +1,122 @@
+// Package geo has distance and bounding box calculations on the WGS84 sphere,
+// geohash encoding and a radius search over a kv.Store.
+//
+//	d := geo.Distance(store, customer)        // meters
+//	box := geo.BoundingBoxAround(store, 5000) // 5 km around store
+//	hash := geo.Encode(customer, 7)           // "6gyf4bf"
+package geo
+
+import (
+	"math"
+	"strconv"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// mean Earth radius in meters
+const EARTH_RADIUS = 6371008.8
+
+type Point struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// Validate checks that the latitude is in [-90, 90] and the longitude in [-180, 180]
+func (p Point) Validate() error {
+	if math.IsNaN(p.Lat) || p.Lat < -90 || p.Lat > 90 {
+		return cerrors.New(cerrors.Invalid, "geo: invalid latitude "+strconv.FormatFloat(p.Lat, 'f', -1, 64))
+	}
+	if math.IsNaN(p.Lon) || p.Lon < -180 || p.Lon > 180 {
+		return cerrors.New(cerrors.Invalid, "geo: invalid longitude "+strconv.FormatFloat(p.Lon, 'f', -1, 64))
+	}
+	return nil
+}
+
+// Distance returns the great circle distance in meters between a and b (haversine)
+func Distance(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLat, dLon := lat2-lat1, radians(b.Lon-a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EARTH_RADIUS * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Bearing returns the initial bearing in degrees from a to b, 0 is north and 90 east
+func Bearing(a, b Point) float64 {
+	lat1, lat2 := radians(a.Lat), radians(b.Lat)
+	dLon := radians(b.Lon - a.Lon)
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination returns the point at distance meters from p following bearing degrees
+func Destination(p Point, bearing, distance float64) Point {
+	lat1, lon1 := radians(p.Lat), radians(p.Lon)
+	angular, theta := distance/EARTH_RADIUS, radians(bearing)
+
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(angular) + math.Cos(lat1)*math.Sin(angular)*math.Cos(theta))
+	lon2 := lon1 + math.Atan2(math.Sin(theta)*math.Sin(angular)*math.Cos(lat1), math.Cos(angular)-math.Sin(lat1)*math.Sin(lat2))
+	return Point{Lat: degrees(lat2), Lon: normalizeLon(degrees(lon2))}
+}
+
+// Box is a latitude/longitude rectangle. Boxes crossing the antimeridian have Min.Lon > Max.Lon.
+type Box struct {
+	Min Point `json:"min"` // south west corner
+	Max Point `json:"max"` // north east corner
+}
+
+// BoundingBoxAround returns the smallest Box containing every point within radius meters of p
+func BoundingBoxAround(p Point, radius float64) Box {
+	dLat := degrees(radius / EARTH_RADIUS)
+	minLat, maxLat := p.Lat-dLat, p.Lat+dLat
+
+	// the circle reaches a pole, every longitude is inside
+	if minLat <= -90 || maxLat >= 90 {
+		return Box{
+			Min: Point{Lat: math.Max(minLat, -90), Lon: -180},
+			Max: Point{Lat: math.Min(maxLat, 90), Lon: 180},
+		}
+	}
+
+	dLon := degrees(math.Asin(math.Min(1, math.Sin(radius/EARTH_RADIUS)/math.Cos(radians(p.Lat)))))
+	if dLon >= 180 {
+		return Box{Min: Point{Lat: minLat, Lon: -180}, Max: Point{Lat: maxLat, Lon: 180}}
+	}
+	return Box{
+		Min: Point{Lat: minLat, Lon: normalizeLon(p.Lon - dLon)},
+		Max: Point{Lat: maxLat, Lon: normalizeLon(p.Lon + dLon)},
+	}
+}
+
+// Contains reports whether p is inside b, edges included
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.Min.Lat || p.Lat > b.Max.Lat {
+		return false
+	}
+	if b.Min.Lon <= b.Max.Lon {
+		return p.Lon >= b.Min.Lon && p.Lon <= b.Max.Lon
+	}
+	return p.Lon >= b.Min.Lon || p.Lon <= b.Max.Lon
+}
+
+// Center returns the middle of b
+func (b Box) Center() Point {
+	maxLon := b.Max.Lon
+	if b.Min.Lon > maxLon {
+		maxLon += 360
+	}
+	return Point{Lat: (b.Min.Lat + b.Max.Lat) / 2, Lon: normalizeLon((b.Min.Lon + maxLon) / 2)}
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
+
+// normalizeLon wraps a longitude to [-180, 180]
+func normalizeLon(lon float64) float64 {
+	if lon >= -180 && lon <= 180 {
+		return lon
+	}
+	return math.Mod(math.Mod(lon+180, 360)+360, 360) - 180
+}
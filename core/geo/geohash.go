@@ -0,0 +1,210 @@
+package geo
+
+import (
+	"math"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	GEOHASH_ALPHABET      = "0123456789bcdefghjkmnpqrstuvwxyz"
+	GEOHASH_MAX_PRECISION = 12
+)
+
+// Encode returns the geohash of p with precision characters, from 1 to 12
+func Encode(p Point, precision int) string {
+	if precision < 1 {
+		precision = 1
+	}
+	if precision > GEOHASH_MAX_PRECISION {
+		precision = GEOHASH_MAX_PRECISION
+	}
+
+	minLat, maxLat, minLon, maxLon := -90.0, 90.0, -180.0, 180.0
+	hash := make([]byte, 0, precision)
+	even := true // bits alternate starting with longitude
+	char, bit := 0, 0
+
+	for len(hash) < precision {
+		if even {
+			mid := (minLon + maxLon) / 2
+			if p.Lon >= mid {
+				char = char<<1 | 1
+				minLon = mid
+			} else {
+				char <<= 1
+				maxLon = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if p.Lat >= mid {
+				char = char<<1 | 1
+				minLat = mid
+			} else {
+				char <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		if bit++; bit == 5 {
+			hash = append(hash, GEOHASH_ALPHABET[char])
+			char, bit = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// Decode returns the cell of a geohash and its center
+func Decode(hash string) (Point, Box, error) {
+	box, err := DecodeBox(hash)
+	if err != nil {
+		return Point{}, Box{}, err
+	}
+	return box.Center(), box, nil
+}
+
+// DecodeBox returns the cell of a geohash
+func DecodeBox(hash string) (Box, error) {
+	if hash == "" || len(hash) > GEOHASH_MAX_PRECISION {
+		return Box{}, cerrors.New(cerrors.Invalid, "geo: invalid geohash length")
+	}
+
+	box := Box{Min: Point{Lat: -90, Lon: -180}, Max: Point{Lat: 90, Lon: 180}}
+	even := true
+	for _, r := range strings.ToLower(hash) {
+		char := strings.IndexRune(GEOHASH_ALPHABET, r)
+		if char < 0 {
+			return Box{}, cerrors.New(cerrors.Invalid, "geo: invalid geohash "+hash)
+		}
+
+		for mask := 16; mask > 0; mask >>= 1 {
+			if even {
+				mid := (box.Min.Lon + box.Max.Lon) / 2
+				if char&mask != 0 {
+					box.Min.Lon = mid
+				} else {
+					box.Max.Lon = mid
+				}
+			} else {
+				mid := (box.Min.Lat + box.Max.Lat) / 2
+				if char&mask != 0 {
+					box.Min.Lat = mid
+				} else {
+					box.Max.Lat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return box, nil
+}
+
+// Neighbors returns the geohashes of the 8 cells around hash, with the same precision.
+// Cells past the poles are left out.
+func Neighbors(hash string) ([]string, error) {
+	box, err := DecodeBox(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	center := box.Center()
+	height, width := box.Max.Lat-box.Min.Lat, box.Max.Lon-box.Min.Lon
+
+	var neighbors []string
+	for _, dLat := range []float64{1, 0, -1} {
+		for _, dLon := range []float64{-1, 0, 1} {
+			if dLat == 0 && dLon == 0 {
+				continue
+			}
+			lat := center.Lat + dLat*height
+			if lat < -90 || lat > 90 {
+				continue
+			}
+			neighbors = append(neighbors, Encode(Point{Lat: lat, Lon: normalizeLon(center.Lon + dLon*width)}, len(hash)))
+		}
+	}
+	return neighbors, nil
+}
+
+// PrecisionFor returns the largest geohash precision whose cells are at least as tall and
+// wide, in degrees, as the bounding box of radius around p, so the cell of p and its
+// Neighbors cover the radius. Radii whose box is larger than a cell of precision 1
+// (45 by 45 degrees), or reaching a pole, can't be covered that way and return 1;
+// Index.Nearby covers them with more cells.
+func PrecisionFor(p Point, radius float64) int {
+	height, width := boxSize(BoundingBoxAround(p, radius))
+	for precision := GEOHASH_MAX_PRECISION; precision > 1; precision-- {
+		latDeg, lonDeg := cellSize(precision)
+		if latDeg >= height && lonDeg >= width {
+			return precision
+		}
+	}
+	return 1
+}
+
+// maxCoverCells bounds the cells of cover, each one is a List on the Store
+const maxCoverCells = 9
+
+// cover returns the geohashes of the cells covering b, with the largest precision needing
+// at most maxCoverCells cells. When even precision 1 needs more it returns the empty
+// prefix, matching every cell.
+func cover(b Box) []string {
+	height, width := boxSize(b)
+
+	precision := 0
+	for pr := GEOHASH_MAX_PRECISION; pr >= 1; pr-- {
+		latDeg, lonDeg := cellSize(pr)
+		if (int(height/latDeg)+2)*(int(width/lonDeg)+2) <= maxCoverCells {
+			precision = pr
+			break
+		}
+	}
+	if precision == 0 {
+		return []string{""}
+	}
+
+	// steps no longer than a cell, so every cell crossed by b is visited
+	latDeg, lonDeg := cellSize(precision)
+	seen := map[string]bool{}
+	var cells []string
+	for dLat := 0.0; ; dLat += latDeg {
+		if dLat > height {
+			dLat = height
+		}
+		for dLon := 0.0; ; dLon += lonDeg {
+			if dLon > width {
+				dLon = width
+			}
+			cell := Encode(Point{Lat: b.Min.Lat + dLat, Lon: normalizeLon(b.Min.Lon + dLon)}, precision)
+			if !seen[cell] {
+				seen[cell] = true
+				cells = append(cells, cell)
+			}
+			if dLon == width {
+				break
+			}
+		}
+		if dLat == height {
+			break
+		}
+	}
+	return cells
+}
+
+// cellSize returns the height and width in degrees of the cells of precision
+func cellSize(precision int) (float64, float64) {
+	latBits := 5 * precision / 2
+	lonBits := 5*precision - latBits
+	return 180 / math.Pow(2, float64(latBits)), 360 / math.Pow(2, float64(lonBits))
+}
+
+// boxSize returns the height and width of b in degrees
+func boxSize(b Box) (float64, float64) {
+	width := b.Max.Lon - b.Min.Lon
+	if b.Min.Lon > b.Max.Lon {
+		width += 360
+	}
+	return b.Max.Lat - b.Min.Lat, width
+}
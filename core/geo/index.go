@@ -0,0 +1,135 @@
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/kv"
+)
+
+type Location struct {
+	ID    string `json:"id"`
+	Point Point  `json:"point"`
+}
+
+type Neighbor struct {
+	Location
+	Distance float64 `json:"distance"` // meters
+}
+
+// Index finds the locations near a point. Each location is stored in a kv.Store under
+// "{prefix}hash/{geohash}/{id}", so a search lists the geohash cells covering the radius
+// and filters them by distance. "{prefix}id/{id}" keeps the geohash to move or remove it.
+//
+// A search is up to 9 List calls on the Store, each one a SCAN on Redis, and reads every
+// location of the covered cells: it suits thousands of locations, beyond that use a store
+// with a geo index such as Redis GEOSEARCH.
+type Index struct {
+	store  kv.Store
+	prefix string
+}
+
+// NewIndex returns an Index keeping its keys under prefix in store, ex: "couriers:"
+func NewIndex(store kv.Store, prefix string) *Index {
+	return &Index{store: store, prefix: prefix}
+}
+
+// Put stores or moves the location of id. A ttl <= 0 never expires.
+func (idx *Index) Put(ctx context.Context, id string, p Point, ttl time.Duration) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	hash := Encode(p, GEOHASH_MAX_PRECISION)
+	if old, err := idx.store.Get(ctx, idx.idKey(id)); err == nil && string(old) != hash {
+		if err := idx.store.Delete(ctx, idx.hashKey(string(old), id)); err != nil {
+			return err
+		}
+	} else if err != nil && !cerrors.Is(err, cerrors.NotFound) {
+		return err
+	}
+
+	data, err := json.Marshal(Location{ID: id, Point: p})
+	if err != nil {
+		return err
+	}
+	if err := idx.store.Set(ctx, idx.hashKey(hash, id), data, ttl); err != nil {
+		return err
+	}
+	return idx.store.Set(ctx, idx.idKey(id), []byte(hash), ttl)
+}
+
+// Get returns the location of id or an error of kind NotFound
+func (idx *Index) Get(ctx context.Context, id string) (*Location, error) {
+	hash, err := idx.store.Get(ctx, idx.idKey(id))
+	if err != nil {
+		return nil, err
+	}
+	data, err := idx.store.Get(ctx, idx.hashKey(string(hash), id))
+	if err != nil {
+		return nil, err
+	}
+
+	var loc Location
+	if err := json.Unmarshal(data, &loc); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "geo.Get", err)
+	}
+	return &loc, nil
+}
+
+// Remove deletes the location of id, removing a missing id is not an error
+func (idx *Index) Remove(ctx context.Context, id string) error {
+	hash, err := idx.store.Get(ctx, idx.idKey(id))
+	if cerrors.Is(err, cerrors.NotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := idx.store.Delete(ctx, idx.hashKey(string(hash), id)); err != nil {
+		return err
+	}
+	return idx.store.Delete(ctx, idx.idKey(id))
+}
+
+// Nearby returns the locations within radius meters of p, nearest first.
+// A limit <= 0 returns all of them.
+func (idx *Index) Nearby(ctx context.Context, p Point, radius float64, limit int) ([]Neighbor, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var found []Neighbor
+	for _, cell := range cover(BoundingBoxAround(p, radius)) {
+		entries, err := idx.store.List(ctx, idx.prefix+"hash/"+cell)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			var loc Location
+			if err := json.Unmarshal(entry.Value, &loc); err != nil {
+				continue
+			}
+			if d := Distance(p, loc.Point); d <= radius {
+				found = append(found, Neighbor{Location: loc, Distance: d})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Distance < found[j].Distance })
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+func (idx *Index) hashKey(hash, id string) string {
+	return idx.prefix + "hash/" + hash + "/" + id
+}
+
+func (idx *Index) idKey(id string) string {
+	return idx.prefix + "id/" + id
+}
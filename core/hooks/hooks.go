@@ -0,0 +1,154 @@
+// Package hooks is the single observability extension point of the library.
+// Every adapter reports its operations here, so registering one Hooks
+// implementation wires logs, metrics and traces across all of them.
+//
+//	hooks.Register(hooks.Log{})
+package hooks
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/redact"
+)
+
+type Phase int
+
+const (
+	Start Phase = iota
+	End
+)
+
+type Event struct {
+	Phase     Phase
+	Component string // ex: "rabbitmq", "redisdb"
+	Operation string // ex: "Producer", "ReadData"
+	Attrs     map[string]interface{}
+	// set on End only
+	Duration time.Duration
+	Err      error
+}
+
+type Hooks interface {
+	OnOperation(ctx context.Context, ev Event)
+}
+
+// Func adapts a function to Hooks
+type Func func(ctx context.Context, ev Event)
+
+func (f Func) OnOperation(ctx context.Context, ev Event) { f(ctx, ev) }
+
+var (
+	lock       sync.RWMutex
+	registered []Hooks
+)
+
+// Register adds h to the hooks notified by every adapter
+func Register(h Hooks) {
+	lock.Lock()
+	defer lock.Unlock()
+	registered = append(registered, h)
+}
+
+// Reset removes every registered Hooks, mainly for tests
+func Reset() {
+	lock.Lock()
+	defer lock.Unlock()
+	registered = nil
+}
+
+// Begin notifies the Start of an operation and returns the function to call with its
+// result, which notifies the End. Adapters use it as:
+//
+//	end := hooks.Begin(ctx, "rabbitmq", "Producer", attrs)
+//	defer func() { end(err) }()
+func Begin(ctx context.Context, component, operation string, attrs map[string]interface{}) func(err error) {
+	end := BeginAttrs(ctx, component, operation, attrs)
+	return func(err error) { end(err, nil) }
+}
+
+// BeginAttrs is Begin for operations with attributes only known at the end, ex: the
+// request units of a query. They are added to a copy of attrs in the End event, attrs
+// must not be changed after Begin since hooks may still hold the Start event.
+func BeginAttrs(ctx context.Context, component, operation string, attrs map[string]interface{}) func(err error, end map[string]interface{}) {
+	lock.RLock()
+	hs := registered
+	lock.RUnlock()
+
+	if len(hs) == 0 {
+		return func(error, map[string]interface{}) {}
+	}
+
+	ev := Event{Phase: Start, Component: component, Operation: operation, Attrs: attrs}
+	for _, h := range hs {
+		h.OnOperation(ctx, ev)
+	}
+
+	started := time.Now()
+	return func(err error, end map[string]interface{}) {
+		ev.Phase = End
+		ev.Duration = time.Since(started)
+		ev.Err = err
+		if len(end) > 0 {
+			merged := make(map[string]interface{}, len(attrs)+len(end))
+			for k, v := range attrs {
+				merged[k] = v
+			}
+			for k, v := range end {
+				merged[k] = v
+			}
+			ev.Attrs = merged
+		}
+		for _, h := range hs {
+			h.OnOperation(ctx, ev)
+		}
+	}
+}
+
+// Log writes the End of every operation with the standard logger
+type Log struct {
+	// OnlyErrors skips successful operations
+	OnlyErrors bool
+	// Redact logs the attrs with these keys redacted by the default Redactor, ex: {"email": redact.Mask},
+	// besides the paths registered with redact.Register and the tags of struct attrs
+	Redact map[string]redact.Strategy
+}
+
+func (l Log) OnOperation(ctx context.Context, ev Event) {
+	if ev.Phase != End || (l.OnlyErrors && ev.Err == nil) {
+		return
+	}
+
+	if r := redact.Default(); len(ev.Attrs) > 0 {
+		attrs := make(map[string]interface{}, len(ev.Attrs))
+		for k, v := range ev.Attrs {
+			// structs are redacted by their own tags
+			if t := reflect.TypeOf(v); t != nil && (t.Kind() == reflect.Struct || (t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct)) {
+				if v, err := r.Value(ctx, v); err == nil {
+					attrs[k] = v
+				} else {
+					attrs[k] = redact.REDACTED
+				}
+				continue
+			}
+			attrs[k] = v
+		}
+
+		if len(l.Redact) > 0 || r.Registered() {
+			if err := r.Fields(ctx, attrs, l.Redact); err != nil {
+				// never log what couldn't be redacted
+				attrs = map[string]interface{}{"attrs": redact.REDACTED}
+			}
+		}
+		ev.Attrs = attrs
+	}
+
+	if ev.Err != nil {
+		log.Printf("%s.%s failed in %s %v: %s", ev.Component, ev.Operation, ev.Duration, ev.Attrs, ev.Err.Error())
+		return
+	}
+	log.Printf("%s.%s done in %s %v", ev.Component, ev.Operation, ev.Duration, ev.Attrs)
+}
@@ -0,0 +1,83 @@
+package httpmiddleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+type CORSOptions struct {
+	AllowedOrigins   []string // "*" allows any origin
+	AllowedMethods   []string // defaults to GET, POST, PUT, PATCH, DELETE, OPTIONS
+	AllowedHeaders   []string // defaults to the headers requested by the preflight
+	ExposedHeaders   []string
+	AllowCredentials bool // only for the origins listed explicitly, never for "*"
+	MaxAge           time.Duration
+}
+
+// CORS answers preflight requests and sets the CORS headers for allowed origins
+func CORS(opts CORSOptions) mux.MiddlewareFunc {
+	if len(opts.AllowedMethods) == 0 {
+		opts.AllowedMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+	}
+
+	// allowed reports whether origin is allowed and whether it was listed explicitly
+	allowed := func(origin string) (ok, listed bool) {
+		for _, o := range opts.AllowedOrigins {
+			if strings.EqualFold(o, origin) {
+				return true, true
+			}
+			if o == "*" {
+				ok = true
+			}
+		}
+		return ok, false
+	}
+	for _, o := range opts.AllowedOrigins {
+		if o == "*" && opts.AllowCredentials {
+			log.Println("CORS credentials are not allowed for the \"*\" origin")
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			ok, listed := allowed(origin)
+			if origin == "" || !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			h.Set("Access-Control-Allow-Origin", origin)
+			if opts.AllowCredentials && listed {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(opts.ExposedHeaders) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(opts.ExposedHeaders, ", "))
+			}
+
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// preflight
+			h.Set("Access-Control-Allow-Methods", strings.Join(opts.AllowedMethods, ", "))
+			if len(opts.AllowedHeaders) > 0 {
+				h.Set("Access-Control-Allow-Headers", strings.Join(opts.AllowedHeaders, ", "))
+			} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				h.Set("Access-Control-Allow-Headers", requested)
+			}
+			if opts.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(opts.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
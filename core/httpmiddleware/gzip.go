@@ -0,0 +1,96 @@
+package httpmiddleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/faelp22/go-commons-libs/core/bufpool"
+	"github.com/gorilla/mux"
+)
+
+type gzipWriter struct {
+	http.ResponseWriter
+	gz          *gzip.Writer
+	wroteHeader bool
+	compress    bool
+}
+
+func (gw *gzipWriter) WriteHeader(status int) {
+	if gw.wroteHeader {
+		return
+	}
+	gw.wroteHeader = true
+
+	h := gw.Header()
+	// already encoded bodies and bodiless responses are passed through
+	gw.compress = h.Get("Content-Encoding") == "" && status != http.StatusNoContent && status != http.StatusNotModified
+	if gw.compress {
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+	}
+	gw.ResponseWriter.WriteHeader(status)
+}
+
+func (gw *gzipWriter) Write(b []byte) (int, error) {
+	if !gw.wroteHeader {
+		if gw.Header().Get("Content-Type") == "" {
+			gw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		gw.WriteHeader(http.StatusOK)
+	}
+	if !gw.compress {
+		return gw.ResponseWriter.Write(b)
+	}
+	if gw.gz == nil {
+		gw.gz = bufpool.GetGzipWriter(gw.ResponseWriter)
+	}
+	return gw.gz.Write(b)
+}
+
+func (gw *gzipWriter) Flush() {
+	if gw.gz != nil {
+		gw.gz.Flush()
+	}
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack hands the connection over, ex: for websockets, nothing was compressed yet
+func (gw *gzipWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := gw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("httpmiddleware: the ResponseWriter doesn't support Hijack")
+	}
+	return h.Hijack()
+}
+
+func (gw *gzipWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// Gzip compresses the responses of clients accepting gzip
+func Gzip() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w}
+			defer func() {
+				if gw.gz != nil {
+					gw.gz.Close()
+					bufpool.PutGzipWriter(gw.gz)
+				}
+			}()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
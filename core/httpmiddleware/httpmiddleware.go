@@ -0,0 +1,91 @@
+// Package httpmiddleware has the usual middlewares of an HTTP server as mux.MiddlewareFunc.
+// Default builds the suite from config.Config:
+//
+//	r := mux.NewRouter()
+//	r.Use(ctxutil.Middleware())
+//	middlewares, maintenance := httpmiddleware.Default(conf)
+//	r.Use(middlewares...)
+package httpmiddleware
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	"github.com/gorilla/mux"
+)
+
+// Default returns, in order, the maintenance, IP allowlist, security headers, CORS,
+// body size limit, timeout and gzip middlewares. The optional ones are enabled by
+// SRV_HTTP_MAINTENANCE, SRV_HTTP_IP_ALLOWLIST (behind SRV_HTTP_TRUSTED_PROXIES),
+// SRV_HTTP_CORS_ORIGINS, SRV_HTTP_MAX_BODY_SIZE and SRV_HTTP_REQUEST_TIMEOUT. The Maintenance toggles
+// the maintenance mode at runtime.
+func Default(conf *config.Config) ([]mux.MiddlewareFunc, *Maintenance) {
+	if conf.HttpConfig == nil {
+		conf.HttpConfig = &config.HttpConfig{}
+	}
+
+	SRV_HTTP_CORS_ORIGINS := os.Getenv("SRV_HTTP_CORS_ORIGINS")
+	if SRV_HTTP_CORS_ORIGINS != "" {
+		conf.HTTP_CORS_ORIGINS = SRV_HTTP_CORS_ORIGINS
+	}
+
+	SRV_HTTP_MAX_BODY_SIZE := os.Getenv("SRV_HTTP_MAX_BODY_SIZE")
+	if SRV_HTTP_MAX_BODY_SIZE != "" {
+		conf.HTTP_MAX_BODY_SIZE, _ = strconv.ParseInt(SRV_HTTP_MAX_BODY_SIZE, 10, 64)
+	}
+
+	SRV_HTTP_REQUEST_TIMEOUT := os.Getenv("SRV_HTTP_REQUEST_TIMEOUT")
+	if SRV_HTTP_REQUEST_TIMEOUT != "" {
+		conf.HTTP_REQUEST_TIMEOUT, _ = strconv.Atoi(SRV_HTTP_REQUEST_TIMEOUT)
+	}
+
+	SRV_HTTP_IP_ALLOWLIST := os.Getenv("SRV_HTTP_IP_ALLOWLIST")
+	if SRV_HTTP_IP_ALLOWLIST != "" {
+		conf.HTTP_IP_ALLOWLIST = SRV_HTTP_IP_ALLOWLIST
+	}
+
+	SRV_HTTP_TRUSTED_PROXIES := os.Getenv("SRV_HTTP_TRUSTED_PROXIES")
+	if SRV_HTTP_TRUSTED_PROXIES != "" {
+		conf.HTTP_TRUSTED_PROXIES = SRV_HTTP_TRUSTED_PROXIES
+	}
+
+	SRV_HTTP_MAINTENANCE := os.Getenv("SRV_HTTP_MAINTENANCE")
+	if SRV_HTTP_MAINTENANCE != "" {
+		conf.HTTP_MAINTENANCE, _ = strconv.ParseBool(SRV_HTTP_MAINTENANCE)
+	}
+
+	maintenance := NewMaintenance()
+	maintenance.Set(conf.HTTP_MAINTENANCE)
+	middlewares := []mux.MiddlewareFunc{maintenance.Middleware()}
+
+	if conf.HTTP_IP_ALLOWLIST != "" {
+		middlewares = append(middlewares, IPAllowlistBehind(split(conf.HTTP_IP_ALLOWLIST), split(conf.HTTP_TRUSTED_PROXIES)))
+	}
+
+	middlewares = append(middlewares, SecurityHeaders(conf.Mode == config.PRODUCTION))
+
+	if conf.HTTP_CORS_ORIGINS != "" {
+		middlewares = append(middlewares, CORS(CORSOptions{AllowedOrigins: split(conf.HTTP_CORS_ORIGINS)}))
+	}
+	if conf.HTTP_MAX_BODY_SIZE > 0 {
+		middlewares = append(middlewares, MaxBodySize(conf.HTTP_MAX_BODY_SIZE))
+	}
+	if conf.HTTP_REQUEST_TIMEOUT > 0 {
+		middlewares = append(middlewares, Timeout(time.Duration(conf.HTTP_REQUEST_TIMEOUT)*time.Second))
+	}
+
+	return append(middlewares, Gzip()), maintenance
+}
+
+func split(list string) []string {
+	var out []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package httpmiddleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// IPAllowlist answers 403 to clients outside the list of IPs and CIDRs. trustProxy trusts
+// the X-Forwarded-For of any peer, enable it only when every request comes through a proxy
+// that sets it; IPAllowlistBehind trusts only the given proxies.
+func IPAllowlist(allowed []string, trustProxy bool) mux.MiddlewareFunc {
+	var proxies []string
+	if trustProxy {
+		proxies = []string{"0.0.0.0/0", "::/0"}
+	}
+	return IPAllowlistBehind(allowed, proxies)
+}
+
+// IPAllowlistBehind is IPAllowlist behind the proxies of trustedProxies (IPs or CIDRs).
+// The client is the last address of X-Forwarded-For not added by a trusted proxy, so a
+// client can't pass for another one by sending its own header.
+func IPAllowlistBehind(allowed, trustedProxies []string) mux.MiddlewareFunc {
+	nets := parseNets(allowed)
+	proxies := parseNets(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r, proxies)
+			if ip != nil && contains(nets, ip) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		})
+	}
+}
+
+func parseNets(list []string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, a := range list {
+		if !strings.Contains(a, "/") {
+			if strings.Contains(a, ":") {
+				a += "/128"
+			} else {
+				a += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(a)
+		if err != nil {
+			log.Println("Erro to parse IP allowlist entry:", a)
+			continue
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP walks X-Forwarded-For from the right while the hops are trusted proxies
+func clientIP(r *http.Request, proxies []*net.IPNet) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !contains(proxies, ip) {
+		return ip
+	}
+
+	fwd := strings.Join(r.Header.Values("X-Forwarded-For"), ",")
+	if fwd == "" {
+		return ip
+	}
+	hops := strings.Split(fwd, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			return nil
+		}
+		ip = hop
+		if !contains(proxies, ip) {
+			break
+		}
+	}
+	return ip
+}
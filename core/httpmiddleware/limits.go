@@ -0,0 +1,139 @@
+package httpmiddleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// MaxBodySize rejects requests declaring a bigger Content-Length with 413 and makes
+// reading past limit bytes of the body fail
+func MaxBodySize(limit int64) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout answers 503 when the handler takes longer than d. The handler keeps its
+// request context, canceled at d, to stop its work. The response is buffered until the
+// handler returns or flushes it, a response already flushed is cut at d instead.
+func Timeout(d time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, h: http.Header{}}
+			done := make(chan struct{})
+			panicked := make(chan interface{}, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p)
+			case <-done:
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.commit()
+			case <-ctx.Done():
+				tw.mu.Lock()
+				defer tw.mu.Unlock()
+				tw.timedOut = true
+				if !tw.committed {
+					http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+				}
+			}
+		})
+	}
+}
+
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	h           http.Header
+	mu          sync.Mutex
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	committed   bool // the buffer was sent by Flush, later writes go straight to w
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) WriteHeader(status int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.status = status
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !tw.wroteHeader {
+		tw.wroteHeader = true
+		tw.status = http.StatusOK
+	}
+	if tw.committed {
+		return tw.w.Write(b)
+	}
+	return tw.buf.Write(b)
+}
+
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.commit()
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// commit sends the header and the buffered body, mu must be held
+func (tw *timeoutWriter) commit() {
+	if tw.committed {
+		return
+	}
+	tw.committed = true
+
+	dst := tw.w.Header()
+	for k, v := range tw.h {
+		dst[k] = v
+	}
+	if !tw.wroteHeader {
+		tw.status = http.StatusOK
+	}
+	tw.w.WriteHeader(tw.status)
+	tw.w.Write(tw.buf.Bytes())
+	tw.buf.Reset()
+}
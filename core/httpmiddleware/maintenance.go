@@ -0,0 +1,43 @@
+package httpmiddleware
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gorilla/mux"
+)
+
+// Maintenance answers 503 with Retry-After while enabled. It can be toggled at
+// runtime, for example from an admin endpoint.
+type Maintenance struct {
+	enabled atomic.Bool
+	// Skip lets requests through during maintenance, usually health checks
+	Skip func(r *http.Request) bool
+	// RetryAfter is sent in seconds, defaults to 120
+	RetryAfter string
+}
+
+func NewMaintenance() *Maintenance {
+	return &Maintenance{RetryAfter: "120"}
+}
+
+func (m *Maintenance) Set(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+func (m *Maintenance) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.enabled.Load() || (m.Skip != nil && m.Skip(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Retry-After", m.RetryAfter)
+			http.Error(w, "service under maintenance", http.StatusServiceUnavailable)
+		})
+	}
+}
@@ -0,0 +1,308 @@
+// Package i18n holds the message catalogs of a service, one JSON file per locale:
+//
+//	// locales/pt-BR.json
+//	{
+//	  "greeting": "Olá, %s",
+//	  "cart.items": {"one": "%d item", "other": "%d itens"},
+//	  "errors.not_found": "Recurso não encontrado"
+//	}
+//
+//	b := i18n.NewBundle("en")
+//	err := b.LoadFS(locales, "locales")
+//	i18n.SetDefault(b)
+//	r.Use(i18n.Middleware(b)) // negotiates Accept-Language
+//	msg := b.Plural(ctx, "cart.items", 3)
+//
+// Messages are fmt formats. Missing keys fall back to the default locale and then to the key.
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Plural categories, as in CLDR
+const (
+	Zero  = "zero"
+	One   = "one"
+	Two   = "two"
+	Few   = "few"
+	Many  = "many"
+	Other = "other"
+)
+
+// Message is a translation, either a single format or one per plural category
+type Message map[string]string
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*m = Message{Other: s}
+		return nil
+	}
+
+	forms := map[string]string{}
+	if err := json.Unmarshal(data, &forms); err != nil {
+		return err
+	}
+	*m = forms
+	return nil
+}
+
+// Blobs is the part of a blob container client LoadBlobs needs
+type Blobs interface {
+	List(ctx context.Context, prefix string) ([]string, error)
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+type Bundle struct {
+	defaultLocale string
+
+	mu       sync.RWMutex
+	catalogs map[string]map[string]Message // locale -> key -> Message
+}
+
+func NewBundle(defaultLocale string) *Bundle {
+	return &Bundle{defaultLocale: Canonical(defaultLocale), catalogs: map[string]map[string]Message{}}
+}
+
+// AddMessages merges messages into the catalog of locale
+func (b *Bundle) AddMessages(locale string, messages map[string]Message) {
+	locale = Canonical(locale)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	catalog, ok := b.catalogs[locale]
+	if !ok {
+		catalog = map[string]Message{}
+		b.catalogs[locale] = catalog
+	}
+	for k, m := range messages {
+		catalog[k] = m
+	}
+}
+
+// LoadJSON merges a JSON catalog into locale
+func (b *Bundle) LoadJSON(locale string, data []byte) error {
+	messages := map[string]Message{}
+	if err := json.Unmarshal(data, &messages); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "i18n: catalog "+locale, err)
+	}
+	b.AddMessages(locale, messages)
+	return nil
+}
+
+// LoadFS loads every "{locale}.json" file of dir, ex: an embed.FS
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "i18n.LoadFS", err)
+	}
+
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return cerrors.Wrap(cerrors.Invalid, "i18n.LoadFS", err)
+		}
+		if err := b.LoadJSON(strings.TrimSuffix(path.Base(file), ".json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadBlobs loads every "{locale}.json" blob below prefix
+func (b *Bundle) LoadBlobs(ctx context.Context, blobs Blobs, prefix string) error {
+	names, err := blobs.List(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if path.Ext(name) != ".json" {
+			continue
+		}
+		data, err := blobs.Get(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := b.LoadJSON(strings.TrimSuffix(path.Base(name), ".json"), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Locales returns the locales with a catalog, sorted
+func (b *Bundle) Locales() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	locales := make([]string, 0, len(b.catalogs))
+	for l := range b.catalogs {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Lookup returns the message of key in the locale of ctx, falling back to its base
+// language and then to the default locale
+func (b *Bundle) Lookup(ctx context.Context, key string) (Message, string, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, locale := range b.fallbacks(Locale(ctx)) {
+		if m, ok := b.catalogs[locale][key]; ok {
+			return m, locale, true
+		}
+	}
+	return nil, "", false
+}
+
+// T formats the message of key with args, returning the key when it's missing
+func (b *Bundle) T(ctx context.Context, key string, args ...interface{}) string {
+	m, _, ok := b.Lookup(ctx, key)
+	if !ok {
+		return key
+	}
+	return format(m[Other], args)
+}
+
+// Plural formats the form of key matching n, with n as first argument followed by args
+func (b *Bundle) Plural(ctx context.Context, key string, n interface{}, args ...interface{}) string {
+	m, locale, ok := b.Lookup(ctx, key)
+	if !ok {
+		return key
+	}
+
+	form, ok := m[PluralCategory(locale, toFloat(n))]
+	if !ok {
+		form = m[Other]
+	}
+	return format(form, append([]interface{}{n}, args...))
+}
+
+// Translate implements templates.Translator. A numeric first argument selects the plural form.
+func (b *Bundle) Translate(ctx context.Context, key string, args ...interface{}) string {
+	if len(args) > 0 && isNumber(args[0]) {
+		return b.Plural(ctx, key, args[0], args[1:]...)
+	}
+	return b.T(ctx, key, args...)
+}
+
+// Error returns the translated message of err under "errors.{kind}", ex: "errors.not_found",
+// or err.Error() when there is none
+func (b *Bundle) Error(ctx context.Context, err error) string {
+	if m, _, ok := b.Lookup(ctx, "errors."+cerrors.KindOf(err).String()); ok {
+		return m[Other]
+	}
+	return err.Error()
+}
+
+// fallbacks returns locale, its base language and the default locale, without duplicates
+func (b *Bundle) fallbacks(locale string) []string {
+	out := make([]string, 0, 3)
+	add := func(l string) {
+		for _, o := range out {
+			if o == l {
+				return
+			}
+		}
+		out = append(out, l)
+	}
+
+	if locale != "" {
+		add(locale)
+		add(Base(locale))
+	}
+	add(b.defaultLocale)
+	add(Base(b.defaultLocale))
+	return out
+}
+
+var (
+	defaultLock   sync.RWMutex
+	defaultBundle *Bundle
+)
+
+// SetDefault makes b the Bundle used by the library components, ex: openapi problems
+func SetDefault(b *Bundle) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultBundle = b
+}
+
+// Default returns the Bundle set with SetDefault, nil when there is none
+func Default() *Bundle {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return defaultBundle
+}
+
+// Text translates key with the default Bundle, returning fallback when it's missing
+func Text(ctx context.Context, key, fallback string) string {
+	b := Default()
+	if b == nil {
+		return fallback
+	}
+	m, _, ok := b.Lookup(ctx, key)
+	if !ok {
+		return fallback
+	}
+	return m[Other]
+}
+
+func format(msg string, args []interface{}) string {
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int8:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	case uint16:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	case float32:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,155 @@
+package i18n
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type ctxKey struct{}
+
+// WithLocale returns a copy of ctx carrying locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, Canonical(locale))
+}
+
+// Locale returns the locale of ctx, "" when not set
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(ctxKey{}).(string)
+	return locale
+}
+
+// Canonical normalizes a language tag, ex: "pt_br" -> "pt-BR"
+func Canonical(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// Base returns the language of a locale, ex: "pt-BR" -> "pt"
+func Base(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header by preference
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var list []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			list = append(list, weighted{locale: Canonical(tag), q: q})
+		}
+	}
+
+	sort.SliceStable(list, func(i, j int) bool { return list[i].q > list[j].q })
+
+	locales := make([]string, len(list))
+	for i, w := range list {
+		locales[i] = w.locale
+	}
+	return locales
+}
+
+// Match returns the best locale of b for an Accept-Language header: an exact match,
+// then a match of the base language, then the default locale
+func (b *Bundle) Match(header string) string {
+	available := b.Locales()
+
+	for _, wanted := range ParseAcceptLanguage(header) {
+		for _, l := range available {
+			if l == wanted {
+				return l
+			}
+		}
+		for _, l := range available {
+			if Base(l) == Base(wanted) {
+				return l
+			}
+		}
+	}
+	return b.defaultLocale
+}
+
+// Middleware stores in the request context the locale negotiated from Accept-Language
+// and reports it in Content-Language
+func Middleware(b *Bundle) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := b.Match(r.Header.Get("Accept-Language"))
+			w.Header().Set("Content-Language", locale)
+			next.ServeHTTP(w, r.WithContext(WithLocale(r.Context(), locale)))
+		})
+	}
+}
+
+// PluralCategory returns the CLDR plural category of n for the language of locale.
+// Languages without specific rules use the English ones.
+func PluralCategory(locale string, n float64) string {
+	i := math.Floor(math.Abs(n))
+	integer := i == math.Abs(n)
+
+	switch Base(locale) {
+	case "ja", "zh", "ko", "vi", "th", "id":
+		return Other
+	case "fr":
+		if i == 0 || i == 1 {
+			return One
+		}
+	case "pt":
+		if locale == "pt-PT" {
+			if n == 1 {
+				return One
+			}
+			return Other
+		}
+		if i == 0 || i == 1 {
+			return One
+		}
+	case "ru", "uk", "pl":
+		if !integer {
+			return Other
+		}
+		mod10, mod100 := math.Mod(i, 10), math.Mod(i, 100)
+		switch {
+		case mod10 == 1 && mod100 != 11 && Base(locale) != "pl":
+			return One
+		case i == 1:
+			return One
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return Few
+		default:
+			return Many
+		}
+	default:
+		if n == 1 {
+			return One
+		}
+	}
+	return Other
+}
@@ -0,0 +1,271 @@
+// Package lifecycle coordinates the graceful shutdown of a service in ordered phases.
+//
+//	lc := lifecycle.New(conf)
+//	lc.OnStart("rabbitmq", rbm.Start)
+//	lc.OnStartOf("redis", rdb)
+//	if err := lc.Start(ctx); err != nil { log.Fatal(err) }
+//	lc.OnShutdown(lifecycle.PhaseHTTP, "http", lifecycle.HTTPServer(srv))
+//	lc.OnShutdown(lifecycle.PhaseConsumers, "rabbitmq", rbm.Drain)
+//	lc.OnShutdown(lifecycle.PhaseFlush, "audit", auditor.Close)
+//	lc.OnShutdown(lifecycle.PhaseConnections, "rabbitmq", rbm.Close)
+//	lc.Wait(context.Background())
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+)
+
+const DEFAULT_SHUTDOWN_PHASE_TIMEOUT = 10 // seconds
+
+// Default shutdown phases, executed in this order
+const (
+	PhaseHTTP        = "http"        // stop accepting HTTP requests
+	PhaseConsumers   = "consumers"   // drain message consumers
+	PhaseFlush       = "flush"       // flush outbox, audit and other buffers
+	PhaseConnections = "connections" // close broker, database and cache connections
+)
+
+type hook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+type phase struct {
+	name    string
+	timeout time.Duration
+	hooks   []hook
+}
+
+type Coordinator struct {
+	mu      sync.Mutex
+	phases  []*phase
+	starts  []hook
+	timeout time.Duration
+	once    sync.Once
+	err     error
+}
+
+func New(conf *config.Config) *Coordinator {
+	if conf.LifecycleConfig == nil {
+		conf.LifecycleConfig = &config.LifecycleConfig{}
+	}
+
+	SRV_SHUTDOWN_PHASE_TIMEOUT := os.Getenv("SRV_SHUTDOWN_PHASE_TIMEOUT")
+	if SRV_SHUTDOWN_PHASE_TIMEOUT != "" {
+		conf.SHUTDOWN_PHASE_TIMEOUT, _ = strconv.Atoi(SRV_SHUTDOWN_PHASE_TIMEOUT)
+	}
+	if conf.SHUTDOWN_PHASE_TIMEOUT <= 0 {
+		conf.SHUTDOWN_PHASE_TIMEOUT = DEFAULT_SHUTDOWN_PHASE_TIMEOUT
+	}
+
+	timeout := time.Duration(conf.SHUTDOWN_PHASE_TIMEOUT) * time.Second
+
+	c := &Coordinator{timeout: timeout}
+	for _, name := range []string{PhaseHTTP, PhaseConsumers, PhaseFlush, PhaseConnections} {
+		c.phases = append(c.phases, &phase{name: name, timeout: timeout})
+	}
+
+	return c
+}
+
+// AddPhase appends a custom phase after the existing ones
+func (c *Coordinator) AddPhase(name string, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.phases = append(c.phases, &phase{name: name, timeout: timeout})
+}
+
+// SetTimeout changes the timeout of a phase
+func (c *Coordinator) SetTimeout(phaseName string, timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p := c.phase(phaseName); p != nil {
+		p.timeout = timeout
+	}
+}
+
+// OnShutdown registers fn in a phase. Hooks of the same phase run concurrently.
+func (c *Coordinator) OnShutdown(phaseName, name string, fn func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := c.phase(phaseName)
+	if p == nil {
+		panic("lifecycle: unknown phase " + phaseName)
+	}
+	p.hooks = append(p.hooks, hook{name: name, fn: fn})
+}
+
+func (c *Coordinator) phase(name string) *phase {
+	for _, p := range c.phases {
+		if p.name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// OnStart registers a warmup fn (connect, declare topology, check containers...).
+// Start runs them in registration order.
+func (c *Coordinator) OnStart(name string, fn func(ctx context.Context) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.starts = append(c.starts, hook{name: name, fn: fn})
+}
+
+// Starter is implemented by the adapters with a warmup. The adapter interfaces don't
+// include it, so implementations outside this library don't have to provide it.
+type Starter interface {
+	Start(ctx context.Context) error
+}
+
+// OnStartOf registers the Start of v when v is a Starter and reports whether it was
+func (c *Coordinator) OnStartOf(name string, v interface{}) bool {
+	s, ok := v.(Starter)
+	if ok {
+		c.OnStart(name, s.Start)
+	}
+	return ok
+}
+
+// Start runs the warmup hooks in order, each bounded by the phase timeout,
+// and stops on the first failure
+func (c *Coordinator) Start(ctx context.Context) error {
+	c.mu.Lock()
+	starts := append([]hook(nil), c.starts...)
+	c.mu.Unlock()
+
+	for _, h := range starts {
+		start := time.Now()
+		hctx, cancel := context.WithTimeout(ctx, c.timeout)
+		err := h.fn(hctx)
+		cancel()
+		if err != nil {
+			log.Printf("Startup %s failed: %s", h.name, err.Error())
+			return fmt.Errorf("start/%s: %w", h.name, err)
+		}
+		log.Printf("Startup %s: done in %s", h.name, time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// LazyConnect reports whether adapters should skip connecting in New and connect on
+// Start or first use instead. Configured by SRV_LAZY_CONNECT.
+func LazyConnect(conf *config.Config) bool {
+	if conf.LifecycleConfig == nil {
+		conf.LifecycleConfig = &config.LifecycleConfig{}
+	}
+
+	SRV_LAZY_CONNECT := os.Getenv("SRV_LAZY_CONNECT")
+	if SRV_LAZY_CONNECT != "" {
+		conf.LAZY_CONNECT, _ = strconv.ParseBool(SRV_LAZY_CONNECT)
+	}
+	return conf.LAZY_CONNECT
+}
+
+// Wait blocks until SIGINT/SIGTERM or ctx is done and then runs Shutdown
+func (c *Coordinator) Wait(ctx context.Context) error {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	select {
+	case s := <-sig:
+		log.Println("Received signal", s.String(), "starting graceful shutdown")
+	case <-ctx.Done():
+		log.Println("Context done, starting graceful shutdown")
+	}
+
+	return c.Shutdown(context.Background())
+}
+
+// Shutdown runs every phase in order, each bounded by its own timeout.
+// A failing phase doesn't stop the next ones. It only runs once.
+func (c *Coordinator) Shutdown(ctx context.Context) error {
+	c.once.Do(func() {
+		c.mu.Lock()
+		phases := append([]*phase(nil), c.phases...)
+		c.mu.Unlock()
+
+		var errs []error
+		for _, p := range phases {
+			if len(p.hooks) == 0 {
+				continue
+			}
+			if err := c.runPhase(ctx, p); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		log.Println("Graceful shutdown finished")
+		c.err = errors.Join(errs...)
+	})
+
+	return c.err
+}
+
+func (c *Coordinator) runPhase(ctx context.Context, p *phase) error {
+	log.Printf("Shutdown phase %s: running %d hook(s)", p.name, len(p.hooks))
+	start := time.Now()
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+
+	for _, h := range p.hooks {
+		wg.Add(1)
+		go func(h hook) {
+			defer wg.Done()
+			if err := h.fn(ctx); err != nil {
+				log.Printf("Shutdown phase %s: %s failed: %s", p.name, h.name, err.Error())
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("%s/%s: %w", p.name, h.name, err))
+				mu.Unlock()
+			}
+		}(h)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Printf("Shutdown phase %s: done in %s", p.name, time.Since(start).Round(time.Millisecond))
+	case <-ctx.Done():
+		log.Printf("Shutdown phase %s: timeout after %s", p.name, p.timeout)
+		mu.Lock()
+		errs = append(errs, fmt.Errorf("%s: %w", p.name, ctx.Err()))
+		mu.Unlock()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// HTTPServer returns a hook that stops srv from accepting connections and waits for active requests
+func HTTPServer(srv *http.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return srv.Shutdown(ctx)
+	}
+}
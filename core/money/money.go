@@ -0,0 +1,382 @@
+// Package money represents amounts as an integer of minor units (cents) of a currency,
+// so arithmetic never goes through float64:
+//
+//	price, _ := money.Parse("19.90", "BRL")
+//	total, _ := price.Mul("3")                   // 59.70
+//	tax, _ := total.MulRound("0.0825", money.HalfEven)
+//	parts := total.Split(3)                      // 19.90, 19.90, 19.90
+//	total.Format("pt-BR")                        // "R$ 59,70"
+//
+// Money marshals to JSON as {"amount":"59.70","currency":"BRL"} and to SQL as "59.70 BRL".
+package money
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type Rounding int
+
+const (
+	HalfEven Rounding = iota // banker's rounding
+	HalfUp                   // half away from zero
+	Down                     // towards zero
+	Up                       // away from zero
+)
+
+type Currency struct {
+	Code   string
+	Digits int // minor unit digits
+	Symbol string
+}
+
+var currencies = map[string]Currency{
+	"BRL": {"BRL", 2, "R$"},
+	"USD": {"USD", 2, "$"},
+	"EUR": {"EUR", 2, "€"},
+	"GBP": {"GBP", 2, "£"},
+	"ARS": {"ARS", 2, "$"},
+	"CLP": {"CLP", 0, "$"},
+	"COP": {"COP", 2, "$"},
+	"MXN": {"MXN", 2, "$"},
+	"JPY": {"JPY", 0, "¥"},
+	"CHF": {"CHF", 2, "CHF"},
+	"BHD": {"BHD", 3, "BD"},
+	"KWD": {"KWD", 3, "KD"},
+}
+
+// RegisterCurrency adds or replaces a currency, call it during init
+func RegisterCurrency(c Currency) {
+	currencies[strings.ToUpper(c.Code)] = c
+}
+
+// LookupCurrency returns the currency of an ISO 4217 code
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, cerrors.New(cerrors.Invalid, "money: unknown currency "+code)
+	}
+	return c, nil
+}
+
+type Money struct {
+	minor    int64
+	currency string
+}
+
+// New returns minor units of a currency, ex: New(1990, "BRL") is R$ 19,90
+func New(minor int64, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{minor: minor, currency: c.Code}, nil
+}
+
+// Parse reads a decimal amount, ex: "19.90" or "-3". More digits than the currency
+// allows are an error, use ParseRound to round them.
+func Parse(amount, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, cerrors.New(cerrors.Invalid, "money: invalid amount "+amount)
+	}
+	minor := new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(c.Digits)))
+	if !minor.IsInt() {
+		return Money{}, cerrors.New(cerrors.Invalid, fmt.Sprintf("money: %s has more than %d decimals", amount, c.Digits))
+	}
+	return fromInt(minor.Num(), c.Code)
+}
+
+// ParseRound reads a decimal amount rounding the digits the currency doesn't have
+func ParseRound(amount, currency string, mode Rounding) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return Money{}, cerrors.New(cerrors.Invalid, "money: invalid amount "+amount)
+	}
+	return fromInt(round(new(big.Rat).Mul(r, new(big.Rat).SetInt(pow10(c.Digits))), mode), c.Code)
+}
+
+// Zero returns no money of currency
+func Zero(currency string) Money {
+	c, _ := LookupCurrency(currency)
+	return Money{currency: c.Code}
+}
+
+func (m Money) Minor() int64        { return m.minor }
+func (m Money) Currency() string    { return m.currency }
+func (m Money) IsZero() bool        { return m.minor == 0 }
+func (m Money) IsNegative() bool    { return m.minor < 0 }
+func (m Money) Neg() Money          { return Money{minor: -m.minor, currency: m.currency} }
+func (m Money) sameAs(o Money) bool { return m.currency == o.currency }
+
+func (m Money) digits() int {
+	c, _ := LookupCurrency(m.currency)
+	return c.Digits
+}
+
+func (m Money) Add(o Money) (Money, error) {
+	if !m.sameAs(o) {
+		return Money{}, mismatch(m, o)
+	}
+	return fromInt(new(big.Int).Add(big.NewInt(m.minor), big.NewInt(o.minor)), m.currency)
+}
+
+func (m Money) Sub(o Money) (Money, error) {
+	if !m.sameAs(o) {
+		return Money{}, mismatch(m, o)
+	}
+	return fromInt(new(big.Int).Sub(big.NewInt(m.minor), big.NewInt(o.minor)), m.currency)
+}
+
+// Cmp returns -1, 0 or +1 comparing m and o
+func (m Money) Cmp(o Money) (int, error) {
+	if !m.sameAs(o) {
+		return 0, mismatch(m, o)
+	}
+	switch {
+	case m.minor < o.minor:
+		return -1, nil
+	case m.minor > o.minor:
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Mul multiplies by a decimal factor, ex: "3" or "1.5", rounding half to even
+func (m Money) Mul(factor string) (Money, error) {
+	return m.MulRound(factor, HalfEven)
+}
+
+// MulRound multiplies by a decimal factor rounding the result with mode
+func (m Money) MulRound(factor string, mode Rounding) (Money, error) {
+	f, ok := new(big.Rat).SetString(strings.TrimSpace(factor))
+	if !ok {
+		return Money{}, cerrors.New(cerrors.Invalid, "money: invalid factor "+factor)
+	}
+	return fromInt(round(new(big.Rat).Mul(new(big.Rat).SetInt64(m.minor), f), mode), m.currency)
+}
+
+// Split divides m in n parts differing by at most one minor unit, the first parts get the remainder
+func (m Money) Split(n int) []Money {
+	if n <= 0 {
+		return nil
+	}
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+	return m.Allocate(ratios...)
+}
+
+// Allocate divides m proportionally to ratios without losing minor units,
+// ex: Allocate(70, 30). The remainder goes one unit at a time to the first parts.
+// The shares are computed with big.Int, as m.minor * ratio may not fit an int64.
+func (m Money) Allocate(ratios ...int) []Money {
+	total := new(big.Int)
+	for _, r := range ratios {
+		if r > 0 {
+			total.Add(total, big.NewInt(int64(r)))
+		}
+	}
+	if total.Sign() == 0 {
+		return nil
+	}
+
+	minor := big.NewInt(m.minor)
+	parts := make([]Money, len(ratios))
+	remainder := m.minor
+	for i, r := range ratios {
+		share := int64(0)
+		if r > 0 {
+			// |share| <= |m.minor| as r <= total, so it fits
+			share = new(big.Int).Quo(new(big.Int).Mul(minor, big.NewInt(int64(r))), total).Int64()
+		}
+		parts[i] = Money{minor: share, currency: m.currency}
+		remainder -= share
+	}
+
+	unit := int64(1)
+	if remainder < 0 {
+		unit = -1
+	}
+	for i := 0; remainder != 0; i = (i + 1) % len(parts) {
+		if ratios[i] > 0 {
+			parts[i].minor += unit
+			remainder -= unit
+		}
+	}
+	return parts
+}
+
+// Decimal returns the amount as a decimal string, ex: "-1234.50"
+func (m Money) Decimal() string {
+	digits := m.digits()
+	minor := m.minor
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+
+	s := new(big.Int).Abs(big.NewInt(minor)).String()
+	if digits == 0 {
+		return sign + s
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency
+}
+
+// Format returns m with the currency symbol and the separators of locale,
+// ex: "R$ 1.234,50" for pt-BR and "$1,234.50" for en-US
+func (m Money) Format(locale string) string {
+	c, _ := LookupCurrency(m.currency)
+	whole, frac, _ := strings.Cut(strings.TrimPrefix(m.Decimal(), "-"), ".")
+
+	thousands, decimal, space := ",", ".", ""
+	lang := strings.ToLower(strings.SplitN(strings.ReplaceAll(locale, "_", "-"), "-", 2)[0])
+	switch lang {
+	case "pt", "es", "de", "it", "id", "nl":
+		thousands, decimal, space = ".", ",", " "
+	case "fr":
+		thousands, decimal, space = " ", ",", " "
+	}
+
+	var b strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(r)
+	}
+	number := b.String()
+	if frac != "" {
+		number += decimal + frac
+	}
+
+	sign := ""
+	if m.minor < 0 {
+		sign = "-"
+	}
+	if lang == "fr" || lang == "de" {
+		return sign + number + space + c.Symbol
+	}
+	return sign + c.Symbol + space + number
+}
+
+type jsonMoney struct {
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonMoney{Amount: m.Decimal(), Currency: m.currency})
+}
+
+// UnmarshalJSON leaves m unchanged on null, like encoding/json does for other types
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if string(bytes.TrimSpace(data)) == "null" {
+		return nil
+	}
+	var j jsonMoney
+	if err := json.Unmarshal(data, &j); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "money.UnmarshalJSON", err)
+	}
+	parsed, err := Parse(j.Amount, j.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores m as "{decimal} {currency}", ex: "19.90 BRL"
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func (m *Money) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return cerrors.New(cerrors.Invalid, fmt.Sprintf("money: cannot scan %T", src))
+	}
+
+	amount, currency, ok := strings.Cut(strings.TrimSpace(s), " ")
+	if !ok {
+		return cerrors.New(cerrors.Invalid, "money: cannot scan "+s)
+	}
+	parsed, err := Parse(amount, currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// round converts r to an integer with mode
+func round(r *big.Rat, mode Rounding) *big.Int {
+	q, rem := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	away := big.NewInt(int64(r.Sign()))
+	switch mode {
+	case Down:
+		return q
+	case Up:
+		return q.Add(q, away)
+	}
+
+	// compare 2*|rem| with the denominator to know if it's past half
+	twice := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2))
+	switch twice.Cmp(r.Denom()) {
+	case 1:
+		return q.Add(q, away)
+	case 0:
+		if mode == HalfUp || q.Bit(0) == 1 {
+			return q.Add(q, away)
+		}
+	}
+	return q
+}
+
+func fromInt(minor *big.Int, currency string) (Money, error) {
+	if !minor.IsInt64() {
+		return Money{}, cerrors.New(cerrors.Invalid, "money: amount overflows")
+	}
+	return Money{minor: minor.Int64(), currency: currency}, nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func mismatch(a, b Money) error {
+	return cerrors.New(cerrors.Invalid, fmt.Sprintf("money: currency mismatch %s and %s", a.currency, b.currency))
+}
@@ -0,0 +1,376 @@
+// Package redact masks personal data before it reaches logs, audit records or message
+// captures. Fields are marked with a strategy in a struct tag:
+//
+//	type Customer struct {
+//		Phone    string `json:"phone" redact:"mask"`       // "*********4321"
+//		Email    string `json:"email" redact:"mask"`       // "j***@example.com"
+//		Document string `json:"document" redact:"hash"`    // "h:3f79bb7b435b05321651daefd374cdc6"
+//		Card     string `json:"card" redact:"token"`       // "tok_9b74c9897bac770ffc029102"
+//		Password string `json:"password" redact:"drop"`    // removed
+//	}
+//
+//	data, err := redact.JSON(ctx, customer)
+//
+// The redacted value follows the JSON encoding of v, so json tags and MarshalJSON apply.
+// Untyped documents, such as the JSON bodies seen by audit and capture or the attrs of
+// hooks.Log, are redacted by the paths of the types registered with Register:
+//
+//	redact.Register(Customer{}, Order{})
+package redact
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	TAG               = "redact"
+	REDACTED          = "[REDACTED]"
+	DEFAULT_MASK_KEEP = 4
+
+	maxRecursion = 4
+)
+
+// Strategy is how a value is redacted, with options after a comma, ex: "mask,keep=2"
+type Strategy string
+
+const (
+	Full     Strategy = "full"  // replaced by REDACTED
+	Mask     Strategy = "mask"  // all but the last keep characters replaced by '*', emails keep their domain
+	Hash     Strategy = "hash"  // HMAC-SHA256 with SRV_REDACT_HASH_KEY, equal values stay joinable. Full without a key.
+	Tokenize Strategy = "token" // replaced by the token of the Tokenizer, reversible by its owner
+	Drop     Strategy = "drop"  // removed from its object
+)
+
+// Tokenizer replaces a value with a stable token that authorized code can resolve back,
+// ex: kv.Tokenizer
+type Tokenizer interface {
+	Tokenize(ctx context.Context, value string) (string, error)
+}
+
+type Redactor struct {
+	hashKey   []byte
+	tokenizer Tokenizer
+	paths     sync.Map // reflect.Type -> []rule
+
+	registeredLock sync.RWMutex
+	registered     map[string]Strategy // dotted paths of the registered types
+}
+
+type rule struct {
+	path     []string
+	strategy Strategy
+}
+
+var (
+	defaultLock     sync.RWMutex
+	defaultRedactor = New(&config.Config{})
+)
+
+// New returns a Redactor hashing with SRV_REDACT_HASH_KEY. Without a key Hash redacts
+// as Full, an unkeyed hash of short values such as CPFs and phones is reversed by brute force.
+func New(conf *config.Config) *Redactor {
+	if conf.RedactConfig == nil {
+		conf.RedactConfig = &config.RedactConfig{}
+	}
+
+	SRV_REDACT_HASH_KEY := os.Getenv("SRV_REDACT_HASH_KEY")
+	if SRV_REDACT_HASH_KEY != "" {
+		conf.REDACT_HASH_KEY = SRV_REDACT_HASH_KEY
+	}
+
+	return &Redactor{hashKey: []byte(conf.REDACT_HASH_KEY)}
+}
+
+// SetTokenizer enables the Tokenize strategy, without a Tokenizer it redacts as Full
+func (r *Redactor) SetTokenizer(t Tokenizer) {
+	r.tokenizer = t
+}
+
+// SetDefault replaces the Redactor used by the package functions, the logger, audit and capture
+func SetDefault(r *Redactor) {
+	defaultLock.Lock()
+	defer defaultLock.Unlock()
+	defaultRedactor = r
+}
+
+// Default returns the Redactor set with SetDefault, otherwise one hashing with
+// SRV_REDACT_HASH_KEY and without Tokenizer
+func Default() *Redactor {
+	defaultLock.RLock()
+	defer defaultLock.RUnlock()
+	return defaultRedactor
+}
+
+// Register adds the tagged paths of the types of vs to the default Redactor, see Redactor.Register
+func Register(vs ...interface{}) {
+	Default().Register(vs...)
+}
+
+// JSON encodes v with its tagged fields redacted by the default Redactor
+func JSON(ctx context.Context, v interface{}) ([]byte, error) {
+	return Default().JSON(ctx, v)
+}
+
+// JSON encodes v with its tagged fields redacted
+func (r *Redactor) JSON(ctx context.Context, v interface{}) ([]byte, error) {
+	doc, err := r.Value(ctx, v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// Value returns the JSON document of v (maps, slices and scalars) with its tagged fields redacted
+func (r *Redactor) Value(ctx context.Context, v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "redact.Value", err)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "redact.Value", err)
+	}
+
+	for _, ru := range r.rules(reflect.TypeOf(v)) {
+		if err := r.apply(ctx, doc, ru.path, ru.strategy); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+// Register adds the tagged paths of the types of vs, structs or pointers to structs, to the
+// paths Fields applies to every document. It is how the tags reach documents without a Go
+// type, ex: the request bodies of audit.Middleware.
+func (r *Redactor) Register(vs ...interface{}) {
+	r.registeredLock.Lock()
+	defer r.registeredLock.Unlock()
+
+	if r.registered == nil {
+		r.registered = map[string]Strategy{}
+	}
+	for _, v := range vs {
+		for _, ru := range r.rules(reflect.TypeOf(v)) {
+			r.registered[strings.Join(ru.path, ".")] = ru.strategy
+		}
+	}
+}
+
+// Registered reports whether Fields has paths to apply besides the explicit ones
+func (r *Redactor) Registered() bool {
+	r.registeredLock.RLock()
+	defer r.registeredLock.RUnlock()
+	return len(r.registered) > 0
+}
+
+// Fields redacts a JSON document in place by dotted paths, ex: {"customer.email": Mask},
+// and by the paths of the registered types. Arrays are walked element by element and "*"
+// matches every key of an object.
+func (r *Redactor) Fields(ctx context.Context, doc interface{}, fields map[string]Strategy) error {
+	r.registeredLock.RLock()
+	all := make(map[string]Strategy, len(r.registered)+len(fields))
+	for path, strategy := range r.registered {
+		all[path] = strategy
+	}
+	r.registeredLock.RUnlock()
+	for path, strategy := range fields {
+		all[path] = strategy
+	}
+
+	for path, strategy := range all {
+		if err := r.apply(ctx, doc, strings.Split(path, "."), strategy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// String redacts a single value with strategy, Drop returns ""
+func (r *Redactor) String(ctx context.Context, value string, strategy Strategy) (string, error) {
+	name, options := parse(strategy)
+
+	switch name {
+	case Mask:
+		keep := DEFAULT_MASK_KEEP
+		if v, ok := options["keep"]; ok {
+			keep, _ = strconv.Atoi(v)
+		}
+		return mask(value, keep), nil
+	case Hash:
+		if len(r.hashKey) == 0 {
+			return REDACTED, nil
+		}
+		mac := hmac.New(sha256.New, r.hashKey)
+		mac.Write([]byte(value))
+		return "h:" + hex.EncodeToString(mac.Sum(nil)[:16]), nil
+	case Tokenize:
+		if r.tokenizer == nil {
+			return REDACTED, nil
+		}
+		return r.tokenizer.Tokenize(ctx, value)
+	case Drop:
+		return "", nil
+	case Full:
+		return REDACTED, nil
+	}
+	return "", cerrors.New(cerrors.Invalid, "redact: unknown strategy "+string(strategy))
+}
+
+// apply walks objects by key and arrays element by element, like capture.RedactFields
+func (r *Redactor) apply(ctx context.Context, doc interface{}, path []string, strategy Strategy) error {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		keys := []string{path[0]}
+		if path[0] == "*" {
+			keys = keys[:0]
+			for k := range v {
+				keys = append(keys, k)
+			}
+		}
+
+		for _, k := range keys {
+			child, ok := v[k]
+			if !ok || child == nil || child == "" {
+				continue
+			}
+			if len(path) > 1 {
+				if err := r.apply(ctx, child, path[1:], strategy); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if name, _ := parse(strategy); name == Drop {
+				delete(v, k)
+				continue
+			}
+			if _, ok := child.(string); !ok {
+				// numbers, objects and arrays are redacted as their JSON text
+				data, _ := json.Marshal(child)
+				child = string(data)
+			}
+			redacted, err := r.String(ctx, child.(string), strategy)
+			if err != nil {
+				return err
+			}
+			v[k] = redacted
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := r.apply(ctx, item, path, strategy); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rules returns the tagged paths of t, cached per type
+func (r *Redactor) rules(t reflect.Type) []rule {
+	if t == nil {
+		return nil
+	}
+	if cached, ok := r.paths.Load(t); ok {
+		return cached.([]rule)
+	}
+
+	var rules []rule
+	collect(t, nil, map[reflect.Type]int{}, &rules)
+	r.paths.Store(t, rules)
+	return rules
+}
+
+func collect(t reflect.Type, prefix []string, visiting map[reflect.Type]int, rules *[]rule) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		collect(t.Elem(), prefix, visiting, rules)
+		return
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			collect(t.Elem(), append(append([]string{}, prefix...), "*"), visiting, rules)
+		}
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	// recursive types are followed up to maxRecursion levels
+	if visiting[t] >= maxRecursion {
+		return
+	}
+	visiting[t]++
+	defer func() { visiting[t]-- }()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && f.Anonymous {
+			// embedded fields are promoted to the object of t
+			collect(f.Type, prefix, visiting, rules)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		path := append(append([]string{}, prefix...), name)
+		if tag := f.Tag.Get(TAG); tag != "" {
+			*rules = append(*rules, rule{path: path, strategy: Strategy(tag)})
+			continue
+		}
+		collect(f.Type, path, visiting, rules)
+	}
+}
+
+func parse(strategy Strategy) (Strategy, map[string]string) {
+	parts := strings.Split(string(strategy), ",")
+	options := map[string]string{}
+	for _, opt := range parts[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		options[k] = v
+	}
+	return Strategy(strings.TrimSpace(parts[0])), options
+}
+
+// mask keeps the last keep characters, or the first character and the domain of an email
+func mask(value string, keep int) string {
+	if local, domain, ok := strings.Cut(value, "@"); ok && local != "" && domain != "" {
+		first, _ := utf8.DecodeRuneInString(local)
+		if utf8.RuneCountInString(local) == 1 {
+			return "*@" + domain
+		}
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(local)-1) + "@" + domain
+	}
+
+	runes := []rune(value)
+	if keep < 0 || keep >= len(runes) {
+		keep = 0
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
@@ -0,0 +1,127 @@
+// Package rest is the JSON over HTTP client shared by the adapters of REST APIs
+// (Key Vault, Cognitive Search, the RabbitMQ management API, the schema registry...):
+// it encodes the request, decodes the response and turns error statuses into errors
+// of the cerrors kind of the status.
+//
+//	c := &rest.Client{HTTP: &http.Client{Timeout: 10 * time.Second}, Name: "keyvault", Message: rest.AzureMessage}
+//	err := c.Do(ctx, http.MethodGet, url, nil, &out)
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	CONTENT_TYPE = "application/json"
+	// errors bodies are read up to it
+	maxErrorBody = 64 << 10
+)
+
+type Client struct {
+	HTTP *http.Client
+	Name string // prefix of the errors, ex: "keyvault"
+	// ContentType of the requests and accepted responses, CONTENT_TYPE when empty
+	ContentType string
+	// Auth sets the credentials of a request, ex: a bearer token. Optional.
+	Auth func(ctx context.Context, req *http.Request) error
+	// Message extracts the message of an error body, optional
+	Message func(body []byte) string
+	// Kind maps an error status to a kind, cerrors.FromHTTPStatus when nil
+	Kind func(status int) cerrors.Kind
+}
+
+// Do sends in, when not nil, as JSON to url and decodes the response into out, when
+// not nil. Statuses >= 300 return an error of the kind of the status, network errors
+// are Unavailable.
+func (c *Client) Do(ctx context.Context, method, url string, in, out interface{}) error {
+	contentType := c.ContentType
+	if contentType == "" {
+		contentType = CONTENT_TYPE
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return cerrors.Wrap(cerrors.Invalid, c.Name, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, c.Name, err)
+	}
+	req.Header.Set("Accept", contentType)
+	if in != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Auth != nil {
+		if err := c.Auth(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, c.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		msg := ""
+		if c.Message != nil {
+			msg = c.Message(data)
+		}
+		kind := cerrors.FromHTTPStatus
+		if c.Kind != nil {
+			kind = c.Kind
+		}
+		return cerrors.New(kind(resp.StatusCode),
+			strings.TrimSpace(fmt.Sprintf("%s: %s %s: %d %s", c.Name, method, req.URL.Path, resp.StatusCode, msg)))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, c.Name, err)
+	}
+	return nil
+}
+
+// AzureMessage reads the {"error": {"code", "message"}} bodies of the Azure APIs
+func AzureMessage(body []byte) string {
+	var apiErr struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &apiErr)
+	return strings.TrimSpace(apiErr.Error.Code + " " + apiErr.Error.Message)
+}
+
+// BasicAuth returns an Auth sending user and pass, or nil when user is empty
+func BasicAuth(user, pass string) func(ctx context.Context, req *http.Request) error {
+	if user == "" {
+		return nil
+	}
+	return func(ctx context.Context, req *http.Request) error {
+		req.SetBasicAuth(user, pass)
+		return nil
+	}
+}
@@ -0,0 +1,57 @@
+package sync
+
+import gosync "sync"
+
+type keyedEntry struct {
+	mu   gosync.Mutex
+	refs int
+}
+
+// KeyedMutex is a mutex per key (tenant, resource...). The lock of a key is removed as
+// soon as nobody holds or waits for it, so the set of keys can be unbounded.
+type KeyedMutex struct {
+	mu    gosync.Mutex
+	locks map[string]*keyedEntry
+}
+
+func NewKeyedMutex() *KeyedMutex {
+	return &KeyedMutex{locks: map[string]*keyedEntry{}}
+}
+
+// Lock locks key and returns the function unlocking it
+//
+//	unlock := km.Lock(tenant)
+//	defer unlock()
+func (km *KeyedMutex) Lock(key string) (unlock func()) {
+	km.mu.Lock()
+	e, ok := km.locks[key]
+	if !ok {
+		e = &keyedEntry{}
+		km.locks[key] = e
+	}
+	e.refs++
+	km.mu.Unlock()
+
+	e.mu.Lock()
+
+	var once gosync.Once
+	return func() {
+		once.Do(func() {
+			e.mu.Unlock()
+
+			km.mu.Lock()
+			e.refs--
+			if e.refs == 0 {
+				delete(km.locks, key)
+			}
+			km.mu.Unlock()
+		})
+	}
+}
+
+// Len returns the number of keys currently locked or waited for
+func (km *KeyedMutex) Len() int {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	return len(km.locks)
+}
@@ -0,0 +1,113 @@
+// Package sync complements the standard sync package with a weighted semaphore,
+// a keyed mutex and singleflight. Import it as csync:
+//
+//	import csync "github.com/faelp22/go-commons-libs/core/sync"
+package sync
+
+import (
+	"container/list"
+	"context"
+	gosync "sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type waiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+// Semaphore is a weighted semaphore. Waiters are served in FIFO order, so a big
+// Acquire is not starved by small ones.
+type Semaphore struct {
+	size    int64
+	cur     int64
+	mu      gosync.Mutex
+	waiters list.List
+}
+
+func NewSemaphore(size int64) *Semaphore {
+	return &Semaphore{size: size}
+}
+
+// Acquire takes n units, blocking until they are available or ctx is done
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	s.mu.Lock()
+	if n > s.size {
+		s.mu.Unlock()
+		return cerrors.New(cerrors.Invalid, "sync: acquire bigger than the semaphore")
+	}
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	elem := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		select {
+		case <-ready:
+			// acquired while being canceled, give it back
+			s.cur -= n
+			s.notify()
+		default:
+			front := s.waiters.Front() == elem
+			s.waiters.Remove(elem)
+			// the next waiters may fit now that the front one left
+			if front && s.size > s.cur {
+				s.notify()
+			}
+		}
+		s.mu.Unlock()
+		return cerrors.Wrap(cerrors.Throttled, "sync.Acquire", ctx.Err())
+	}
+}
+
+// TryAcquire takes n units only if they are available right away
+func (s *Semaphore) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+// Release gives back n units
+func (s *Semaphore) Release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur -= n
+	if s.cur < 0 {
+		panic("sync: released more than held")
+	}
+	s.notify()
+}
+
+func (s *Semaphore) notify() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+
+		w := next.Value.(waiter)
+		if s.size-s.cur < w.n {
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
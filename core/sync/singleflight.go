@@ -0,0 +1,64 @@
+package sync
+
+import (
+	gosync "sync"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+)
+
+type call[T any] struct {
+	wg   gosync.WaitGroup
+	val  T
+	err  error
+	dups int
+}
+
+// Singleflight deduplicates concurrent calls by key: while a call for a key is in
+// flight the other callers wait for and share its result. Panics are returned as
+// *async.PanicError to every caller.
+type Singleflight[T any] struct {
+	mu    gosync.Mutex
+	calls map[string]*call[T]
+}
+
+// Do runs fn once for the concurrent callers of key. shared reports whether the
+// result was given to more than one caller.
+func (g *Singleflight[T]) Do(key string, fn func() (T, error)) (v T, err error, shared bool) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*call[T]{}
+	}
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err, true
+	}
+
+	c := &call[T]{}
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	c.err = async.Safe(func() (err error) {
+		c.val, err = fn()
+		return err
+	})
+	c.wg.Done()
+
+	g.mu.Lock()
+	if g.calls[key] == c {
+		delete(g.calls, key)
+	}
+	shared = c.dups > 0
+	g.mu.Unlock()
+
+	return c.val, c.err, shared
+}
+
+// Forget makes the next Do of key run fn instead of waiting for the call in flight
+func (g *Singleflight[T]) Forget(key string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.calls, key)
+}
@@ -0,0 +1,39 @@
+package tenancy
+
+import (
+	"context"
+
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+// RoutingKey prefixes key with the tenant found in ctx, ex: "acme.order.created"
+func RoutingKey(ctx context.Context, key string) (string, error) {
+	tenant, err := FromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tenant + "." + key, nil
+}
+
+type tenantRabbit struct {
+	rabbitmq.RabbitInterface
+}
+
+// NewRabbitMQ wraps a RabbitInterface so every message published through Producer
+// has its routing key prefixed with the tenant from the context. Publishing without
+// a tenant in the context fails with ErrNoTenant.
+func NewRabbitMQ(rbm rabbitmq.RabbitInterface) rabbitmq.RabbitInterface {
+	return &tenantRabbit{RabbitInterface: rbm}
+}
+
+func (tr *tenantRabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	key, err := RoutingKey(ctx, pc.Key)
+	if err != nil {
+		return err
+	}
+
+	scoped := *pc
+	scoped.Key = key
+
+	return tr.RabbitInterface.Producer(ctx, &scoped, msg)
+}
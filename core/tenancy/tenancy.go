@@ -0,0 +1,68 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/gorilla/mux"
+)
+
+const DEFAULT_TENANT_HEADER = ctxutil.HEADER_TENANT
+
+var (
+	ErrNoTenant      = errors.New("tenant not found in context")
+	ErrInvalidTenant = errors.New("invalid tenant id")
+)
+
+// tenants are part of routing keys, store keys and paths, so they are restricted
+// to letters, digits, '_' and '-'
+var validTenant = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Valid reports whether tenant is a valid tenant id, up to 64 letters, digits, '_' or '-'
+func Valid(tenant string) bool {
+	return validTenant.MatchString(tenant)
+}
+
+// WithTenant returns a copy of ctx carrying the tenant id
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return ctxutil.WithTenant(ctx, tenant)
+}
+
+// FromContext returns the tenant id stored in ctx, ErrNoTenant or ErrInvalidTenant when
+// it came, ex: from message headers, with characters out of Valid
+func FromContext(ctx context.Context) (string, error) {
+	tenant := ctxutil.Tenant(ctx)
+	if tenant == "" {
+		return "", ErrNoTenant
+	}
+	if !Valid(tenant) {
+		return "", ErrInvalidTenant
+	}
+	return tenant, nil
+}
+
+// Middleware resolves the tenant from the given header (DEFAULT_TENANT_HEADER when empty)
+// and rejects requests without a Valid one with 400 Bad Request
+func Middleware(header string) mux.MiddlewareFunc {
+	if header == "" {
+		header = DEFAULT_TENANT_HEADER
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenant := r.Header.Get(header)
+			if tenant == "" {
+				http.Error(w, "missing tenant header "+header, http.StatusBadRequest)
+				return
+			}
+			if !Valid(tenant) {
+				http.Error(w, "invalid tenant header "+header, http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenant)))
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Package workerpool runs jobs on a bounded, resizable set of workers with a
+// bounded queue, per-job timeouts and panic isolation:
+//
+//	pool := workerpool.New(workerpool.Config{Workers: 8, QueueSize: 100, JobTimeout: 30 * time.Second})
+//	pool.Submit(ctx, func(ctx context.Context) error { return upload(ctx, file) })
+//	...
+//	pool.Drain(ctx)
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const DEFAULT_WORKERS = 4
+
+// ErrClosed is returned by Submit after Drain
+var ErrClosed = cerrors.New(cerrors.Unavailable, "workerpool: pool is closed")
+
+// Job receives the context given to Submit, bounded by the JobTimeout
+type Job func(ctx context.Context) error
+
+type Config struct {
+	Workers    int           // initial workers, DEFAULT_WORKERS when <= 0
+	QueueSize  int           // jobs waiting for a worker, Submit blocks when full
+	JobTimeout time.Duration // optional deadline of every job
+	// OnError is called with the error of every failed job, panics included as *async.PanicError
+	OnError func(err error)
+}
+
+type task struct {
+	ctx    context.Context
+	job    Job
+	result chan error
+}
+
+type Pool struct {
+	conf    Config
+	jobs    chan task
+	lock    sync.RWMutex
+	closed  bool
+	closing chan struct{} // closed by Drain, releases the Submit blocked on a full queue
+	once    sync.Once
+	workers []chan struct{} // quit channel of every worker
+	running sync.WaitGroup
+}
+
+func New(conf Config) *Pool {
+	if conf.Workers <= 0 {
+		conf.Workers = DEFAULT_WORKERS
+	}
+	if conf.QueueSize < 0 {
+		conf.QueueSize = 0
+	}
+
+	p := &Pool{conf: conf, jobs: make(chan task, conf.QueueSize), closing: make(chan struct{})}
+	p.Resize(conf.Workers)
+	return p
+}
+
+// Submit queues job, blocking while the queue is full until ctx is done
+func (p *Pool) Submit(ctx context.Context, job Job) error {
+	_, err := p.submit(ctx, job, nil)
+	return err
+}
+
+// Go queues job and returns a channel receiving its result
+func (p *Pool) Go(ctx context.Context, job Job) (<-chan error, error) {
+	return p.submit(ctx, job, make(chan error, 1))
+}
+
+// TrySubmit queues job only if a worker or a queue slot is free
+func (p *Pool) TrySubmit(ctx context.Context, job Job) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.closed {
+		return false
+	}
+	select {
+	case p.jobs <- task{ctx: ctx, job: job}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Pool) submit(ctx context.Context, job Job, result chan error) (<-chan error, error) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.closed {
+		return nil, ErrClosed
+	}
+
+	select {
+	case p.jobs <- task{ctx: ctx, job: job, result: result}:
+		return result, nil
+	case <-ctx.Done():
+		return nil, cerrors.Wrap(cerrors.Throttled, "workerpool.Submit", ctx.Err())
+	case <-p.closing:
+		return nil, ErrClosed
+	}
+}
+
+// Resize changes the number of workers. Removed workers finish their current job first.
+func (p *Pool) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	for len(p.workers) < n {
+		quit := make(chan struct{})
+		p.workers = append(p.workers, quit)
+		p.running.Add(1)
+		go p.work(quit)
+	}
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		close(p.workers[last])
+		p.workers = p.workers[:last]
+	}
+}
+
+// Size returns the number of workers
+func (p *Pool) Size() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return len(p.workers)
+}
+
+// Pending returns the number of queued jobs
+func (p *Pool) Pending() int {
+	return len(p.jobs)
+}
+
+// Drain stops accepting jobs and waits, until ctx is done, for the queued and
+// running jobs to finish. The Submit blocked on a full queue return ErrClosed.
+func (p *Pool) Drain(ctx context.Context) error {
+	p.once.Do(func() { close(p.closing) })
+
+	done := make(chan struct{})
+	go func() {
+		// the submitters hold the read lock until they see closing
+		p.lock.Lock()
+		if !p.closed {
+			p.closed = true
+			close(p.jobs)
+		}
+		p.lock.Unlock()
+
+		p.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) work(quit chan struct{}) {
+	defer p.running.Done()
+
+	for {
+		// a removed worker stops before taking another job
+		select {
+		case <-quit:
+			return
+		default:
+		}
+
+		select {
+		case <-quit:
+			return
+		case t, ok := <-p.jobs:
+			if !ok {
+				return
+			}
+			p.run(t)
+		}
+	}
+}
+
+func (p *Pool) run(t task) {
+	ctx := t.ctx
+	if p.conf.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.conf.JobTimeout)
+		defer cancel()
+	}
+
+	err := async.Safe(func() error { return t.job(ctx) })
+	if err != nil && p.conf.OnError != nil {
+		p.conf.OnError(err)
+	}
+	if t.result != nil {
+		t.result <- err
+		close(t.result)
+	}
+}
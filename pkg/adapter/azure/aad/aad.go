@@ -0,0 +1,178 @@
+// Package aad gets Azure AD access tokens for the REST based Azure adapters, with a
+// service principal secret or the managed identity of the host, caching each token
+// until shortly before it expires.
+//
+//	ts, err := aad.New(conf)
+//	token, err := ts.Token(ctx, "https://vault.azure.net/.default")
+package aad
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	DEFAULT_AUTHORITY = "https://login.microsoftonline.com"
+	IMDS_ENDPOINT     = "http://169.254.169.254/metadata/identity/oauth2/token"
+	// tokens are renewed this long before they expire
+	REFRESH_MARGIN = 5 * time.Minute
+)
+
+type TokenSource interface {
+	// Token returns a bearer token for scope, ex: "https://vault.azure.net/.default"
+	Token(ctx context.Context, scope string) (string, error)
+}
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+type token_source struct {
+	tenant   string
+	clientID string
+	secret   string
+	http     *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]token
+}
+
+// New returns a TokenSource configured by SRV_AZURE_TENANT_ID, SRV_AZURE_CLIENT_ID and
+// SRV_AZURE_CLIENT_SECRET. Without a secret the managed identity is used, SRV_AZURE_CLIENT_ID
+// then selects a user assigned identity.
+func New(conf *config.Config) (TokenSource, error) {
+	if conf.AzureConfig == nil {
+		conf.AzureConfig = &config.AzureConfig{}
+	}
+
+	SRV_AZURE_TENANT_ID := os.Getenv("SRV_AZURE_TENANT_ID")
+	if SRV_AZURE_TENANT_ID != "" {
+		conf.AZURE_TENANT_ID = SRV_AZURE_TENANT_ID
+	}
+
+	SRV_AZURE_CLIENT_ID := os.Getenv("SRV_AZURE_CLIENT_ID")
+	if SRV_AZURE_CLIENT_ID != "" {
+		conf.AZURE_CLIENT_ID = SRV_AZURE_CLIENT_ID
+	}
+
+	SRV_AZURE_CLIENT_SECRET := os.Getenv("SRV_AZURE_CLIENT_SECRET")
+	if SRV_AZURE_CLIENT_SECRET != "" {
+		conf.AZURE_CLIENT_SECRET = SRV_AZURE_CLIENT_SECRET
+	}
+
+	if conf.AZURE_CLIENT_SECRET != "" && (conf.AZURE_TENANT_ID == "" || conf.AZURE_CLIENT_ID == "") {
+		return nil, cerrors.New(cerrors.Invalid, "aad: SRV_AZURE_TENANT_ID and SRV_AZURE_CLIENT_ID are required with a client secret")
+	}
+
+	return &token_source{
+		tenant:   conf.AZURE_TENANT_ID,
+		clientID: conf.AZURE_CLIENT_ID,
+		secret:   conf.AZURE_CLIENT_SECRET,
+		http:     &http.Client{Timeout: 10 * time.Second},
+		tokens:   map[string]token{},
+	}, nil
+}
+
+func (ts *token_source) Token(ctx context.Context, scope string) (string, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if t, ok := ts.tokens[scope]; ok && time.Until(t.expires) > REFRESH_MARGIN {
+		return t.value, nil
+	}
+
+	var (
+		t   token
+		err error
+	)
+	if ts.secret != "" {
+		t, err = ts.clientCredentials(ctx, scope)
+	} else {
+		t, err = ts.managedIdentity(ctx, scope)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	ts.tokens[scope] = t
+	return t.value, nil
+}
+
+func (ts *token_source) clientCredentials(ctx context.Context, scope string) (token, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {ts.clientID},
+		"client_secret": {ts.secret},
+		"scope":         {scope},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/%s/oauth2/v2.0/token", DEFAULT_AUTHORITY, url.PathEscape(ts.tenant)),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return ts.do(req)
+}
+
+func (ts *token_source) managedIdentity(ctx context.Context, scope string) (token, error) {
+	query := url.Values{
+		"api-version": {"2018-02-01"},
+		"resource":    {strings.TrimSuffix(scope, "/.default")},
+	}
+	if ts.clientID != "" {
+		query.Set("client_id", ts.clientID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, IMDS_ENDPOINT+"?"+query.Encode(), nil)
+	if err != nil {
+		return token{}, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	return ts.do(req)
+}
+
+func (ts *token_source) do(req *http.Request) (token, error) {
+	resp, err := ts.http.Do(req)
+	if err != nil {
+		return token{}, cerrors.Wrap(cerrors.Unavailable, "aad", err)
+	}
+	defer resp.Body.Close()
+
+	// expires_in is a number for AAD and a string for the managed identity endpoint
+	var body struct {
+		AccessToken string      `json:"access_token"`
+		ExpiresIn   json.Number `json:"expires_in"`
+		Error       string      `json:"error"`
+		Description string      `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil && resp.StatusCode < 300 {
+		return token{}, cerrors.Wrap(cerrors.Unavailable, "aad", err)
+	}
+
+	if resp.StatusCode >= 300 || body.AccessToken == "" {
+		kind := cerrors.Invalid
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			kind = cerrors.Unavailable
+		}
+		return token{}, cerrors.New(kind, fmt.Sprintf("aad: %d %s %s", resp.StatusCode, body.Error, body.Description))
+	}
+
+	seconds, _ := strconv.Atoi(body.ExpiresIn.String())
+	return token{value: body.AccessToken, expires: time.Now().Add(time.Duration(seconds) * time.Second)}, nil
+}
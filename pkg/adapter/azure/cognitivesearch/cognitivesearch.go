@@ -0,0 +1,343 @@
+// Package cognitivesearch implements search.Searcher with Azure Cognitive Search over its
+// REST API, and manages the index definitions.
+//
+//	s, err := cognitivesearch.New(conf)
+//	err = s.CreateOrUpdateIndex(ctx, cognitivesearch.Index{Name: "products", Fields: fields})
+//	err = s.Index(ctx, "products", docs)
+//	res, err := s.Search(ctx, "products", search.Request{Text: "shoe", Facets: []string{"brand"}})
+package cognitivesearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/rest"
+	"github.com/faelp22/go-commons-libs/pkg/search"
+)
+
+const (
+	API_VERSION = "2023-11-01"
+	// max documents per indexing request accepted by the service
+	MAX_BATCH = 1000
+)
+
+type Field struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"` // ex: "Edm.String", "Edm.Double", "Collection(Edm.String)"
+	Key         bool    `json:"key,omitempty"`
+	Searchable  bool    `json:"searchable"`
+	Filterable  bool    `json:"filterable"`
+	Sortable    bool    `json:"sortable"`
+	Facetable   bool    `json:"facetable"`
+	Retrievable *bool   `json:"retrievable,omitempty"`
+	Analyzer    string  `json:"analyzer,omitempty"`
+	Fields      []Field `json:"fields,omitempty"` // sub fields of Edm.ComplexType
+}
+
+type Index struct {
+	Name   string  `json:"name"`
+	Fields []Field `json:"fields"`
+	// Extra holds the other properties of the definition (suggesters, scoringProfiles...)
+	Extra map[string]interface{} `json:"-"`
+}
+
+type CognitiveSearchInterface interface {
+	search.Searcher
+	CreateOrUpdateIndex(ctx context.Context, idx Index) error
+	GetIndex(ctx context.Context, name string) (*Index, error)
+	DeleteIndex(ctx context.Context, name string) error
+}
+
+type cognitive_search struct {
+	endpoint string
+	rest     *rest.Client
+
+	keysLock sync.RWMutex
+	keys     map[string]string // key field of each index
+}
+
+// New returns a client configured by SRV_SEARCH_ENDPOINT and SRV_SEARCH_API_KEY
+func New(conf *config.Config) (CognitiveSearchInterface, error) {
+	if conf.SearchConfig == nil {
+		conf.SearchConfig = &config.SearchConfig{}
+	}
+
+	SRV_SEARCH_ENDPOINT := os.Getenv("SRV_SEARCH_ENDPOINT")
+	if SRV_SEARCH_ENDPOINT != "" {
+		conf.SEARCH_ENDPOINT = SRV_SEARCH_ENDPOINT
+	}
+
+	SRV_SEARCH_API_KEY := os.Getenv("SRV_SEARCH_API_KEY")
+	if SRV_SEARCH_API_KEY != "" {
+		conf.SEARCH_API_KEY = SRV_SEARCH_API_KEY
+	}
+
+	if conf.SEARCH_ENDPOINT == "" || conf.SEARCH_API_KEY == "" {
+		return nil, cerrors.New(cerrors.Invalid, "cognitivesearch: SRV_SEARCH_ENDPOINT and SRV_SEARCH_API_KEY are required")
+	}
+
+	apiKey := conf.SEARCH_API_KEY
+	return &cognitive_search{
+		endpoint: strings.TrimSuffix(conf.SEARCH_ENDPOINT, "/"),
+		rest: &rest.Client{
+			HTTP: &http.Client{Timeout: 30 * time.Second},
+			Name: "cognitivesearch",
+			Auth: func(ctx context.Context, req *http.Request) error {
+				req.Header.Set("api-key", apiKey)
+				return nil
+			},
+			Message: rest.AzureMessage,
+			Kind:    kindOfStatus,
+		},
+		keys: map[string]string{},
+	}, nil
+}
+
+func (cs *cognitive_search) CreateOrUpdateIndex(ctx context.Context, idx Index) (err error) {
+	end := hooks.Begin(ctx, "cognitivesearch", "CreateOrUpdateIndex", map[string]interface{}{"index": idx.Name})
+	defer func() { end(err) }()
+
+	def := map[string]interface{}{}
+	for k, v := range idx.Extra {
+		def[k] = v
+	}
+	def["name"] = idx.Name
+	def["fields"] = idx.Fields
+
+	err = cs.do(ctx, http.MethodPut, "/indexes/"+url.PathEscape(idx.Name), def, nil)
+	if err == nil {
+		cs.keysLock.Lock()
+		delete(cs.keys, idx.Name)
+		cs.keysLock.Unlock()
+	}
+	return err
+}
+
+func (cs *cognitive_search) GetIndex(ctx context.Context, name string) (*Index, error) {
+	var raw map[string]json.RawMessage
+	if err := cs.do(ctx, http.MethodGet, "/indexes/"+url.PathEscape(name), nil, &raw); err != nil {
+		return nil, err
+	}
+
+	idx := &Index{Extra: map[string]interface{}{}}
+	for k, v := range raw {
+		var err error
+		switch k {
+		case "name":
+			err = json.Unmarshal(v, &idx.Name)
+		case "fields":
+			err = json.Unmarshal(v, &idx.Fields)
+		case "@odata.context", "@odata.etag":
+		default:
+			var value interface{}
+			err = json.Unmarshal(v, &value)
+			idx.Extra[k] = value
+		}
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "cognitivesearch.GetIndex", err)
+		}
+	}
+	return idx, nil
+}
+
+func (cs *cognitive_search) DeleteIndex(ctx context.Context, name string) error {
+	cs.keysLock.Lock()
+	delete(cs.keys, name)
+	cs.keysLock.Unlock()
+
+	err := cs.do(ctx, http.MethodDelete, "/indexes/"+url.PathEscape(name), nil, nil)
+	if cerrors.Is(err, cerrors.NotFound) {
+		return nil
+	}
+	return err
+}
+
+func (cs *cognitive_search) Index(ctx context.Context, index string, docs []search.Document) (err error) {
+	end := hooks.Begin(ctx, "cognitivesearch", "Index", map[string]interface{}{"index": index, "count": len(docs)})
+	defer func() { end(err) }()
+
+	actions := make([]map[string]interface{}, len(docs))
+	for i, doc := range docs {
+		action := make(map[string]interface{}, len(doc)+1)
+		for k, v := range doc {
+			action[k] = v
+		}
+		action["@search.action"] = "upload" // replaces the whole document, as documented by search.Searcher
+		actions[i] = action
+	}
+	return cs.batch(ctx, index, actions)
+}
+
+func (cs *cognitive_search) Delete(ctx context.Context, index string, keys []string) (err error) {
+	end := hooks.Begin(ctx, "cognitivesearch", "Delete", map[string]interface{}{"index": index, "count": len(keys)})
+	defer func() { end(err) }()
+
+	keyField, err := cs.keyField(ctx, index)
+	if err != nil {
+		return err
+	}
+
+	actions := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		actions[i] = map[string]interface{}{"@search.action": "delete", keyField: key}
+	}
+	return cs.batch(ctx, index, actions)
+}
+
+func (cs *cognitive_search) Search(ctx context.Context, index string, req search.Request) (res *search.Result, err error) {
+	end := hooks.Begin(ctx, "cognitivesearch", "Search", map[string]interface{}{"index": index})
+	defer func() { end(err) }()
+
+	body := map[string]interface{}{"search": req.Text, "count": req.Count}
+	if req.Text == "" {
+		body["search"] = "*"
+	}
+	if req.Filter != "" {
+		body["filter"] = req.Filter
+	}
+	if len(req.Facets) > 0 {
+		body["facets"] = req.Facets
+	}
+	if len(req.OrderBy) > 0 {
+		body["orderby"] = strings.Join(req.OrderBy, ",")
+	}
+	if len(req.Select) > 0 {
+		body["select"] = strings.Join(req.Select, ",")
+	}
+	if req.Top > 0 {
+		body["top"] = req.Top
+	}
+	if req.Skip > 0 {
+		body["skip"] = req.Skip
+	}
+
+	var out struct {
+		Count  *int64                       `json:"@odata.count"`
+		Facets map[string][]json.RawMessage `json:"@search.facets"`
+		Value  []json.RawMessage            `json:"value"`
+	}
+	if err = cs.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(index)+"/docs/search", body, &out); err != nil {
+		return nil, err
+	}
+
+	res = &search.Result{Count: -1, Hits: make([]search.Hit, 0, len(out.Value))}
+	if out.Count != nil {
+		res.Count = *out.Count
+	}
+
+	for _, raw := range out.Value {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &fields); err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "cognitivesearch.Search", err)
+		}
+
+		hit := search.Hit{}
+		json.Unmarshal(fields["@search.score"], &hit.Score)
+		for k := range fields {
+			if strings.HasPrefix(k, "@search.") {
+				delete(fields, k)
+			}
+		}
+		hit.Document, _ = json.Marshal(fields)
+		res.Hits = append(res.Hits, hit)
+	}
+
+	if len(out.Facets) > 0 {
+		res.Facets = make(map[string][]search.FacetValue, len(out.Facets))
+		for field, values := range out.Facets {
+			for _, raw := range values {
+				var fv struct {
+					Value interface{} `json:"value"`
+					From  interface{} `json:"from"`
+					Count int64       `json:"count"`
+				}
+				json.Unmarshal(raw, &fv)
+				if fv.Value == nil {
+					// range facets report the lower bound
+					fv.Value = fv.From
+				}
+				res.Facets[field] = append(res.Facets[field], search.FacetValue{Value: fv.Value, Count: fv.Count})
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// batch sends actions in requests of MAX_BATCH and fails on the first rejected document
+func (cs *cognitive_search) batch(ctx context.Context, index string, actions []map[string]interface{}) error {
+	for start := 0; start < len(actions); start += MAX_BATCH {
+		stop := start + MAX_BATCH
+		if stop > len(actions) {
+			stop = len(actions)
+		}
+
+		var out struct {
+			Value []struct {
+				Key          string `json:"key"`
+				Status       bool   `json:"status"`
+				ErrorMessage string `json:"errorMessage"`
+				StatusCode   int    `json:"statusCode"`
+			} `json:"value"`
+		}
+		body := map[string]interface{}{"value": actions[start:stop]}
+		if err := cs.do(ctx, http.MethodPost, "/indexes/"+url.PathEscape(index)+"/docs/index", body, &out); err != nil {
+			return err
+		}
+
+		for _, r := range out.Value {
+			if !r.Status {
+				return cerrors.New(kindOfStatus(r.StatusCode),
+					fmt.Sprintf("cognitivesearch: document %s: %d %s", r.Key, r.StatusCode, r.ErrorMessage))
+			}
+		}
+	}
+	return nil
+}
+
+func (cs *cognitive_search) keyField(ctx context.Context, index string) (string, error) {
+	cs.keysLock.RLock()
+	key, ok := cs.keys[index]
+	cs.keysLock.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	idx, err := cs.GetIndex(ctx, index)
+	if err != nil {
+		return "", err
+	}
+	for _, f := range idx.Fields {
+		if f.Key {
+			cs.keysLock.Lock()
+			cs.keys[index] = f.Name
+			cs.keysLock.Unlock()
+			return f.Name, nil
+		}
+	}
+	return "", cerrors.New(cerrors.Invalid, "cognitivesearch: index "+index+" has no key field")
+}
+
+// do sends a request to the service, 207 is a partial indexing success reported per
+// document by batch
+func (cs *cognitive_search) do(ctx context.Context, method, path string, in, out interface{}) error {
+	return cs.rest.Do(ctx, method, cs.endpoint+path+"?api-version="+API_VERSION, in, out)
+}
+
+// kindOfStatus is cerrors.FromHTTPStatus, except that the service answers 503 when throttling
+func kindOfStatus(status int) cerrors.Kind {
+	if status == http.StatusServiceUnavailable {
+		return cerrors.Throttled
+	}
+	return cerrors.FromHTTPStatus(status)
+}
@@ -0,0 +1,274 @@
+package cosmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+)
+
+// Container gives typed access to the items of a collection. T must marshal its id
+// in the "id" JSON field, ex: `json:"id"`.
+type Container[T any] struct {
+	db   CosmosInterface
+	name string
+	link string
+}
+
+// planned matches the queries needing a query plan to run across partitions, which the
+// REST API doesn't compute: ORDER BY, GROUP BY, DISTINCT, TOP and aggregates
+var planned = regexp.MustCompile(`(?i)\bORDER\s+BY\b|\bGROUP\s+BY\b|\bDISTINCT\b|\bTOP\b|\b(COUNT|SUM|AVG|MIN|MAX)\s*\(`)
+
+type Query struct {
+	SQL    string
+	Params map[string]interface{} // names start with "@"
+	// PartitionKey restricts the query to one partition, nil queries across partitions,
+	// which rejects ORDER BY, GROUP BY, DISTINCT, TOP and aggregates
+	PartitionKey interface{}
+	MaxItems     int
+	// Continuation resumes a query from the Continuation of the previous Page
+	Continuation string
+}
+
+type Page[T any] struct {
+	Items []T
+	// Continuation is empty on the last page
+	Continuation  string
+	RequestCharge float64
+}
+
+func NewContainer[T any](db CosmosInterface, name string) *Container[T] {
+	return &Container[T]{db: db, name: name, link: "dbs/" + db.Database() + "/colls/" + name}
+}
+
+// Create inserts item in the partition pk, with an error of kind Conflict when the id exists
+func (c *Container[T]) Create(ctx context.Context, pk interface{}, item *T) error {
+	return c.write(ctx, "Create", pk, item, map[string]string{})
+}
+
+// Upsert inserts or replaces item
+func (c *Container[T]) Upsert(ctx context.Context, pk interface{}, item *T) error {
+	return c.write(ctx, "Upsert", pk, item, map[string]string{"x-ms-documentdb-is-upsert": "True"})
+}
+
+// Read returns the item id of partition pk, with an error of kind NotFound when missing
+func (c *Container[T]) Read(ctx context.Context, pk interface{}, id string) (item *T, err error) {
+	attrs := c.attrs(id)
+	var resp *Response
+	end := hooks.BeginAttrs(ctx, "cosmos", "Read", attrs)
+	defer func() { end(err, charged(resp)) }()
+
+	headers, err := partitionHeaders(pk, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err = c.db.Do(ctx, &Request{
+		Method: http.MethodGet, ResourceType: "docs", ResourceLink: c.link + "/docs/" + id,
+		Path: c.link + "/docs/" + url.PathEscape(id), Headers: headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	item = new(T)
+	if err := json.Unmarshal(resp.Body, item); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "cosmos.Read", err)
+	}
+	return item, nil
+}
+
+// Replace overwrites the item id. With etag set the replace only succeeds when the
+// stored item still has that _etag, otherwise it fails with an error of kind Conflict.
+func (c *Container[T]) Replace(ctx context.Context, pk interface{}, id string, item *T, etag string) (err error) {
+	attrs := c.attrs(id)
+	var resp *Response
+	end := hooks.BeginAttrs(ctx, "cosmos", "Replace", attrs)
+	defer func() { end(err, charged(resp)) }()
+
+	headers, err := partitionHeaders(pk, nil)
+	if err != nil {
+		return err
+	}
+	if etag != "" {
+		headers["If-Match"] = etag
+	}
+
+	resp, err = c.db.Do(ctx, &Request{
+		Method: http.MethodPut, ResourceType: "docs", ResourceLink: c.link + "/docs/" + id,
+		Path: c.link + "/docs/" + url.PathEscape(id), Headers: headers, Body: item,
+	})
+	if err != nil {
+		return err
+	}
+	return c.decodeInto(resp, item)
+}
+
+// Delete removes the item id, with an error of kind NotFound when missing
+func (c *Container[T]) Delete(ctx context.Context, pk interface{}, id string) (err error) {
+	attrs := c.attrs(id)
+	var resp *Response
+	end := hooks.BeginAttrs(ctx, "cosmos", "Delete", attrs)
+	defer func() { end(err, charged(resp)) }()
+
+	headers, err := partitionHeaders(pk, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err = c.db.Do(ctx, &Request{
+		Method: http.MethodDelete, ResourceType: "docs", ResourceLink: c.link + "/docs/" + id,
+		Path: c.link + "/docs/" + url.PathEscape(id), Headers: headers,
+	})
+	return err
+}
+
+// Query returns one page of results, pass its Continuation in the next Query for the following one
+func (c *Container[T]) Query(ctx context.Context, q Query) (page *Page[T], err error) {
+	attrs := map[string]interface{}{"container": c.name, "query": q.SQL}
+	var resp *Response
+	end := hooks.BeginAttrs(ctx, "cosmos", "Query", attrs)
+	defer func() { end(err, charged(resp)) }()
+
+	headers := map[string]string{
+		"Content-Type":            "application/query+json",
+		"x-ms-documentdb-isquery": "True",
+	}
+	if q.PartitionKey != nil {
+		if headers, err = partitionHeaders(q.PartitionKey, headers); err != nil {
+			return nil, err
+		}
+	} else {
+		if planned.MatchString(q.SQL) {
+			return nil, cerrors.New(cerrors.Invalid, "cosmos: ORDER BY, GROUP BY, DISTINCT, TOP and aggregates need a PartitionKey, the REST API can't run them across partitions")
+		}
+		headers["x-ms-documentdb-query-enablecrosspartition"] = "True"
+	}
+	if q.MaxItems > 0 {
+		headers["x-ms-max-item-count"] = strconv.Itoa(q.MaxItems)
+	}
+	if q.Continuation != "" {
+		headers["x-ms-continuation"] = q.Continuation
+	}
+
+	type param struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	body := struct {
+		Query      string  `json:"query"`
+		Parameters []param `json:"parameters"`
+	}{Query: q.SQL, Parameters: []param{}}
+
+	names := make([]string, 0, len(q.Params))
+	for name := range q.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body.Parameters = append(body.Parameters, param{Name: name, Value: q.Params[name]})
+	}
+
+	resp, err = c.db.Do(ctx, &Request{
+		Method: http.MethodPost, ResourceType: "docs", ResourceLink: c.link,
+		Path: c.link + "/docs", Headers: headers, Body: body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out struct {
+		Documents []T `json:"Documents"`
+	}
+	if err := json.Unmarshal(resp.Body, &out); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "cosmos.Query", err)
+	}
+
+	return &Page[T]{
+		Items:         out.Documents,
+		Continuation:  resp.Header.Get("x-ms-continuation"),
+		RequestCharge: resp.RequestCharge,
+	}, nil
+}
+
+// QueryAll follows the continuations of q and returns every item
+func (c *Container[T]) QueryAll(ctx context.Context, q Query) ([]T, error) {
+	var items []T
+	for {
+		page, err := c.Query(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+		if page.Continuation == "" {
+			return items, nil
+		}
+		q.Continuation = page.Continuation
+	}
+}
+
+func (c *Container[T]) write(ctx context.Context, op string, pk interface{}, item *T, headers map[string]string) (err error) {
+	attrs := c.attrs("")
+	var resp *Response
+	end := hooks.BeginAttrs(ctx, "cosmos", op, attrs)
+	defer func() { end(err, charged(resp)) }()
+
+	if headers, err = partitionHeaders(pk, headers); err != nil {
+		return err
+	}
+
+	resp, err = c.db.Do(ctx, &Request{
+		Method: http.MethodPost, ResourceType: "docs", ResourceLink: c.link,
+		Path: c.link + "/docs", Headers: headers, Body: item,
+	})
+	if err != nil {
+		return err
+	}
+	return c.decodeInto(resp, item)
+}
+
+// decodeInto refreshes item with the stored version, which carries _etag and _ts
+func (c *Container[T]) decodeInto(resp *Response, item *T) error {
+	if len(resp.Body) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Body, item); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "cosmos", err)
+	}
+	return nil
+}
+
+func (c *Container[T]) attrs(id string) map[string]interface{} {
+	attrs := map[string]interface{}{"container": c.name}
+	if id != "" {
+		attrs["id"] = id
+	}
+	return attrs
+}
+
+// charged returns the hooks attributes of the End event: the request units of resp
+func charged(resp *Response) map[string]interface{} {
+	if resp == nil {
+		return nil
+	}
+	return map[string]interface{}{"request_charge": resp.RequestCharge}
+}
+
+func partitionHeaders(pk interface{}, headers map[string]string) (map[string]string, error) {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	value, err := json.Marshal([]interface{}{pk})
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "cosmos: partition key", err)
+	}
+	headers["x-ms-documentdb-partitionkey"] = string(value)
+	return headers, nil
+}
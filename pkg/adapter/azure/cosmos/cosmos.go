@@ -0,0 +1,253 @@
+// Package cosmos is an Azure Cosmos DB (SQL API) adapter talking to the REST API with
+// the account key. Items are typed through Container:
+//
+//	db, err := cosmos.New(conf)
+//	orders := cosmos.NewContainer[Order](db, "orders")
+//	err = orders.Create(ctx, tenant, &order)
+//	page, err := orders.Query(ctx, cosmos.Query{
+//		SQL:          "SELECT * FROM c WHERE c.status = @status",
+//		Params:       map[string]interface{}{"@status": "open"},
+//		PartitionKey: tenant,
+//	})
+//
+// Throttled requests (429) are retried after the delay asked by the service. The
+// request units of every operation are reported in the "request_charge" attribute of
+// the hooks End event. The REST API computes no query plans, so queries across
+// partitions can't use ORDER BY, GROUP BY, DISTINCT, TOP or aggregates.
+package cosmos
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	API_VERSION         = "2018-12-31"
+	DEFAULT_MAX_RETRIES = 9
+	// used when a 429 response has no x-ms-retry-after-ms
+	DEFAULT_RETRY_AFTER = time.Second
+)
+
+// Request is a REST call on a resource, ex: ResourceType "docs" and
+// ResourceLink "dbs/app/colls/orders" for the documents of a collection
+type Request struct {
+	Method       string
+	ResourceType string
+	ResourceLink string
+	// Path is the request path without the leading "/", ResourceLink when empty
+	Path    string
+	Headers map[string]string
+	Body    interface{}
+}
+
+type Response struct {
+	StatusCode    int
+	Header        http.Header
+	Body          []byte
+	RequestCharge float64
+}
+
+type CosmosInterface interface {
+	// Database returns the configured database id
+	Database() string
+	// Do signs and sends req, retrying throttled requests. Statuses >= 300 are returned
+	// as errors: 404 NotFound, 409 and 412 Conflict, 429 Throttled, 5xx Unavailable.
+	Do(ctx context.Context, req *Request) (*Response, error)
+	// Start reads the database, failing when the account or the database are unreachable
+	Start(ctx context.Context) error
+}
+
+type cosmos struct {
+	endpoint   string
+	key        []byte
+	database   string
+	maxRetries int
+	http       *http.Client
+	clock      clock.Clock
+}
+
+// New returns a client configured by SRV_COSMOS_ENDPOINT, SRV_COSMOS_KEY, SRV_COSMOS_DATABASE
+// and SRV_COSMOS_MAX_RETRIES
+func New(conf *config.Config) (CosmosInterface, error) {
+	if conf.CosmosConfig == nil {
+		conf.CosmosConfig = &config.CosmosConfig{}
+	}
+
+	SRV_COSMOS_ENDPOINT := os.Getenv("SRV_COSMOS_ENDPOINT")
+	if SRV_COSMOS_ENDPOINT != "" {
+		conf.COSMOS_ENDPOINT = SRV_COSMOS_ENDPOINT
+	}
+
+	SRV_COSMOS_KEY := os.Getenv("SRV_COSMOS_KEY")
+	if SRV_COSMOS_KEY != "" {
+		conf.COSMOS_KEY = SRV_COSMOS_KEY
+	}
+
+	SRV_COSMOS_DATABASE := os.Getenv("SRV_COSMOS_DATABASE")
+	if SRV_COSMOS_DATABASE != "" {
+		conf.COSMOS_DATABASE = SRV_COSMOS_DATABASE
+	}
+
+	SRV_COSMOS_MAX_RETRIES := os.Getenv("SRV_COSMOS_MAX_RETRIES")
+	if SRV_COSMOS_MAX_RETRIES != "" {
+		conf.COSMOS_MAX_RETRIES, _ = strconv.Atoi(SRV_COSMOS_MAX_RETRIES)
+	}
+	if conf.COSMOS_MAX_RETRIES == 0 {
+		conf.COSMOS_MAX_RETRIES = DEFAULT_MAX_RETRIES
+	}
+
+	if conf.COSMOS_ENDPOINT == "" || conf.COSMOS_KEY == "" || conf.COSMOS_DATABASE == "" {
+		return nil, cerrors.New(cerrors.Invalid, "cosmos: SRV_COSMOS_ENDPOINT, SRV_COSMOS_KEY and SRV_COSMOS_DATABASE are required")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(conf.COSMOS_KEY)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "cosmos: SRV_COSMOS_KEY", err)
+	}
+
+	return &cosmos{
+		endpoint:   strings.TrimSuffix(conf.COSMOS_ENDPOINT, "/"),
+		key:        key,
+		database:   conf.COSMOS_DATABASE,
+		maxRetries: conf.COSMOS_MAX_RETRIES,
+		http:       &http.Client{Timeout: 30 * time.Second},
+		clock:      clock.New(),
+	}, nil
+}
+
+// SetClock replaces the Clock used for signing and retry delays, mainly for tests
+func (c *cosmos) SetClock(cl clock.Clock) {
+	c.clock = cl
+}
+
+func (c *cosmos) Database() string {
+	return c.database
+}
+
+func (c *cosmos) Start(ctx context.Context) error {
+	_, err := c.Do(ctx, &Request{
+		Method: http.MethodGet, ResourceType: "dbs", ResourceLink: "dbs/" + c.database,
+	})
+	return err
+}
+
+func (c *cosmos) Do(ctx context.Context, req *Request) (*Response, error) {
+	var data []byte
+	if req.Body != nil {
+		var err error
+		if data, err = json.Marshal(req.Body); err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "cosmos", err)
+		}
+	}
+
+	p := req.Path
+	if p == "" {
+		p = req.ResourceLink
+	}
+
+	charge := 0.0
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, req, p, data)
+		if err != nil {
+			return nil, err
+		}
+		charge += resp.RequestCharge
+		resp.RequestCharge = charge
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.maxRetries {
+			wait := DEFAULT_RETRY_AFTER
+			if ms, err := strconv.Atoi(resp.Header.Get("x-ms-retry-after-ms")); err == nil {
+				wait = time.Duration(ms) * time.Millisecond
+			}
+			select {
+			case <-c.clock.After(wait):
+				continue
+			case <-ctx.Done():
+				return nil, cerrors.Wrap(cerrors.Throttled, "cosmos", ctx.Err())
+			}
+		}
+
+		if resp.StatusCode >= 300 {
+			var apiErr struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			json.Unmarshal(resp.Body, &apiErr)
+			return resp, cerrors.New(cerrors.FromHTTPStatus(resp.StatusCode),
+				fmt.Sprintf("cosmos: %s %s: %d %s %s", req.Method, p, resp.StatusCode, apiErr.Code, firstLine(apiErr.Message)))
+		}
+		return resp, nil
+	}
+}
+
+func (c *cosmos) send(ctx context.Context, req *Request, p string, data []byte) (*Response, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	hreq, err := http.NewRequestWithContext(ctx, req.Method, c.endpoint+"/"+p, body)
+	if err != nil {
+		return nil, err
+	}
+
+	date := strings.ToLower(c.clock.Now().UTC().Format(http.TimeFormat))
+	hreq.Header.Set("x-ms-date", date)
+	hreq.Header.Set("x-ms-version", API_VERSION)
+	hreq.Header.Set("Authorization", c.sign(req.Method, req.ResourceType, req.ResourceLink, date))
+	hreq.Header.Set("Accept", "application/json")
+	if data != nil {
+		hreq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.Headers {
+		hreq.Header.Set(k, v)
+	}
+
+	resp, err := c.http.Do(hreq)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "cosmos", err)
+	}
+	defer resp.Body.Close()
+
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "cosmos", err)
+	}
+
+	charge, _ := strconv.ParseFloat(resp.Header.Get("x-ms-request-charge"), 64)
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: out, RequestCharge: charge}, nil
+}
+
+// sign returns the master key authorization of a request
+func (c *cosmos) sign(method, resourceType, resourceLink, date string) string {
+	payload := strings.ToLower(method) + "\n" + strings.ToLower(resourceType) + "\n" + resourceLink + "\n" + date + "\n\n"
+
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(payload))
+	sig := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return url.QueryEscape("type=master&ver=1.0&sig=" + sig)
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -0,0 +1,395 @@
+// Package keyvault is a client for the secrets and certificates of an Azure Key Vault,
+// for services managing secrets at runtime (ex: per tenant API credentials). It talks
+// to the REST API with tokens from pkg/adapter/azure/aad.
+//
+//	kv, err := keyvault.New(conf, tokenSource)
+//	kv.OnRotate(func(name string, s *keyvault.Secret) { reconnect(s.Value) })
+//	go kv.Start(ctx)
+//	secret, err := kv.GetSecret(ctx, "partner-api-key")
+package keyvault
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/rest"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/azure/aad"
+)
+
+const (
+	API_VERSION          = "7.4"
+	SCOPE                = "https://vault.azure.net/.default"
+	DEFAULT_KEYVAULT_TTL = 300 // seconds
+	DEFAULT_KEYVAULT_MAX = 1000
+	DEFAULT_WATCH_PERIOD = time.Minute
+	CONTENT_TYPE_PEM     = "application/x-pem-file"
+	CONTENT_TYPE_PKCS12  = "application/x-pkcs12"
+	secretsPath          = "/secrets/"
+	certificatesPath     = "/certificates/"
+)
+
+type Attributes struct {
+	Enabled bool  `json:"enabled"`
+	Created int64 `json:"created,omitempty"` // unix seconds
+	Updated int64 `json:"updated,omitempty"`
+	Expires int64 `json:"exp,omitempty"`
+}
+
+type Secret struct {
+	Name        string            `json:"-"`
+	Version     string            `json:"-"`
+	ID          string            `json:"id"`
+	Value       string            `json:"value"`
+	ContentType string            `json:"contentType,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Attributes  Attributes        `json:"attributes"`
+}
+
+// SecretItem is a listed secret, without its value
+type SecretItem struct {
+	Name        string            `json:"-"`
+	ID          string            `json:"id"`
+	ContentType string            `json:"contentType,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Attributes  Attributes        `json:"attributes"`
+}
+
+type Certificate struct {
+	Name    string
+	Version string
+	// Certificate is the public certificate
+	Certificate *x509.Certificate
+	// PrivateKey is the PEM or PKCS#12 (base64) bundle with the private key, read from the
+	// secret backing the certificate. Empty when the policy doesn't allow exporting it.
+	PrivateKey  string
+	ContentType string
+}
+
+type KeyVaultInterface interface {
+	// GetSecret returns the latest version of a secret, from the cache when fresh.
+	// Missing secrets return an error of kind NotFound.
+	GetSecret(ctx context.Context, name string) (*Secret, error)
+	// SetSecret creates a new version of a secret
+	SetSecret(ctx context.Context, name, value string, tags map[string]string) (*Secret, error)
+	// ListSecrets returns every secret of the vault, without values
+	ListSecrets(ctx context.Context) ([]SecretItem, error)
+	// GetCertificate returns the latest version of a certificate with its private key when exportable
+	GetCertificate(ctx context.Context, name string) (*Certificate, error)
+	// OnRotate registers fn, called when Start sees a new version of a cached secret
+	OnRotate(fn func(name string, s *Secret))
+	// Start refreshes the cached secrets every period until ctx is done
+	Start(ctx context.Context) error
+}
+
+type cached struct {
+	secret  *Secret
+	fetched time.Time
+	used    time.Time // last GetSecret, the least recently used secret is evicted first
+}
+
+type keyvault struct {
+	baseURL string
+	ttl     time.Duration
+	max     int
+	tokens  aad.TokenSource
+	rest    *rest.Client
+	clock   clock.Clock
+
+	mu       sync.RWMutex
+	cache    map[string]cached
+	onRotate []func(name string, s *Secret)
+}
+
+// New returns a client configured by SRV_KEYVAULT_URL, SRV_KEYVAULT_CACHE_TTL (seconds,
+// negative disables the cache) and SRV_KEYVAULT_CACHE_MAX (secrets cached at most)
+func New(conf *config.Config, tokens aad.TokenSource) (KeyVaultInterface, error) {
+	if conf.KeyVaultConfig == nil {
+		conf.KeyVaultConfig = &config.KeyVaultConfig{}
+	}
+
+	SRV_KEYVAULT_URL := os.Getenv("SRV_KEYVAULT_URL")
+	if SRV_KEYVAULT_URL != "" {
+		conf.KEYVAULT_URL = SRV_KEYVAULT_URL
+	}
+	if conf.KEYVAULT_URL == "" {
+		return nil, cerrors.New(cerrors.Invalid, "keyvault: SRV_KEYVAULT_URL is required")
+	}
+
+	SRV_KEYVAULT_CACHE_TTL := os.Getenv("SRV_KEYVAULT_CACHE_TTL")
+	if SRV_KEYVAULT_CACHE_TTL != "" {
+		conf.KEYVAULT_CACHE_TTL, _ = strconv.Atoi(SRV_KEYVAULT_CACHE_TTL)
+	}
+	if conf.KEYVAULT_CACHE_TTL == 0 {
+		conf.KEYVAULT_CACHE_TTL = DEFAULT_KEYVAULT_TTL
+	}
+
+	SRV_KEYVAULT_CACHE_MAX := os.Getenv("SRV_KEYVAULT_CACHE_MAX")
+	if SRV_KEYVAULT_CACHE_MAX != "" {
+		conf.KEYVAULT_CACHE_MAX, _ = strconv.Atoi(SRV_KEYVAULT_CACHE_MAX)
+	}
+	if conf.KEYVAULT_CACHE_MAX <= 0 {
+		conf.KEYVAULT_CACHE_MAX = DEFAULT_KEYVAULT_MAX
+	}
+
+	kv := &keyvault{
+		baseURL: strings.TrimSuffix(conf.KEYVAULT_URL, "/"),
+		ttl:     time.Duration(conf.KEYVAULT_CACHE_TTL) * time.Second,
+		max:     conf.KEYVAULT_CACHE_MAX,
+		tokens:  tokens,
+		clock:   clock.New(),
+		cache:   map[string]cached{},
+	}
+	kv.rest = &rest.Client{
+		HTTP:    &http.Client{Timeout: 10 * time.Second},
+		Name:    "keyvault",
+		Auth:    kv.auth,
+		Message: rest.AzureMessage,
+	}
+	return kv, nil
+}
+
+// SetClock replaces the Clock used for the cache and the refresh loop, mainly for tests
+func (kv *keyvault) SetClock(c clock.Clock) {
+	kv.clock = c
+}
+
+func (kv *keyvault) GetSecret(ctx context.Context, name string) (s *Secret, err error) {
+	now := kv.clock.Now()
+	kv.mu.Lock()
+	c, ok := kv.cache[name]
+	if ok {
+		c.used = now
+		kv.cache[name] = c
+	}
+	kv.mu.Unlock()
+	if ok && kv.ttl > 0 && now.Sub(c.fetched) < kv.ttl {
+		return c.secret, nil
+	}
+
+	end := hooks.Begin(ctx, "keyvault", "GetSecret", map[string]interface{}{"name": name})
+	defer func() { end(err) }()
+
+	s, err = kv.fetchSecret(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	kv.store(name, s)
+	return s, nil
+}
+
+func (kv *keyvault) SetSecret(ctx context.Context, name, value string, tags map[string]string) (s *Secret, err error) {
+	end := hooks.Begin(ctx, "keyvault", "SetSecret", map[string]interface{}{"name": name})
+	defer func() { end(err) }()
+
+	s = &Secret{}
+	in := map[string]interface{}{"value": value}
+	if len(tags) > 0 {
+		in["tags"] = tags
+	}
+	if err = kv.do(ctx, http.MethodPut, secretsPath+url.PathEscape(name), in, s); err != nil {
+		return nil, err
+	}
+	s.Name, s.Version = parseID(s.ID)
+
+	kv.store(name, s)
+	return s, nil
+}
+
+func (kv *keyvault) ListSecrets(ctx context.Context) (items []SecretItem, err error) {
+	end := hooks.Begin(ctx, "keyvault", "ListSecrets", nil)
+	defer func() { end(err) }()
+
+	next := kv.baseURL + "/secrets?api-version=" + API_VERSION
+	for next != "" {
+		var page struct {
+			Value    []SecretItem `json:"value"`
+			NextLink string       `json:"nextLink"`
+		}
+		if err = kv.doURL(ctx, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Value {
+			item.Name, _ = parseID(item.ID)
+			items = append(items, item)
+		}
+		next = page.NextLink
+	}
+
+	return items, nil
+}
+
+func (kv *keyvault) GetCertificate(ctx context.Context, name string) (cert *Certificate, err error) {
+	end := hooks.Begin(ctx, "keyvault", "GetCertificate", map[string]interface{}{"name": name})
+	defer func() { end(err) }()
+
+	var bundle struct {
+		ID  string `json:"id"`
+		SID string `json:"sid"`
+		CER string `json:"cer"` // base64 DER
+	}
+	if err = kv.do(ctx, http.MethodGet, certificatesPath+url.PathEscape(name), nil, &bundle); err != nil {
+		return nil, err
+	}
+
+	der, err := base64.StdEncoding.DecodeString(bundle.CER)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "keyvault.GetCertificate", err)
+	}
+	x509cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "keyvault.GetCertificate", err)
+	}
+
+	cert = &Certificate{Certificate: x509cert}
+	cert.Name, cert.Version = parseID(bundle.ID)
+
+	// the private key lives in the secret of the same name and version
+	if bundle.SID != "" {
+		s := &Secret{}
+		err := kv.doURL(ctx, http.MethodGet, bundle.SID+"?api-version="+API_VERSION, nil, s)
+		switch {
+		case err == nil:
+			cert.PrivateKey = s.Value
+			cert.ContentType = s.ContentType
+		case !cerrors.Is(err, cerrors.NotFound) && !cerrors.Is(err, cerrors.Invalid):
+			return nil, err
+		}
+	}
+
+	return cert, nil
+}
+
+func (kv *keyvault) OnRotate(fn func(name string, s *Secret)) {
+	kv.mu.Lock()
+	defer kv.mu.Unlock()
+	kv.onRotate = append(kv.onRotate, fn)
+}
+
+// Start checks the cached secrets each DEFAULT_WATCH_PERIOD, refetches the ones older
+// than the cache TTL and notifies OnRotate of the ones with a new version
+func (kv *keyvault) Start(ctx context.Context) error {
+	ticker := kv.clock.NewTicker(DEFAULT_WATCH_PERIOD)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			kv.refresh(ctx)
+		}
+	}
+}
+
+func (kv *keyvault) refresh(ctx context.Context) {
+	now := kv.clock.Now()
+	kv.mu.RLock()
+	names := make([]string, 0, len(kv.cache))
+	for name, c := range kv.cache {
+		if now.Sub(c.fetched) >= kv.ttl {
+			names = append(names, name)
+		}
+	}
+	kv.mu.RUnlock()
+
+	for _, name := range names {
+		s, err := kv.fetchSecret(ctx, name)
+		if err != nil {
+			log.Println("Erro to refresh secret in Key Vault:", name, err.Error())
+			continue
+		}
+		kv.store(name, s)
+	}
+}
+
+// store caches s, evicting the least recently used secret beyond the cache size, and
+// notifies OnRotate when it replaces another version
+func (kv *keyvault) store(name string, s *Secret) {
+	now := kv.clock.Now()
+	kv.mu.Lock()
+	previous, ok := kv.cache[name]
+	entry := cached{secret: s, fetched: now, used: now}
+	if ok {
+		entry.used = previous.used // refreshes don't count as uses
+	}
+	kv.cache[name] = entry
+	if len(kv.cache) > kv.max {
+		kv.evict()
+	}
+	fns := kv.onRotate
+	kv.mu.Unlock()
+
+	if ok && previous.secret.Version != s.Version {
+		for _, fn := range fns {
+			fn(name, s)
+		}
+	}
+}
+
+// evict removes the least recently used secret, kv.mu must be held
+func (kv *keyvault) evict() {
+	oldest := ""
+	for name, c := range kv.cache {
+		if oldest == "" || c.used.Before(kv.cache[oldest].used) {
+			oldest = name
+		}
+	}
+	delete(kv.cache, oldest)
+}
+
+func (kv *keyvault) fetchSecret(ctx context.Context, name string) (*Secret, error) {
+	s := &Secret{}
+	if err := kv.do(ctx, http.MethodGet, secretsPath+url.PathEscape(name), nil, s); err != nil {
+		return nil, err
+	}
+	s.Name, s.Version = parseID(s.ID)
+	return s, nil
+}
+
+func (kv *keyvault) do(ctx context.Context, method, p string, in, out interface{}) error {
+	return kv.doURL(ctx, method, kv.baseURL+p+"?api-version="+API_VERSION, in, out)
+}
+
+func (kv *keyvault) doURL(ctx context.Context, method, u string, in, out interface{}) error {
+	return kv.rest.Do(ctx, method, u, in, out)
+}
+
+func (kv *keyvault) auth(ctx context.Context, req *http.Request) error {
+	token, err := kv.tokens.Token(ctx, SCOPE)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// parseID returns the name and version of ".../secrets/{name}/{version}"
+func parseID(id string) (name, version string) {
+	u, err := url.Parse(id)
+	if err != nil {
+		return "", ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) >= 3 {
+		return parts[1], parts[2]
+	}
+	if len(parts) == 2 {
+		return parts[1], ""
+	}
+	return path.Base(u.Path), ""
+}
@@ -0,0 +1,38 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// commandMonitor reports every command of the client to hooks, the operation is the
+// command name, ex: "find" or "insert"
+func commandMonitor() *event.CommandMonitor {
+	var pending sync.Map // connection and request ids -> end of hooks.Begin
+
+	key := func(connection string, request int64) string {
+		return connection + "/" + strconv.FormatInt(request, 10)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, ev *event.CommandStartedEvent) {
+			end := hooks.Begin(ctx, "mongodb", ev.CommandName, map[string]interface{}{"database": ev.DatabaseName})
+			pending.Store(key(ev.ConnectionID, ev.RequestID), end)
+		},
+		Succeeded: func(ctx context.Context, ev *event.CommandSucceededEvent) {
+			if end, ok := pending.LoadAndDelete(key(ev.ConnectionID, ev.RequestID)); ok {
+				end.(func(error))(nil)
+			}
+		},
+		Failed: func(ctx context.Context, ev *event.CommandFailedEvent) {
+			if end, ok := pending.LoadAndDelete(key(ev.ConnectionID, ev.RequestID)); ok {
+				end.(func(error))(errors.New(ev.Failure))
+			}
+		},
+	}
+}
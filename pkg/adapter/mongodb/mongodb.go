@@ -2,11 +2,12 @@ package mongodb
 
 import (
 	"context"
-	"errors"
 	"log"
 	"os"
 
 	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/lifecycle"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -55,14 +56,16 @@ func New(conf *config.Config) MongoDBInterface {
 
 	} else {
 
-		client, err := mongo.Connect(ctx, options.Client().ApplyURI(conf.MDB_URI))
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(conf.MDB_URI).SetMonitor(commandMonitor()))
 		if err != nil {
 			log.Fatal("Erro to make Connect DB:", err.Error())
 		}
 
-		err = client.Ping(ctx, nil)
-		if err != nil {
-			log.Fatal("Erro to contact DB:", err.Error())
+		if !lifecycle.LazyConnect(conf) {
+			err = client.Ping(ctx, nil)
+			if err != nil {
+				log.Fatal("Erro to contact DB:", err.Error())
+			}
 		}
 
 		mdbpool = &mongodb_pool{
@@ -75,10 +78,20 @@ func New(conf *config.Config) MongoDBInterface {
 	return mdbpool
 }
 
+// Start checks the connection, in lazy mode (SRV_LAZY_CONNECT) New doesn't. It is not
+// part of the interface, reach it as a lifecycle.Starter.
+func (mdbp *mongodb_pool) Start(ctx context.Context) error {
+	if err := mdbp.DB.Ping(ctx, nil); err != nil {
+		log.Println("Erro to contact DB:", err.Error())
+		return cerrors.Wrap(cerrors.Unavailable, "mongodb.Start", err)
+	}
+	return nil
+}
+
 func (mdbp *mongodb_pool) GetCollection() (*mongo.Collection, error) {
 
 	if mdbp.DBDefaultCollection == "" {
-		return nil, errors.New("para usar esse método a variável SRV_MDB_DEFAULT_COLLECTION precisa ser informada ou use GetCollectionByName")
+		return nil, cerrors.New(cerrors.Invalid, "para usar esse método a variável SRV_MDB_DEFAULT_COLLECTION precisa ser informada ou use GetCollectionByName")
 	}
 
 	return mdbp.DB.Database(mdbp.DBName).Collection(mdbp.DBDefaultCollection), nil
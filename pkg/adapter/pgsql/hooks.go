@@ -0,0 +1,116 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/lib/pq"
+)
+
+// open opens dsn, reporting the statements of the postgres driver to hooks as the
+// "Exec" and "Query" operations. Prepared statements are not reported.
+func open(drive, dsn string) (*sql.DB, error) {
+	if drive != "postgres" {
+		return sql.Open(drive, dsn)
+	}
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&hooks_connector{Connector: connector}), nil
+}
+
+type hooks_connector struct {
+	driver.Connector
+}
+
+func (hc *hooks_connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := hc.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &hooks_conn{Conn: conn}, nil
+}
+
+// hooks_conn reports the statements of Conn, the optional interfaces of database/sql
+// are forwarded when Conn implements them
+type hooks_conn struct {
+	driver.Conn
+}
+
+func (hc *hooks_conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (res driver.Result, err error) {
+	execer, ok := hc.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	end := hooks.Begin(ctx, "pgsql", "Exec", statementAttrs(query))
+	defer func() { end(skipped(err)) }()
+
+	return execer.ExecContext(ctx, query, args)
+}
+
+func (hc *hooks_conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := hc.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	end := hooks.Begin(ctx, "pgsql", "Query", statementAttrs(query))
+	defer func() { end(skipped(err)) }()
+
+	return queryer.QueryContext(ctx, query, args)
+}
+
+func (hc *hooks_conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := hc.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return hc.Conn.Prepare(query)
+}
+
+func (hc *hooks_conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := hc.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	return hc.Conn.Begin()
+}
+
+func (hc *hooks_conn) Ping(ctx context.Context) error {
+	if pinger, ok := hc.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (hc *hooks_conn) ResetSession(ctx context.Context) error {
+	if resetter, ok := hc.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (hc *hooks_conn) IsValid() bool {
+	if validator, ok := hc.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// statementAttrs reports the verb of query, the statement itself may hold personal data
+func statementAttrs(query string) map[string]interface{} {
+	verb, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return map[string]interface{}{"statement": strings.ToUpper(verb)}
+}
+
+// skipped hides driver.ErrSkip, database/sql retries the statement through Prepare
+func skipped(err error) error {
+	if err == driver.ErrSkip {
+		return nil
+	}
+	return err
+}
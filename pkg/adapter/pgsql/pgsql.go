@@ -1,6 +1,7 @@
 package pgsql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/lifecycle"
 
 	_ "github.com/lib/pq"
 )
@@ -115,6 +118,16 @@ func (d *dabase_pool) GetDB() (DB *sql.DB) {
 	return d.DB
 }
 
+// Start checks the connection, in lazy mode (SRV_LAZY_CONNECT) New doesn't. It is not
+// part of the interface, reach it as a lifecycle.Starter.
+func (d *dabase_pool) Start(ctx context.Context) error {
+	if err := d.DB.PingContext(ctx); err != nil {
+		log.Println("Erro to contact DB:", err.Error())
+		return cerrors.Wrap(cerrors.Unavailable, "pgsql.Start", err)
+	}
+	return nil
+}
+
 func pgConn(conf *config.Config) *dabase_pool {
 
 	if dbpool != nil && dbpool.DB != nil {
@@ -123,7 +136,7 @@ func pgConn(conf *config.Config) *dabase_pool {
 
 	} else {
 
-		db, err := sql.Open(conf.DB_DRIVE, conf.DB_DSN)
+		db, err := open(conf.DB_DRIVE, conf.DB_DSN)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -133,9 +146,11 @@ func pgConn(conf *config.Config) *dabase_pool {
 		db.SetMaxIdleConns(conf.DB_SET_MAX_IDLE_CONNS)
 		db.SetConnMaxLifetime(time.Duration(conf.DB_SET_CONN_MAX_LIFE_TIME) * time.Minute)
 
-		err = db.Ping()
-		if err != nil {
-			log.Fatal(err)
+		if !lifecycle.LazyConnect(conf) {
+			err = db.Ping()
+			if err != nil {
+				log.Fatal(err)
+			}
 		}
 
 		dbpool = &dabase_pool{
@@ -0,0 +1,295 @@
+package pgsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/config"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+)
+
+const (
+	DEFAULT_REPLICA_MAX_LAG        = 5  // seconds
+	DEFAULT_REPLICA_CHECK_INTERVAL = 10 // seconds
+)
+
+// lagQuery returns whether the node is a standby, whether its WAL receiver is streaming
+// and its lag in seconds: 0 when it replayed all the WAL it received, as the age of the
+// last replayed transaction also grows while the primary is idle, otherwise that age
+// (-1 when none was replayed).
+const lagQuery = `SELECT pg_is_in_recovery(),
+	EXISTS (SELECT 1 FROM pg_stat_wal_receiver WHERE status = 'streaming'),
+	CASE WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), -1) END`
+
+type forcePrimaryKey struct{}
+
+// WithPrimary sends the reads of ctx to the primary, ex: to read a row just written
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// RouterStats counts the routing decisions, read them to export metrics
+type RouterStats struct {
+	PrimaryReads atomic.Int64
+	ReplicaReads atomic.Int64
+	Writes       atomic.Int64
+	// Fallbacks counts the reads sent to the primary because no replica was healthy
+	Fallbacks atomic.Int64
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+	lag     atomic.Int64 // milliseconds
+}
+
+// Router sends reads to healthy read replicas within the lag tolerance and writes,
+// transactions and forced reads to the primary. Replicas are checked by Start, before
+// the first check every read goes to the primary. A replica is healthy while it is a
+// standby streaming from the primary.
+//
+// Router implements dbx.Querier routing by method, not by statement: QueryContext
+// always reads from a replica, so queries that write or lock such as
+// "INSERT ... RETURNING" or "SELECT ... FOR UPDATE" must go to Writer:
+//
+//	users, err := dbx.Select[User](ctx, router.Reader(ctx), `SELECT id, name FROM users`)
+//	id, err := dbx.Get[int64](ctx, router.Writer(), `INSERT INTO users (name) VALUES ($1) RETURNING id`, name)
+type Router struct {
+	primary  *sql.DB
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	next     atomic.Uint64
+	clock    clock.Clock
+	Stats    RouterStats
+}
+
+// NewRouter opens the replicas of SRV_DB_REPLICA_HOSTS with the credentials of the
+// primary, call it after New. SRV_DB_REPLICA_MAX_LAG and SRV_DB_REPLICA_CHECK_INTERVAL
+// are in seconds. Without replicas every query goes to the primary.
+func NewRouter(conf *config.Config, primary DatabaseInterface) *Router {
+
+	SRV_DB_REPLICA_HOSTS := os.Getenv("SRV_DB_REPLICA_HOSTS")
+	if SRV_DB_REPLICA_HOSTS != "" {
+		conf.DB_REPLICA_HOSTS = SRV_DB_REPLICA_HOSTS
+	}
+
+	SRV_DB_REPLICA_MAX_LAG := os.Getenv("SRV_DB_REPLICA_MAX_LAG")
+	if SRV_DB_REPLICA_MAX_LAG != "" {
+		conf.DB_REPLICA_MAX_LAG, _ = strconv.Atoi(SRV_DB_REPLICA_MAX_LAG)
+	}
+	if conf.DB_REPLICA_MAX_LAG <= 0 {
+		conf.DB_REPLICA_MAX_LAG = DEFAULT_REPLICA_MAX_LAG
+	}
+
+	SRV_DB_REPLICA_CHECK_INTERVAL := os.Getenv("SRV_DB_REPLICA_CHECK_INTERVAL")
+	if SRV_DB_REPLICA_CHECK_INTERVAL != "" {
+		conf.DB_REPLICA_CHECK_INTERVAL, _ = strconv.Atoi(SRV_DB_REPLICA_CHECK_INTERVAL)
+	}
+	if conf.DB_REPLICA_CHECK_INTERVAL <= 0 {
+		conf.DB_REPLICA_CHECK_INTERVAL = DEFAULT_REPLICA_CHECK_INTERVAL
+	}
+
+	r := &Router{
+		primary:  primary.GetDB(),
+		maxLag:   time.Duration(conf.DB_REPLICA_MAX_LAG) * time.Second,
+		interval: time.Duration(conf.DB_REPLICA_CHECK_INTERVAL) * time.Second,
+		clock:    clock.New(),
+	}
+
+	sslmode := "disable"
+	if conf.Mode == config.PRODUCTION {
+		sslmode = "require"
+	}
+
+	for _, host := range strings.Split(conf.DB_REPLICA_HOSTS, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" {
+			continue
+		}
+
+		port := conf.DB_PORT
+		if h, p, err := net.SplitHostPort(host); err == nil {
+			host, port = h, p
+		}
+
+		dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+			host, port, conf.DB_USER, conf.DB_PASS, conf.DB_NAME, sslmode)
+		db, err := open(conf.DB_DRIVE, dsn)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		db.SetMaxOpenConns(conf.DB_SET_MAX_OPEN_CONNS)
+		db.SetMaxIdleConns(conf.DB_SET_MAX_IDLE_CONNS)
+		db.SetConnMaxLifetime(time.Duration(conf.DB_SET_CONN_MAX_LIFE_TIME) * time.Minute)
+
+		r.replicas = append(r.replicas, &replica{name: net.JoinHostPort(host, port), db: db})
+	}
+
+	return r
+}
+
+func (r *Router) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Primary returns the primary, for writes and transactions
+func (r *Router) Primary() *sql.DB {
+	return r.primary
+}
+
+// Writer returns the primary as a Querier counted in Stats.Writes, for the statements
+// QueryContext would send to a replica
+func (r *Router) Writer() *Writer {
+	return &Writer{r: r}
+}
+
+// Writer runs every statement on the primary of a Router
+type Writer struct {
+	r *Router
+}
+
+func (w *Writer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	w.r.Stats.Writes.Add(1)
+	return w.r.primary.QueryContext(ctx, query, args...)
+}
+
+func (w *Writer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	w.r.Stats.Writes.Add(1)
+	return w.r.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (w *Writer) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return w.r.ExecContext(ctx, query, args...)
+}
+
+// Reader returns a healthy replica in round robin, or the primary when ctx was given to
+// WithPrimary or no replica is healthy within the lag tolerance
+func (r *Router) Reader(ctx context.Context) *sql.DB {
+	db, _ := r.reader(ctx)
+	return db
+}
+
+func (r *Router) reader(ctx context.Context) (*sql.DB, string) {
+	if forced, _ := ctx.Value(forcePrimaryKey{}).(bool); forced || len(r.replicas) == 0 {
+		r.Stats.PrimaryReads.Add(1)
+		return r.primary, "primary"
+	}
+
+	start := r.next.Add(1)
+	for i := 0; i < len(r.replicas); i++ {
+		rep := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if rep.healthy.Load() && time.Duration(rep.lag.Load())*time.Millisecond <= r.maxLag {
+			r.Stats.ReplicaReads.Add(1)
+			return rep.db, rep.name
+		}
+	}
+
+	r.Stats.Fallbacks.Add(1)
+	r.Stats.PrimaryReads.Add(1)
+	return r.primary, "primary"
+}
+
+// QueryContext runs a read on Reader, statements that write or lock must use Writer
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, err error) {
+	db, target := r.reader(ctx)
+
+	// the statement itself is reported by the driver as "Query"
+	end := hooks.Begin(ctx, "pgsql", "Route", map[string]interface{}{"target": target})
+	defer func() { end(err) }()
+
+	return db.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a single row read on Reader
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	db, _ := r.reader(ctx)
+	return db.QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext runs a write on the primary
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.Stats.Writes.Add(1)
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction on the primary, reads inside it see its writes
+func (r *Router) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	r.Stats.Writes.Add(1)
+	return r.primary.BeginTx(ctx, opts)
+}
+
+// Start checks the replicas now and on every check interval until ctx is done
+func (r *Router) Start(ctx context.Context) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	r.check(ctx)
+	async.Go(func() error {
+		ticker := r.clock.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C():
+				r.check(ctx)
+			}
+		}
+	})
+}
+
+// check measures the replication lag of every replica. Failing replicas, nodes that are
+// not standbys (ex: promoted) and standbys not streaming from the primary are unhealthy.
+func (r *Router) check(ctx context.Context) {
+	for _, rep := range r.replicas {
+		checkCtx, cancel := context.WithTimeout(ctx, r.interval)
+		var (
+			recovery, streaming bool
+			lag                 float64
+		)
+		err := rep.db.QueryRowContext(checkCtx, lagQuery).Scan(&recovery, &streaming, &lag)
+		cancel()
+
+		if err == nil && !recovery {
+			err = errors.New("not a standby")
+		} else if err == nil && !streaming {
+			err = errors.New("replication is not streaming")
+		} else if err == nil && lag < 0 {
+			err = errors.New("no transaction replayed yet")
+		}
+
+		if err != nil {
+			if rep.healthy.Swap(false) {
+				log.Println("Erro to check DB replica", rep.name+":", err.Error())
+			}
+			continue
+		}
+		rep.lag.Store(int64(lag * 1000))
+		rep.healthy.Store(true)
+	}
+}
+
+// Close closes the replicas, the primary belongs to its DatabaseInterface
+func (r *Router) Close() error {
+	for _, rep := range r.replicas {
+		rep.db.Close()
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package rabbitmq
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ActiveConsumer tracks whether this instance is the active consumer of a Queue declared
+// with x-single-active-consumer (see Queue.SingleActiveConsumer). The broker only delivers
+// to the active consumer, so the instance becomes active on its first delivery and
+// inactive when its deliveries stop, letting leader-style consumers run without an
+// external lock:
+//
+//	ac := rabbitmq.NewActiveConsumer(func() { log.Println("leader") }, func() { log.Println("standby") })
+//	rbm.StartConsumer(ac.Config(cc), ac.Wrap(handler))
+//	go ac.Start(ctx, time.Minute, management.ActiveConsumerTag(mgmt, "/"))
+//
+// AMQP doesn't tell a consumer it became active, so without Start an instance promoted
+// on an empty queue only learns it on its next delivery.
+type ActiveConsumer struct {
+	mu         sync.Mutex
+	active     bool
+	queue      string
+	cc         *ConsumerConfig // the config of the last Config call
+	onActive   func()
+	onInactive func()
+}
+
+// ActiveProbe returns the tag of the active consumer of queue, ex: management.ActiveConsumerTag
+type ActiveProbe func(ctx context.Context, queue string) (string, error)
+
+// NewActiveConsumer creates an ActiveConsumer. onActive and onInactive may be nil.
+func NewActiveConsumer(onActive, onInactive func()) *ActiveConsumer {
+	return &ActiveConsumer{onActive: onActive, onInactive: onInactive}
+}
+
+// Config sets the OnClose of cc to track when the deliveries stop, keeping the previous
+// one, and gives cc a consumer tag when it has none so Start can recognize it. Calling
+// it again with the same cc, ex: on every reconnect, doesn't change it again.
+func (a *ActiveConsumer) Config(cc *ConsumerConfig) *ConsumerConfig {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.queue = cc.Queue
+	if a.cc == cc {
+		return cc
+	}
+	a.cc = cc
+
+	if cc.Consumer == "" {
+		cc.Consumer = cc.Queue + "-active-" + ctxutil.NewID()
+	}
+	previous := cc.OnClose
+	cc.OnClose = func() {
+		a.set(false)
+		if previous != nil {
+			previous()
+		}
+	}
+	return cc
+}
+
+// Start asks probe for the active consumer every interval until ctx is done, so the
+// instance learns it was promoted or demoted without waiting for a delivery
+func (a *ActiveConsumer) Start(ctx context.Context, interval time.Duration, probe ActiveProbe) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		a.mu.Lock()
+		queue, tag := a.queue, ""
+		if a.cc != nil {
+			tag = a.cc.Consumer
+		}
+		a.mu.Unlock()
+
+		if tag != "" {
+			active, err := probe(ctx, queue)
+			if err != nil {
+				log.Println("Erro to check the active consumer in RabbitMQ:", err.Error())
+			} else {
+				a.set(active == tag)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wrap marks the instance active before calling callback
+func (a *ActiveConsumer) Wrap(callback func(msg *amqp.Delivery)) func(msg *amqp.Delivery) {
+	return func(msg *amqp.Delivery) {
+		a.set(true)
+		callback(msg)
+	}
+}
+
+// IsActive reports whether this instance is currently receiving the deliveries of the Queue
+func (a *ActiveConsumer) IsActive() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+// set changes the state and, on transitions, notifies hooks and calls the callbacks
+func (a *ActiveConsumer) set(active bool) {
+	a.mu.Lock()
+	if a.active == active {
+		a.mu.Unlock()
+		return
+	}
+	a.active = active
+	queue := a.queue
+	a.mu.Unlock()
+
+	end := hooks.Begin(context.Background(), "rabbitmq", "ActiveConsumer", map[string]interface{}{
+		"queue": queue, "active": active,
+	})
+	end(nil)
+
+	if active && a.onActive != nil {
+		a.onActive()
+	}
+	if !active && a.onInactive != nil {
+		a.onInactive()
+	}
+}
@@ -0,0 +1,96 @@
+package rabbitmq
+
+import (
+	"encoding/json"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Well known x-arguments. Any other broker argument can be passed with WithArgument.
+const (
+	ARG_QUEUE_MODE             = "x-queue-mode"
+	ARG_QUEUE_TYPE             = "x-queue-type"
+	ARG_SINGLE_ACTIVE_CONSUMER = "x-single-active-consumer"
+	ARG_MESSAGE_TTL            = "x-message-ttl"
+	ARG_MAX_LENGTH             = "x-max-length"
+	ARG_MAX_PRIORITY           = "x-max-priority"
+	ARG_DEAD_LETTER_EXCHANGE   = "x-dead-letter-exchange"
+	ARG_DEAD_LETTER_ROUTING    = "x-dead-letter-routing-key"
+	ARG_ALTERNATE_EXCHANGE     = "alternate-exchange"
+
+	QUEUE_MODE_LAZY    = "lazy"
+	QUEUE_MODE_DEFAULT = "default"
+)
+
+// WithArgument returns a copy of the Queue with the argument set
+func (q Queue) WithArgument(key string, value interface{}) Queue {
+	q.Arguments = withArgument(q.Arguments, key, value)
+	return q
+}
+
+// Lazy returns a copy of the Queue declared with x-queue-mode=lazy, keeping messages on disk
+func (q Queue) Lazy() Queue {
+	return q.WithArgument(ARG_QUEUE_MODE, QUEUE_MODE_LAZY)
+}
+
+// SingleActiveConsumer returns a copy of the Queue declared with x-single-active-consumer,
+// so only one of its consumers receives messages at a time
+func (q Queue) SingleActiveConsumer() Queue {
+	return q.WithArgument(ARG_SINGLE_ACTIVE_CONSUMER, true)
+}
+
+// WithArgument returns a copy of the Exchange with the argument set
+func (e Exchange) WithArgument(key string, value interface{}) Exchange {
+	e.Arguments = withArgument(e.Arguments, key, value)
+	return e
+}
+
+// withArgument copies args so Queues and Exchanges built from the same value don't share the map
+func withArgument(args amqp.Table, key string, value interface{}) amqp.Table {
+	table := make(amqp.Table, len(args)+1)
+	for k, v := range args {
+		table[k] = v
+	}
+	table[key] = value
+	return table
+}
+
+// TableFromJSON converts a table decoded with json.Decoder.UseNumber into values AMQP
+// accepts: objects become amqp.Table, arrays []interface{} and json.Number int64, or
+// float64 when it has a fraction, so the broker doesn't reject x-message-ttl or
+// x-max-length sent as floats.
+func TableFromJSON(m map[string]interface{}) amqp.Table {
+	if m == nil {
+		return nil
+	}
+	table := make(amqp.Table, len(m))
+	for k, v := range m {
+		table[k] = fromJSON(v)
+	}
+	return table
+}
+
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return TableFromJSON(v)
+	case amqp.Table:
+		return TableFromJSON(v)
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = fromJSON(item)
+		}
+		return list
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if n, err := v.Int64(); err == nil {
+				return n
+			}
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
@@ -1,11 +1,18 @@
 package rabbitmq
 
 import (
-	"errors"
+	"context"
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/workerpool"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -17,12 +24,30 @@ type ConsumerConfig struct {
 	NoLocal   bool
 	NoWait    bool
 	Args      amqp.Table
+	// Workers handles up to Workers messages in parallel on a workerpool, one at a time
+	// when <= 1. The channel prefetch should be at least Workers.
+	Workers int
+	// OnClose, when set, is called after the deliveries stop because the consumer
+	// was cancelled or the channel closed
+	OnClose func()
 }
 
 func (rbm *rbm_pool) Consumer(cc *ConsumerConfig, callback func(msg *amqp.Delivery)) {
-	HOSTNAME := os.Getenv("HOSTNAME")
-	if HOSTNAME == "" {
-		cc.Consumer = "worker-read-msg"
+	// every consumer gets a tag so Drain can cancel it
+	if cc.Consumer == "" {
+		cc.Consumer = rbm.consumerTag(cc.Queue)
+	}
+
+	if err := rbm.injectFault("rabbitmq.Consumer"); err != nil {
+		log.Println("Failed to register a consumer")
+		log.Println(err)
+		return
+	}
+
+	if err := rbm.ensureConnected(); err != nil {
+		log.Println("Failed to register a consumer")
+		log.Println(err)
+		return
 	}
 
 	msgs, err := rbm.channel.Consume(
@@ -38,48 +63,129 @@ func (rbm *rbm_pool) Consumer(cc *ConsumerConfig, callback func(msg *amqp.Delive
 	if err != nil {
 		log.Println("Failed to register a consumer")
 		log.Println(err)
+		return
 	}
 
-	go func() {
+	tagWG := &sync.WaitGroup{}
+	rbm.tagsLock.Lock()
+	if wg, ok := rbm.tags[cc.Consumer]; ok {
+		tagWG = wg
+	} else {
+		rbm.tags[cc.Consumer] = tagWG
+	}
+	rbm.tagsLock.Unlock()
+
+	rbm.consumers.Add(1)
+	tagWG.Add(1)
+	async.Go(func() error {
+		defer rbm.consumers.Done()
+		defer tagWG.Done()
+		handle := func(msg amqp.Delivery) {
+			// hooks see the request, correlation and tenant ids propagated by the publisher
+			end := hooks.Begin(ctxutil.FromAMQPHeaders(context.Background(), msg.Headers), "rabbitmq", "Consume", map[string]interface{}{
+				"queue": cc.Queue, "key": msg.RoutingKey, "size": len(msg.Body),
+			})
+			err := async.Safe(func() error { callback(&msg); return nil })
+			end(err)
+			if err != nil && !cc.AutoAck {
+				// a panicking callback would panic again on redelivery, dead-letter it instead
+				msg.Nack(false, false)
+			}
+		}
+
+		var pool *workerpool.Pool
+		if cc.Workers > 1 {
+			// without a queue Submit waits for a free worker, holding back the deliveries
+			pool = workerpool.New(workerpool.Config{Workers: cc.Workers})
+		}
+
 		log.Println("Start Consumer")
 		for msg := range msgs {
-			callback(&msg)
+			msg := msg
+			if pool == nil {
+				handle(msg)
+				continue
+			}
+			pool.Submit(context.Background(), func(ctx context.Context) error {
+				handle(msg)
+				return nil
+			})
+		}
+		if pool != nil {
+			pool.Drain(context.Background())
 		}
 		log.Println("Close Consumer")
-	}()
+		if cc.OnClose != nil {
+			cc.OnClose()
+		}
+		return nil
+	})
+}
+
+// consumerTag returns a consumer tag unique in the pool, "{HOSTNAME}-{queue}-{n}"
+func (rbm *rbm_pool) consumerTag(queue string) string {
+	return hostTag(queue, strconv.FormatInt(rbm.tagSeq.Add(1), 10))
+}
+
+// hostTag returns "{HOSTNAME}-{queue}-{suffix}"
+func hostTag(queue, suffix string) string {
+	host := os.Getenv("HOSTNAME")
+	if host == "" {
+		host = "worker-read-msg"
+	}
+	return fmt.Sprintf("%s-%s-%s", host, queue, suffix)
 }
 
 func (rbm *rbm_pool) StartConsumer(cc *ConsumerConfig, callback func(msg *amqp.Delivery)) {
-	isClosed := false
+	rbm.supervise(nil, func() { rbm.Consumer(cc, callback) })
+}
+
+func (rbm *rbm_pool) Supervise(stop <-chan struct{}, register func()) {
+	rbm.supervise(stop, register)
+}
+
+// supervise connects, runs register and runs it again after every reconnect, until stop
+// is closed or the pool is closed. Every supervisor is notified of the closes on its own,
+// the reconnect goes through connect so only the first one dials.
+func (rbm *rbm_pool) supervise(stop <-chan struct{}, register func()) {
+	w := rbm.watch()
+	defer rbm.unwatch(w)
+
 	count := 0
 	for {
-
-		if !isClosed {
-			go rbm.Consumer(cc, callback)
+		if err := rbm.connect(); err != nil {
+			if rbm.closed.Load() {
+				return
+			}
+			count++
+			if count >= rbm.conf.RMQ_MAXX_RECONNECT_TIMES {
+				log.Println("Erro to reconnect 3 times in RabbitMQ")
+				os.Exit(1)
+			}
+			log.Println("Waiting 30 seconds to try again")
+			rbm.clock.Sleep(time.Duration(30) * time.Second) // wait 30 seconds
+			continue
 		}
+		count = 0
 
-		if count >= rbm.conf.RMQ_MAXX_RECONNECT_TIMES {
-			log.Println("Erro to reconnect 3 times in RabbitMQ")
-			os.Exit(1)
-		}
+		register()
+		registered := rbm.gen.Load()
 
-		if err := <-rbm.err; err != nil {
-			if !isClosed {
-				log.Println("Connection is closed, trying to reconnect in RabbitMQ")
+		// closes of connections older than the one registered on are stale
+		for lost := false; !lost; {
+			select {
+			case gen := <-w:
+				lost = gen >= registered
+			case <-stop:
+				return
+			case <-rbm.done:
+				return
 			}
+		}
 
-			rb_conn, err2 := rbm.Connect()
-			if err2 != nil {
-				go func() { rbm.err <- errors.New("connection closed") }()
-				count++
-				isClosed = true
-				log.Println("Waiting 30 seconds to try again")
-				time.Sleep(time.Duration(30) * time.Second) // wait 30 seconds
-			} else {
-				count = 0
-				isClosed = false
-				rbm.conn = rb_conn.GetConnect().conn
-			}
+		if rbm.closed.Load() {
+			return
 		}
+		log.Println("Connection is closed, trying to reconnect in RabbitMQ")
 	}
 }
@@ -7,28 +7,28 @@ import (
 )
 
 type Queue struct {
-	Name       string     // name
-	Durable    bool       // durable
-	AutoDelete bool       // delete when unused
-	Exclusive  bool       // exclusive
-	NoWait     bool       // no-wait
-	Arguments  amqp.Table // arguments
-	Binds      *[]Bind    // bind to exchange and route with queue bind
+	Name       string     `json:"name"`        // name
+	Durable    bool       `json:"durable"`     // durable
+	AutoDelete bool       `json:"auto_delete"` // delete when unused
+	Exclusive  bool       `json:"exclusive"`   // exclusive
+	NoWait     bool       `json:"no_wait"`     // no-wait
+	Arguments  amqp.Table `json:"arguments"`   // arguments
+	Binds      *[]Bind    `json:"binds"`       // bind to exchange and route with queue bind
 }
 
 type Bind struct {
-	ExchangeName string
-	BindingKey   string
+	ExchangeName string `json:"exchange_name"`
+	BindingKey   string `json:"binding_key"`
 }
 
 type Exchange struct {
-	Name       string     // name
-	Kind       string     // kind of exchange. ex: 'direct' | 'topic' | 'fanout'
-	Durable    bool       // durable
-	AutoDelete bool       // delete when unused
-	Internal   bool       // internal exchange
-	NoWait     bool       // no-wait
-	Arguments  amqp.Table // arguments
+	Name       string     `json:"name"`        // name
+	Kind       string     `json:"kind"`        // kind of exchange. ex: 'direct' | 'topic' | 'fanout'
+	Durable    bool       `json:"durable"`     // durable
+	AutoDelete bool       `json:"auto_delete"` // delete when unused
+	Internal   bool       `json:"internal"`    // internal exchange
+	NoWait     bool       `json:"no_wait"`     // no-wait
+	Arguments  amqp.Table `json:"arguments"`   // arguments
 }
 
 func (rbm *rbm_pool) SimpleQueueDeclare(sq Queue) (queue amqp.Queue, err error) {
@@ -43,7 +43,7 @@ func (rbm *rbm_pool) SimpleQueueDeclare(sq Queue) (queue amqp.Queue, err error)
 
 	if err != nil {
 		log.Println("Erro to QueueDeclare Queue in RabbitMQ")
-		return queue, err
+		return queue, wrapError("SimpleQueueDeclare", err)
 	}
 
 	return queue, nil
@@ -61,7 +61,7 @@ func (rbm *rbm_pool) CompleteQueueDeclare(cq []Queue) []error {
 			queue.Arguments,  // arguments
 		); err != nil {
 			log.Println("Erro to QueueDeclare Queue in RabbitMQ")
-			listErrors = append(listErrors, err)
+			listErrors = append(listErrors, wrapError("CompleteQueueDeclare", err))
 		}
 
 		if queue.Binds != nil {
@@ -74,7 +74,7 @@ func (rbm *rbm_pool) CompleteQueueDeclare(cq []Queue) []error {
 					queue.Arguments,
 				); err != nil {
 					log.Println("Erro to QueueBind in RabbitMQ")
-					listErrors = append(listErrors, err)
+					listErrors = append(listErrors, wrapError("CompleteQueueDeclare", err))
 				}
 			}
 		}
@@ -94,7 +94,7 @@ func (rbm *rbm_pool) SimpleExchangeDeclare(se Exchange) error {
 		se.Arguments,  // arguments
 	); err != nil {
 		log.Println("Erro to ExchangeDeclare in RabbitMQ")
-		return err
+		return wrapError("SimpleExchangeDeclare", err)
 	}
 
 	return nil
@@ -113,7 +113,7 @@ func (rbm *rbm_pool) CompleteExchangeDeclare(ce []Exchange) []error {
 			exchange.Arguments,  // arguments
 		); err != nil {
 			log.Println("Erro to ExchangeDeclare in RabbitMQ")
-			listErrors = append(listErrors, err)
+			listErrors = append(listErrors, wrapError("CompleteExchangeDeclare", err))
 		}
 	}
 
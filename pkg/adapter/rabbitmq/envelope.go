@@ -0,0 +1,55 @@
+package rabbitmq
+
+import (
+	"context"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// EnvelopeMessage encodes an Envelope as a Message, copying its identifiers
+// into the AMQP properties so they are visible without decoding the body
+func EnvelopeMessage(env *envelope.Envelope) (*Message, error) {
+	data, err := envelope.Marshal(env)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "rabbitmq.EnvelopeMessage", err)
+	}
+
+	headers := amqp.Table{}
+	if env.Tenant != "" {
+		headers[ctxutil.AMQP_TENANT] = env.Tenant
+	}
+	if env.CausationID != "" {
+		headers["x-causation-id"] = env.CausationID
+	}
+	if env.SchemaVersion != "" {
+		headers["x-schema-version"] = env.SchemaVersion
+	}
+
+	return &Message{
+		Data:          data,
+		ContentType:   envelope.CONTENT_TYPE,
+		MessageID:     env.ID,
+		CorrelationID: env.CorrelationID,
+		Type:          env.Type,
+		AppID:         env.Source,
+		Timestamp:     env.Timestamp,
+		Headers:       headers,
+	}, nil
+}
+
+// ProduceEnvelope publishes an Envelope following the configuration passed on ProducerConfig
+func ProduceEnvelope(ctx context.Context, rbm RabbitInterface, pc *ProducerConfig, env *envelope.Envelope) error {
+	msg, err := EnvelopeMessage(env)
+	if err != nil {
+		return err
+	}
+	return rbm.Producer(ctx, pc, msg)
+}
+
+// DecodeEnvelope parses the Envelope carried by a consumed message
+func DecodeEnvelope(msg *amqp.Delivery) (*envelope.Envelope, error) {
+	return envelope.Unmarshal(msg.Body)
+}
@@ -0,0 +1,45 @@
+package rabbitmq
+
+import (
+	"errors"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/fault"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// wrapError classifies AMQP reply codes into core error kinds.
+// Anything not recognized is treated as the broker being unavailable.
+func wrapError(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	kind := cerrors.Unavailable
+
+	var amqpErr *amqp.Error
+	if errors.As(err, &amqpErr) {
+		switch amqpErr.Code {
+		case amqp.NotFound:
+			kind = cerrors.NotFound
+		case amqp.ResourceLocked, amqp.PreconditionFailed:
+			kind = cerrors.Conflict
+		case amqp.AccessRefused, amqp.InvalidPath, amqp.SyntaxError, amqp.CommandInvalid, amqp.NotAllowed:
+			kind = cerrors.Invalid
+		case amqp.ResourceError:
+			kind = cerrors.Throttled
+		}
+	}
+
+	return cerrors.Wrap(kind, "rabbitmq."+op, err)
+}
+
+// injectFault applies the fault rule of op, closing the connection on ErrDropConnection
+// so the reconnect logic of StartConsumer runs as it would on a network failure
+func (rbm *rbm_pool) injectFault(op string) error {
+	err := fault.Inject(op)
+	if errors.Is(err, fault.ErrDropConnection) && rbm.conn != nil {
+		rbm.conn.Close()
+	}
+	return err
+}
@@ -0,0 +1,229 @@
+// Package management is a thin client for the RabbitMQ management HTTP API, used by
+// dashboards and autoscalers to read the cluster state without rabbitmqadmin.
+package management
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/rest"
+)
+
+const DEFAULT_VHOST = "/"
+
+type Rate struct {
+	Rate float64 `json:"rate"`
+}
+
+type MessageStats struct {
+	Publish        int64 `json:"publish"`
+	PublishDetails Rate  `json:"publish_details"`
+	Deliver        int64 `json:"deliver_get"`
+	DeliverDetails Rate  `json:"deliver_get_details"`
+	Ack            int64 `json:"ack"`
+	AckDetails     Rate  `json:"ack_details"`
+}
+
+type Queue struct {
+	Name                   string                 `json:"name"`
+	Vhost                  string                 `json:"vhost"`
+	Type                   string                 `json:"type"`
+	State                  string                 `json:"state"`
+	Durable                bool                   `json:"durable"`
+	Consumers              int                    `json:"consumers"`
+	Messages               int64                  `json:"messages"`
+	MessagesReady          int64                  `json:"messages_ready"`
+	MessagesUnacknowledged int64                  `json:"messages_unacknowledged"`
+	MessagesDetails        Rate                   `json:"messages_details"`
+	MessageStats           MessageStats           `json:"message_stats"`
+	Arguments              map[string]interface{} `json:"arguments"`
+	// SingleActiveConsumerTag is the consumer receiving the deliveries of a queue with
+	// x-single-active-consumer, empty otherwise
+	SingleActiveConsumerTag string `json:"single_active_consumer_tag,omitempty"`
+}
+
+type Connection struct {
+	Name             string                 `json:"name"`
+	User             string                 `json:"user"`
+	Vhost            string                 `json:"vhost"`
+	State            string                 `json:"state"`
+	Channels         int                    `json:"channels"`
+	PeerHost         string                 `json:"peer_host"`
+	PeerPort         int                    `json:"peer_port"`
+	ClientProperties map[string]interface{} `json:"client_properties"`
+}
+
+type Policy struct {
+	Name       string                 `json:"name,omitempty"`
+	Vhost      string                 `json:"vhost,omitempty"`
+	Pattern    string                 `json:"pattern"`
+	ApplyTo    string                 `json:"apply-to,omitempty"` // "queues" | "exchanges" | "all"
+	Priority   int                    `json:"priority"`
+	Definition map[string]interface{} `json:"definition"`
+}
+
+type ManagementInterface interface {
+	// ListQueues returns the queues of vhost with their depths and rates, or of every vhost when vhost is empty
+	ListQueues(ctx context.Context, vhost string) ([]Queue, error)
+	// GetQueue returns a single Queue, with an error of kind NotFound when it doesn't exist
+	GetQueue(ctx context.Context, vhost, name string) (*Queue, error)
+	// ListConnections returns the client connections of the cluster
+	ListConnections(ctx context.Context) ([]Connection, error)
+	// SetPolicy creates or replaces a policy
+	SetPolicy(ctx context.Context, p Policy) error
+	// DeletePolicy removes a policy
+	DeletePolicy(ctx context.Context, vhost, name string) error
+	// Healthy runs the alarms health check of the node serving the request
+	Healthy(ctx context.Context) error
+}
+
+type management struct {
+	baseURL string
+	rest    *rest.Client
+}
+
+// New returns a client configured by SRV_RMQ_MANAGEMENT_URL, SRV_RMQ_MANAGEMENT_USER and
+// SRV_RMQ_MANAGEMENT_PASS. When they are not set they are derived from RMQ_URI (SRV_RMQ_URI),
+// on the default management port 15672.
+func New(conf *config.Config) (ManagementInterface, error) {
+	if conf.RMQConfig == nil {
+		conf.RMQConfig = &config.RMQConfig{}
+	}
+
+	SRV_RMQ_MANAGEMENT_URL := os.Getenv("SRV_RMQ_MANAGEMENT_URL")
+	if SRV_RMQ_MANAGEMENT_URL != "" {
+		conf.RMQ_MANAGEMENT_URL = SRV_RMQ_MANAGEMENT_URL
+	}
+
+	SRV_RMQ_MANAGEMENT_USER := os.Getenv("SRV_RMQ_MANAGEMENT_USER")
+	if SRV_RMQ_MANAGEMENT_USER != "" {
+		conf.RMQ_MANAGEMENT_USER = SRV_RMQ_MANAGEMENT_USER
+	}
+
+	SRV_RMQ_MANAGEMENT_PASS := os.Getenv("SRV_RMQ_MANAGEMENT_PASS")
+	if SRV_RMQ_MANAGEMENT_PASS != "" {
+		conf.RMQ_MANAGEMENT_PASS = SRV_RMQ_MANAGEMENT_PASS
+	}
+
+	SRV_RMQ_URI := os.Getenv("SRV_RMQ_URI")
+	if SRV_RMQ_URI != "" && conf.RMQ_URI == "" {
+		conf.RMQ_URI = SRV_RMQ_URI
+	}
+
+	if conf.RMQ_MANAGEMENT_URL == "" && conf.RMQ_URI != "" {
+		uri, err := url.Parse(conf.RMQ_URI)
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "management.New", err)
+		}
+
+		scheme := "http"
+		if uri.Scheme == "amqps" {
+			scheme = "https"
+		}
+		conf.RMQ_MANAGEMENT_URL = fmt.Sprintf("%s://%s:15672", scheme, uri.Hostname())
+
+		if conf.RMQ_MANAGEMENT_USER == "" && uri.User != nil {
+			conf.RMQ_MANAGEMENT_USER = uri.User.Username()
+			conf.RMQ_MANAGEMENT_PASS, _ = uri.User.Password()
+		}
+	}
+
+	if conf.RMQ_MANAGEMENT_URL == "" {
+		return nil, cerrors.New(cerrors.Invalid, "management: SRV_RMQ_MANAGEMENT_URL or SRV_RMQ_URI is required")
+	}
+
+	return &management{
+		baseURL: conf.RMQ_MANAGEMENT_URL,
+		rest: &rest.Client{
+			HTTP:    &http.Client{Timeout: 10 * time.Second},
+			Name:    "management",
+			Auth:    rest.BasicAuth(conf.RMQ_MANAGEMENT_USER, conf.RMQ_MANAGEMENT_PASS),
+			Message: message,
+		},
+	}, nil
+}
+
+func (m *management) ListQueues(ctx context.Context, vhost string) ([]Queue, error) {
+	path := "/api/queues"
+	if vhost != "" {
+		path += "/" + url.PathEscape(vhost)
+	}
+
+	var queues []Queue
+	err := m.do(ctx, http.MethodGet, path, nil, &queues)
+	return queues, err
+}
+
+// ActiveConsumerTag returns a probe reading the single active consumer of the queues of
+// vhost, for rabbitmq.ActiveConsumer.Start
+func ActiveConsumerTag(m ManagementInterface, vhost string) func(ctx context.Context, queue string) (string, error) {
+	return func(ctx context.Context, queue string) (string, error) {
+		q, err := m.GetQueue(ctx, vhost, queue)
+		if err != nil {
+			return "", err
+		}
+		return q.SingleActiveConsumerTag, nil
+	}
+}
+
+func (m *management) GetQueue(ctx context.Context, vhost, name string) (*Queue, error) {
+	if vhost == "" {
+		vhost = DEFAULT_VHOST
+	}
+
+	q := &Queue{}
+	if err := m.do(ctx, http.MethodGet, "/api/queues/"+url.PathEscape(vhost)+"/"+url.PathEscape(name), nil, q); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (m *management) ListConnections(ctx context.Context) ([]Connection, error) {
+	var conns []Connection
+	err := m.do(ctx, http.MethodGet, "/api/connections", nil, &conns)
+	return conns, err
+}
+
+func (m *management) SetPolicy(ctx context.Context, p Policy) error {
+	if p.Name == "" || p.Pattern == "" {
+		return cerrors.New(cerrors.Invalid, "management: policy name and pattern are required")
+	}
+	vhost := p.Vhost
+	if vhost == "" {
+		vhost = DEFAULT_VHOST
+	}
+
+	return m.do(ctx, http.MethodPut, "/api/policies/"+url.PathEscape(vhost)+"/"+url.PathEscape(p.Name), p, nil)
+}
+
+func (m *management) DeletePolicy(ctx context.Context, vhost, name string) error {
+	if vhost == "" {
+		vhost = DEFAULT_VHOST
+	}
+	return m.do(ctx, http.MethodDelete, "/api/policies/"+url.PathEscape(vhost)+"/"+url.PathEscape(name), nil, nil)
+}
+
+func (m *management) Healthy(ctx context.Context) error {
+	return m.do(ctx, http.MethodGet, "/api/health/checks/alarms", nil, nil)
+}
+
+func (m *management) do(ctx context.Context, method, path string, in, out interface{}) error {
+	return m.rest.Do(ctx, method, m.baseURL+path, in, out)
+}
+
+// message reads the {"error", "reason"} error bodies of the API
+func message(body []byte) string {
+	var apiErr struct {
+		Error  string `json:"error"`
+		Reason string `json:"reason"`
+	}
+	json.Unmarshal(body, &apiErr)
+	return apiErr.Error + " " + apiErr.Reason
+}
@@ -0,0 +1,93 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	"github.com/faelp22/go-commons-libs/pkg/messaging"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type publisher struct {
+	rbm      RabbitInterface
+	exchange string
+}
+
+// NewPublisher returns a messaging.Publisher publishing to exchange with the topic as routing key
+func NewPublisher(rbm RabbitInterface, exchange string) messaging.Publisher {
+	return &publisher{rbm: rbm, exchange: exchange}
+}
+
+func (p *publisher) Publish(ctx context.Context, topic string, env *envelope.Envelope) error {
+	return ProduceEnvelope(ctx, p.rbm, &ProducerConfig{Exchange: p.exchange, Key: topic}, env)
+}
+
+type subscriber struct {
+	rbm RabbitInterface
+	cc  ConsumerConfig
+}
+
+// NewSubscriber returns a messaging.Subscriber where the subscription is the Queue name.
+// ConsumerConfig is used as template, AutoAck is always disabled.
+func NewSubscriber(rbm RabbitInterface, cc ConsumerConfig) messaging.Subscriber {
+	cc.AutoAck = false
+	return &subscriber{rbm: rbm, cc: cc}
+}
+
+// Subscribe consumes the Queue through a Registry, so the consumer is registered again
+// after reconnects, until ctx is done. Cancelling ctx unsubscribes and waits for the
+// handlers in progress.
+func (s *subscriber) Subscribe(ctx context.Context, subscription string, handler messaging.Handler) error {
+	cc := s.cc
+	cc.Queue = subscription
+
+	return NewRegistry(s.rbm).Register(&cc, func(msg *amqp.Delivery) {
+		env, err := DecodeEnvelope(msg)
+		if err != nil {
+			log.Println("Erro to decode envelope, rejecting message:", err.Error())
+			msg.Nack(false, false)
+			return
+		}
+
+		hctx, cancel := deadline.Apply(ctxutil.FromAMQPHeaders(ctx, msg.Headers), deadline.Consume)
+		d := &delivery{msg: msg, env: env, cancel: cancel}
+		err = handler(hctx, d)
+		if errors.Is(err, messaging.ErrDeferred) {
+			// d may be settled concurrently from now on, its context lives until then
+			return
+		}
+		defer cancel()
+		if !d.settled {
+			if err := messaging.Settle(d, err); err != nil {
+				log.Println("Erro to settle message in RabbitMQ:", err.Error())
+			}
+		}
+	}).StartAll(ctx)
+}
+
+type delivery struct {
+	msg     *amqp.Delivery
+	env     *envelope.Envelope
+	settled bool
+	cancel  context.CancelFunc // releases the handler context once settled
+}
+
+func (d *delivery) Envelope() *envelope.Envelope    { return d.env }
+func (d *delivery) Headers() map[string]interface{} { return d.msg.Headers }
+func (d *delivery) Redelivered() bool               { return d.msg.Redelivered }
+
+func (d *delivery) Ack() error {
+	d.settled = true
+	defer d.cancel()
+	return wrapError("Ack", d.msg.Ack(false))
+}
+
+func (d *delivery) Nack(requeue bool) error {
+	d.settled = true
+	defer d.cancel()
+	return wrapError("Nack", d.msg.Nack(false, requeue))
+}
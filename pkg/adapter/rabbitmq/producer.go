@@ -2,14 +2,32 @@ package rabbitmq
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"time"
 
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type Message struct {
-	Data        []byte
-	ContentType string
+	Data          []byte
+	ContentType   string
+	MessageID     string
+	CorrelationID string
+	Type          string
+	AppID         string
+	Timestamp     time.Time
+	Headers       amqp.Table
+	// optional properties, ex: kept when a message is moved between queues
+	DeliveryMode    uint8
+	Priority        uint8
+	ContentEncoding string
+	ReplyTo         string
+	Expiration      string
 }
 
 type ProducerConfig struct {
@@ -17,22 +35,174 @@ type ProducerConfig struct {
 	Key       string
 	Mandatory bool
 	Immediate bool
+	// Confirm waits for the broker to confirm the message, on a channel in confirm mode.
+	// A message nacked by the broker returns an error of kind Unavailable. With Mandatory,
+	// a message no queue is bound for is returned by the broker and fails with NotFound;
+	// such publishes are serialized on their own channel.
+	Confirm bool
 }
 
-func (rbm *rbm_pool) Producer(ctx context.Context, pc *ProducerConfig, msg *Message) error {
-	err := rbm.channel.PublishWithContext(ctx,
+func (rbm *rbm_pool) Producer(ctx context.Context, pc *ProducerConfig, msg *Message) (err error) {
+	ctx, cancel := deadline.Apply(ctx, deadline.Publish)
+	defer cancel()
+
+	end := hooks.Begin(ctx, "rabbitmq", "Producer", map[string]interface{}{
+		"exchange": pc.Exchange, "key": pc.Key, "size": len(msg.Data),
+	})
+	defer func() { end(err) }()
+
+	if err := rbm.injectFault("rabbitmq.Producer"); err != nil {
+		return err
+	}
+
+	if err := rbm.ensureConnected(); err != nil {
+		return err
+	}
+
+	// request values of ctx are propagated unless the caller set them explicitly
+	headers := amqp.Table(ctxutil.AMQPHeaders(ctx))
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+
+	publishing := amqp.Publishing{
+		Body:          msg.Data,
+		ContentType:   msg.ContentType,
+		MessageId:     msg.MessageID,
+		CorrelationId: msg.CorrelationID,
+		Type:          msg.Type,
+		AppId:         msg.AppID,
+		Timestamp:     msg.Timestamp,
+		Headers:       headers,
+
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		ContentEncoding: msg.ContentEncoding,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+	}
+
+	if pc.Confirm && pc.Mandatory {
+		return rbm.publishMandatory(ctx, pc, publishing)
+	}
+	if pc.Confirm {
+		return rbm.publishConfirmed(ctx, pc, publishing)
+	}
+
+	err = rbm.channel.PublishWithContext(ctx,
 		pc.Exchange,  // exchange
 		pc.Key,       // routing key
 		pc.Mandatory, // mandatory
 		pc.Immediate, // immediate
-		amqp.Publishing{
-			Body:        msg.Data,
-			ContentType: msg.ContentType,
-		})
+		publishing)
 
 	if err != nil {
 		log.Println(err)
 	}
 
-	return err
+	return wrapError("Producer", err)
+}
+
+// publishConfirmed publishes on the confirm channel and waits, until ctx is done, for
+// the broker to confirm it
+func (rbm *rbm_pool) publishConfirmed(ctx context.Context, pc *ProducerConfig, publishing amqp.Publishing) error {
+	ch, err := rbm.confirmChannel()
+	if err != nil {
+		return err
+	}
+
+	dc, err := ch.PublishWithDeferredConfirmWithContext(ctx, pc.Exchange, pc.Key, pc.Mandatory, pc.Immediate, publishing)
+	if err != nil {
+		log.Println(err)
+		return wrapError("Producer", err)
+	}
+
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "rabbitmq.Producer", err)
+	}
+	if !acked {
+		return cerrors.New(cerrors.Unavailable, "rabbitmq: message nacked by the broker")
+	}
+	return nil
+}
+
+// confirmChannel returns the channel in confirm mode, opened on first use and after
+// it is closed, so the main channel keeps publishing without confirms
+func (rbm *rbm_pool) confirmChannel() (*amqp.Channel, error) {
+	rbm.confirmLock.Lock()
+	defer rbm.confirmLock.Unlock()
+
+	if rbm.confirm != nil && !rbm.confirm.IsClosed() {
+		return rbm.confirm, nil
+	}
+
+	ch, err := rbm.conn.Channel()
+	if err != nil {
+		log.Println("Erro to open confirm Channel in RabbitMQ")
+		return nil, wrapError("Producer", err)
+	}
+	if err := ch.Confirm(false); err != nil {
+		ch.Close()
+		log.Println("Erro to put Channel in confirm mode in RabbitMQ")
+		return nil, wrapError("Producer", err)
+	}
+
+	rbm.confirm = ch
+	return ch, nil
+}
+
+// publishMandatory publishes on the mandatory channel and waits for the confirm. The broker
+// sends the basic.return of an unroutable message before its ack, so with one message in
+// flight a return received by then belongs to it.
+func (rbm *rbm_pool) publishMandatory(ctx context.Context, pc *ProducerConfig, publishing amqp.Publishing) error {
+	rbm.mandatoryLock.Lock()
+	defer rbm.mandatoryLock.Unlock()
+
+	if rbm.mandatory == nil || rbm.mandatory.IsClosed() {
+		ch, err := rbm.conn.Channel()
+		if err != nil {
+			log.Println("Erro to open mandatory Channel in RabbitMQ")
+			return wrapError("Producer", err)
+		}
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			log.Println("Erro to put Channel in confirm mode in RabbitMQ")
+			return wrapError("Producer", err)
+		}
+		rbm.mandatory = ch
+		rbm.returns = ch.NotifyReturn(make(chan amqp.Return, 16))
+	}
+
+	// returns of publishes that timed out before their confirm
+	for drained := false; !drained; {
+		select {
+		case <-rbm.returns:
+		default:
+			drained = true
+		}
+	}
+
+	dc, err := rbm.mandatory.PublishWithDeferredConfirmWithContext(ctx, pc.Exchange, pc.Key, true, pc.Immediate, publishing)
+	if err != nil {
+		log.Println(err)
+		return wrapError("Producer", err)
+	}
+
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "rabbitmq.Producer", err)
+	}
+	if !acked {
+		return cerrors.New(cerrors.Unavailable, "rabbitmq: message nacked by the broker")
+	}
+
+	select {
+	case ret, ok := <-rbm.returns:
+		if ok {
+			return cerrors.New(cerrors.NotFound, fmt.Sprintf("rabbitmq: message returned by the broker: %d %s", ret.ReplyCode, ret.ReplyText))
+		}
+	default:
+	}
+	return nil
 }
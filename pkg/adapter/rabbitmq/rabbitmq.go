@@ -6,8 +6,15 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"sync"
+	"sync/atomic"
 
+	"github.com/faelp22/go-commons-libs/core/clock"
 	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/fault"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/lifecycle"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -18,6 +25,9 @@ type RabbitInterface interface {
 	Connect() (RabbitInterface, error)
 	// GetConnect gets the active connection
 	GetConnect() *rbm_pool
+	// Start connects unless already connected, used as warmup by the lifecycle coordinator.
+	// In lazy mode (SRV_LAZY_CONNECT) Producer and Consumer also connect on first use.
+	Start(ctx context.Context) error
 
 	// SimpleQueueDeclare used to declare a single Queue into RabbitMQ and returns it or an error
 	SimpleQueueDeclare(sq Queue) (queue amqp.Queue, err error)
@@ -50,18 +60,93 @@ type RabbitInterface interface {
 	// RabbitMQ service currently running. You can define this number by setting an env variable called
 	// SRV_RMQ_MAXX_RECONNECT_TIMES
 	StartConsumer(cc *ConsumerConfig, callback func(msg *amqp.Delivery))
+
+	// SetClock replaces the Clock used to wait between reconnect attempts, mainly for tests
+	SetClock(c clock.Clock)
+
+	// CancelConsumer cancels the Consumer with the given tag and waits, until ctx is done,
+	// for its callbacks to return
+	CancelConsumer(ctx context.Context, tag string) error
+	// Drain cancels every Consumer and waits, until ctx is done,
+	// for the callbacks in progress to return
+	Drain(ctx context.Context) error
+	// Close drains the consumers and closes the channel and the connection.
+	// StartConsumer returns instead of reconnecting after Close.
+	Close(ctx context.Context) error
+}
+
+// AdminInterface holds the inspection and maintenance commands of the broker, used by
+// pkg/ops. The pool of New implements it, reach it with Admin.
+type AdminInterface interface {
+	// InspectQueue passively declares a Queue and returns its current state (messages and consumers).
+	// It returns an error if the Queue doesn't exist.
+	InspectQueue(name string) (queue amqp.Queue, err error)
+	// ExchangeExists reports whether an Exchange is declared in RabbitMQ
+	ExchangeExists(name string) (bool, error)
+	// PurgeQueue removes every ready message from a Queue and returns how many were removed
+	PurgeQueue(name string) (int, error)
+	// GetMessage synchronously fetches a single message from a Queue without auto-ack.
+	// ok is false when the Queue is empty.
+	GetMessage(queue string) (msg amqp.Delivery, ok bool, err error)
+}
+
+// Admin returns the AdminInterface of rbm: rbm itself when it implements it, ex: a fake,
+// or the pool behind it
+func Admin(rbm RabbitInterface) AdminInterface {
+	if admin, ok := rbm.(AdminInterface); ok {
+		return admin
+	}
+	return rbm.GetConnect()
+}
+
+// SupervisorInterface keeps consumers registered across reconnects, used by Registry.
+// The pool of New implements it, reach it with Supervisor.
+type SupervisorInterface interface {
+	// Supervise runs register now and again after every reconnect, until stop is closed
+	// or the connection is closed
+	Supervise(stop <-chan struct{}, register func())
+}
+
+// Supervisor returns the SupervisorInterface of rbm: rbm itself when it implements it,
+// ex: a fake, or the pool behind it
+func Supervisor(rbm RabbitInterface) SupervisorInterface {
+	if sup, ok := rbm.(SupervisorInterface); ok {
+		return sup
+	}
+	return rbm.GetConnect()
 }
 
 type rbm_pool struct {
 	conn                 *amqp.Connection
 	channel              *amqp.Channel
+	confirm              *amqp.Channel // publisher confirms, see ProducerConfig.Confirm
+	confirmLock          sync.Mutex
+	mandatory            *amqp.Channel // confirmed mandatory publishes, see publishMandatory
+	returns              <-chan amqp.Return
+	mandatoryLock        sync.Mutex
+	get                  *amqp.Channel // GetMessage, see getChannel
+	getLock              sync.Mutex
 	conf                 *config.Config
-	err                  chan error
+	gen                  atomic.Uint64 // connections dialed, identifies the one a notification is about
+	watchLock            sync.Mutex
+	watchers             map[chan uint64]struct{} // one per supervisor, see watch
+	clock                clock.Clock
+	consumers            sync.WaitGroup
+	tagsLock             sync.Mutex
+	tags                 map[string]*sync.WaitGroup // consumers by consumer tag
+	closed               atomic.Bool
+	closeOnce            sync.Once
+	done                 chan struct{} // closed by Close, stops the NotifyClose listeners
+	tagSeq               atomic.Int64
+	lazy                 bool
+	connectLock          sync.Mutex
 	MAXX_RECONNECT_TIMES int
 }
 
 var rbmpool = &rbm_pool{
-	err: make(chan error),
+	done:     make(chan struct{}),
+	tags:     map[string]*sync.WaitGroup{},
+	watchers: map[chan uint64]struct{}{},
 }
 
 func New(conf *config.Config) RabbitInterface {
@@ -81,42 +166,226 @@ func New(conf *config.Config) RabbitInterface {
 	}
 
 	rbmpool = &rbm_pool{
-		conf: conf,
-		err:  make(chan error),
+		conf:     conf,
+		clock:    clock.New(),
+		done:     make(chan struct{}),
+		tags:     map[string]*sync.WaitGroup{},
+		watchers: map[chan uint64]struct{}{},
+		lazy:     lifecycle.LazyConnect(conf),
 	}
 	return rbmpool
 }
 
 func (rbm *rbm_pool) Connect() (RabbitInterface, error) {
-	var err error
+	rbm.connectLock.Lock()
+	defer rbm.connectLock.Unlock()
+
+	return rbm, rbm.dial()
+}
+
+// connect connects unless the channel is open, Start, the lazy mode and the reconnects
+// of the supervisors go through it so they never dial concurrently
+func (rbm *rbm_pool) connect() error {
+	rbm.connectLock.Lock()
+	defer rbm.connectLock.Unlock()
+
+	if rbm.channel != nil && !rbm.channel.IsClosed() {
+		return nil
+	}
+	return rbm.dial()
+}
+
+// dial opens a new connection and channel, connectLock must be held
+func (rbm *rbm_pool) dial() (err error) {
+	end := hooks.Begin(context.Background(), "rabbitmq", "Connect", nil)
+	defer func() { end(err) }()
+
+	if err = fault.Inject("rabbitmq.Connect"); err != nil {
+		return err
+	}
+
+	// a previous connection still open would leak
+	if rbm.conn != nil && !rbm.conn.IsClosed() {
+		rbm.conn.Close()
+	}
 
 	rbm.conn, err = amqp.Dial(rbm.conf.RMQ_URI)
 	if err != nil {
 		log.Println("Erro to Connect in RabbitMQ")
-		return rbm, err
+		return wrapError("Connect", err)
 	}
 
-	go func() {
-		<-rbm.conn.NotifyClose(make(chan *amqp.Error)) // Listen to Connection NotifyClose
-		rbm.err <- errors.New("connection closed")
-	}()
+	gen := rbm.gen.Add(1)
+	go rbm.notifyClose(rbm.conn.NotifyClose(make(chan *amqp.Error, 1)), gen) // Listen to Connection NotifyClose
 
 	rbm.channel, err = rbm.conn.Channel()
 	if err != nil {
 		log.Println("Erro to Connect in RabbitMQ Channel")
-		return rbm, err
+		return wrapError("Connect", err)
 	}
 
-	go func() {
-		<-rbm.channel.NotifyClose(make(chan *amqp.Error)) // Listen to Channel NotifyClose
-		rbm.err <- errors.New("channel closed")
-	}()
+	go rbm.notifyClose(rbm.channel.NotifyClose(make(chan *amqp.Error, 1)), gen) // Listen to Channel NotifyClose
 
 	log.Println("New RabbitMQ Connect Success")
 
-	return rbm, nil
+	return nil
+}
+
+// notifyClose reports the close of the connection or channel of gen to every supervisor,
+// giving up when the pool is closed
+func (rbm *rbm_pool) notifyClose(closed <-chan *amqp.Error, gen uint64) {
+	select {
+	case <-closed:
+	case <-rbm.done:
+		return
+	}
+
+	rbm.watchLock.Lock()
+	defer rbm.watchLock.Unlock()
+	for w := range rbm.watchers {
+		// a notification still pending is about the same or an older connection
+		select {
+		case w <- gen:
+		default:
+		}
+	}
+}
+
+// watch returns a channel receiving the generation of every connection closed until unwatch
+func (rbm *rbm_pool) watch() chan uint64 {
+	w := make(chan uint64, 1)
+	rbm.watchLock.Lock()
+	rbm.watchers[w] = struct{}{}
+	rbm.watchLock.Unlock()
+	return w
+}
+
+func (rbm *rbm_pool) unwatch(w chan uint64) {
+	rbm.watchLock.Lock()
+	delete(rbm.watchers, w)
+	rbm.watchLock.Unlock()
 }
 
 func (rbm *rbm_pool) GetConnect() *rbm_pool {
 	return rbm
 }
+
+func (rbm *rbm_pool) Start(ctx context.Context) error {
+	// connect holds connectLock even after ctx is done, a following Start waits for it
+	done := make(chan error, 1)
+	go func() {
+		done <- rbm.connect()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return cerrors.Wrap(cerrors.Unavailable, "rabbitmq.Start", ctx.Err())
+	}
+}
+
+// ensureConnected connects on first use in lazy mode
+func (rbm *rbm_pool) ensureConnected() error {
+	if !rbm.lazy {
+		return nil
+	}
+	return rbm.connect()
+}
+
+func (rbm *rbm_pool) SetClock(c clock.Clock) {
+	rbm.clock = c
+}
+
+func (rbm *rbm_pool) Drain(ctx context.Context) error {
+	rbm.tagsLock.Lock()
+	tags := make([]string, 0, len(rbm.tags))
+	for tag := range rbm.tags {
+		tags = append(tags, tag)
+	}
+	rbm.tagsLock.Unlock()
+
+	if err := rbm.cancelConsumers(ctx, tags); err != nil {
+		return err
+	}
+
+	return waitGroup(ctx, &rbm.consumers)
+}
+
+func (rbm *rbm_pool) CancelConsumer(ctx context.Context, tag string) error {
+	return rbm.cancelConsumers(ctx, []string{tag})
+}
+
+// cancelConsumers cancels the consumers with the given tags and waits for their callbacks to return
+func (rbm *rbm_pool) cancelConsumers(ctx context.Context, tags []string) error {
+	var (
+		errs []error
+		wgs  []*sync.WaitGroup
+	)
+
+	for _, tag := range tags {
+		rbm.tagsLock.Lock()
+		wg, ok := rbm.tags[tag]
+		delete(rbm.tags, tag)
+		rbm.tagsLock.Unlock()
+
+		if !ok {
+			continue
+		}
+		wgs = append(wgs, wg)
+
+		if err := rbm.channel.Cancel(tag, false); err != nil {
+			errs = append(errs, wrapError("Drain", err))
+		}
+	}
+
+	for _, wg := range wgs {
+		if err := waitGroup(ctx, wg); err != nil {
+			errs = append(errs, err)
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Println("Timeout waiting RabbitMQ consumers to drain")
+		return ctx.Err()
+	}
+}
+
+func (rbm *rbm_pool) Close(ctx context.Context) error {
+	rbm.closed.Store(true)
+	defer rbm.closeOnce.Do(func() { close(rbm.done) })
+
+	var errs []error
+	if rbm.channel != nil {
+		if err := rbm.Drain(ctx); err != nil {
+			errs = append(errs, err)
+		}
+		if err := rbm.channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			errs = append(errs, wrapError("Close", err))
+		}
+	}
+
+	if rbm.conn != nil {
+		if err := rbm.conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			errs = append(errs, wrapError("Close", err))
+		}
+	}
+
+	log.Println("RabbitMQ Connection Closed")
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,175 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type Registration struct {
+	Config  *ConsumerConfig
+	Handler func(msg *amqp.Delivery)
+	// Weight is the share of the workers given to the queue when the Registry has a
+	// worker pool (see SetWorkers). Defaults to 1.
+	Weight int
+}
+
+// Registry starts and stops several consumers sharing the connection
+// management of the pool, replacing one StartConsumer goroutine per queue
+type Registry struct {
+	rbm     RabbitInterface
+	mu      sync.Mutex
+	regs    []Registration
+	stop    chan struct{}
+	running bool
+	tags    []string // consumer tags of the running consumers
+	workers int
+	buffer  int
+	sched   *scheduler
+}
+
+// NewRegistry creates a Registry over rbm. Wrapped interfaces (tenancy, claimcheck,
+// checksum...) are honored since consumers are registered through rbm.Consumer.
+func NewRegistry(rbm RabbitInterface) *Registry {
+	return &Registry{rbm: rbm}
+}
+
+// Register adds a consumer started by StartAll. Consumers without a consumer tag get
+// a unique one when started, so they can be cancelled individually; cc itself is not
+// changed.
+func (r *Registry) Register(cc *ConsumerConfig, handler func(msg *amqp.Delivery)) *Registry {
+	return r.RegisterWeighted(cc, 1, handler)
+}
+
+// RegisterWeighted adds a consumer with the given Weight, e.g. 10 for a critical
+// queue and 1 for a bulk queue sharing the same workers
+func (r *Registry) RegisterWeighted(cc *ConsumerConfig, weight int, handler func(msg *amqp.Delivery)) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.regs = append(r.regs, Registration{Config: cc, Handler: handler, Weight: weight})
+	return r
+}
+
+// SetWorkers makes the registered consumers share n workers scheduled by Weight instead
+// of each consumer running its handler. buffer bounds the messages waiting per queue
+// (DEFAULT_SCHEDULER_BUFFER when <= 0) and should not be lower than the channel prefetch.
+// It must be called before StartAll.
+func (r *Registry) SetWorkers(n, buffer int) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.workers = n
+	r.buffer = buffer
+	return r
+}
+
+// Registrations returns a copy of the registered consumers
+func (r *Registry) Registrations() []Registration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Registration(nil), r.regs...)
+}
+
+// StartAll starts every registered consumer and keeps them registered across
+// reconnects. It returns immediately; the consumers stop on StopAll or when ctx is done.
+func (r *Registry) StartAll(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return cerrors.New(cerrors.Conflict, "rabbitmq: registry already started")
+	}
+
+	sup := Supervisor(r.rbm)
+
+	// the configs are copied so the tags are owned by the registry
+	regs := append([]Registration(nil), r.regs...)
+	tags := make([]string, len(regs))
+	for i := range regs {
+		cc := *regs[i].Config
+		if cc.Consumer == "" {
+			cc.Consumer = hostTag(cc.Queue, ctxutil.NewID())
+		}
+		regs[i].Config = &cc
+		tags[i] = cc.Consumer
+	}
+
+	if r.workers > 0 {
+		// the pool counts the scheduled jobs so its Drain waits for them
+		drain := &sync.WaitGroup{}
+		if pool, ok := sup.(*rbm_pool); ok {
+			drain = &pool.consumers
+		}
+		r.sched = newScheduler(regs, r.buffer, drain)
+		r.sched.start(r.workers)
+		for i := range regs {
+			regs[i].Handler = r.sched.handler(i)
+		}
+	}
+
+	stop := make(chan struct{})
+	r.stop = stop
+	r.tags = tags
+	r.running = true
+
+	async.Go(func() error {
+		sup.Supervise(stop, func() {
+			for _, reg := range regs {
+				r.rbm.Consumer(reg.Config, reg.Handler)
+			}
+		})
+		return nil
+	})
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			r.StopAll(context.Background())
+		case <-stop:
+		}
+	}()
+
+	return nil
+}
+
+// StopAll cancels the registered consumers and waits, until ctx is done,
+// for the callbacks in progress to return
+func (r *Registry) StopAll(ctx context.Context) error {
+	r.mu.Lock()
+	if !r.running {
+		r.mu.Unlock()
+		return nil
+	}
+	close(r.stop)
+	r.running = false
+
+	tags := r.tags
+	r.tags = nil
+	sched := r.sched
+	r.sched = nil
+	r.mu.Unlock()
+
+	// cancelled together, so the waits for their callbacks overlap
+	errs := make([]error, len(tags))
+	var wg sync.WaitGroup
+	for i, tag := range tags {
+		wg.Add(1)
+		go func(i int, tag string) {
+			defer wg.Done()
+			errs[i] = r.rbm.CancelConsumer(ctx, tag)
+		}(i, tag)
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	if sched != nil {
+		err = errors.Join(err, sched.close(ctx))
+	}
+	return err
+}
@@ -0,0 +1,153 @@
+package rabbitmq
+
+import (
+	"context"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const DEFAULT_SCHEDULER_BUFFER = 16
+
+type sched_job struct {
+	msg *amqp.Delivery
+}
+
+type sched_queue struct {
+	reg     Registration
+	weight  int
+	current int
+	pending []sched_job
+}
+
+// scheduler shares a fixed number of workers between several queues using smooth weighted
+// round-robin: under load a queue with weight 3 is served three times as often as a
+// queue with weight 1, and idle queues don't take turns
+type scheduler struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  []*sched_queue
+	buffer  int
+	closed  bool
+	workers sync.WaitGroup
+	drain   *sync.WaitGroup // the consumers of the pool, counts the pending jobs so Drain waits for them
+}
+
+func newScheduler(regs []Registration, buffer int, drain *sync.WaitGroup) *scheduler {
+	if buffer <= 0 {
+		buffer = DEFAULT_SCHEDULER_BUFFER
+	}
+
+	s := &scheduler{buffer: buffer, drain: drain}
+	s.cond = sync.NewCond(&s.mu)
+	for _, reg := range regs {
+		weight := reg.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		s.queues = append(s.queues, &sched_queue{reg: reg, weight: weight})
+	}
+	return s
+}
+
+// handler returns the callback registered on RabbitMQ for the queue i. It blocks
+// while the queue already has buffer messages waiting for a worker.
+func (s *scheduler) handler(i int) func(msg *amqp.Delivery) {
+	q := s.queues[i]
+	return func(msg *amqp.Delivery) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for len(q.pending) >= s.buffer && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			if !q.reg.Config.AutoAck {
+				msg.Nack(false, true)
+			}
+			return
+		}
+
+		// added while the consumer callback runs, so before Drain can see the count at zero
+		s.drain.Add(1)
+		q.pending = append(q.pending, sched_job{msg: msg})
+		s.cond.Broadcast()
+	}
+}
+
+func (s *scheduler) start(workers int) {
+	for i := 0; i < workers; i++ {
+		s.workers.Add(1)
+		async.Go(func() error {
+			defer s.workers.Done()
+			for {
+				q, job, ok := s.next()
+				if !ok {
+					return nil
+				}
+				s.run(q, job)
+			}
+		})
+	}
+}
+
+// next blocks until there is a message to handle. ok is false once the
+// scheduler is closed and every pending message was handled.
+func (s *scheduler) next() (q *sched_queue, job sched_job, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		total := 0
+		for _, c := range s.queues {
+			if len(c.pending) == 0 {
+				continue
+			}
+			c.current += c.weight
+			total += c.weight
+			if q == nil || c.current > q.current {
+				q = c
+			}
+		}
+
+		if q != nil {
+			q.current -= total
+			job = q.pending[0]
+			q.pending = q.pending[1:]
+			s.cond.Broadcast()
+			return q, job, true
+		}
+
+		if s.closed {
+			return nil, job, false
+		}
+		s.cond.Wait()
+	}
+}
+
+func (s *scheduler) run(q *sched_queue, job sched_job) {
+	defer s.drain.Done()
+
+	end := hooks.Begin(ctxutil.FromAMQPHeaders(context.Background(), job.msg.Headers), "rabbitmq", "Schedule", map[string]interface{}{
+		"queue": q.reg.Config.Queue, "weight": q.weight,
+	})
+	err := async.Safe(func() error { q.reg.Handler(job.msg); return nil })
+	end(err)
+	if err != nil && !q.reg.Config.AutoAck {
+		job.msg.Nack(false, false)
+	}
+}
+
+// close stops accepting messages and waits, until ctx is done, for the workers
+// to handle the messages already pending
+func (s *scheduler) close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	return waitGroup(ctx, &s.workers)
+}
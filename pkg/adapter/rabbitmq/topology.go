@@ -0,0 +1,152 @@
+package rabbitmq
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Topology groups every Exchange and Queue (with its Binds) a service depends on
+type Topology struct {
+	Exchanges []Exchange `json:"exchanges"`
+	Queues    []Queue    `json:"queues"`
+}
+
+// DeclareOnStart returns a warmup hook declaring t, for lifecycle.Coordinator.OnStart
+func DeclareOnStart(rbm RabbitInterface, t *Topology) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := rbm.Start(ctx); err != nil {
+			return err
+		}
+		return errors.Join(rbm.CompleteDeclare(t.Queues, t.Exchanges)...)
+	}
+}
+
+// LoadTopology reads a Topology definition from a JSON file
+func LoadTopology(path string) (*Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Println("Erro to read topology file")
+		return nil, err
+	}
+
+	t := &Topology{}
+	if err := json.Unmarshal(data, t); err != nil {
+		log.Println("Erro to parse topology file")
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// UnmarshalJSON declares integer arguments, ex: x-message-ttl, as int64 instead of the
+// float64 of encoding/json, which the broker rejects, see TableFromJSON
+func (t *Topology) UnmarshalJSON(data []byte) error {
+	type topology Topology
+	var raw topology
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	for i := range raw.Exchanges {
+		raw.Exchanges[i].Arguments = TableFromJSON(raw.Exchanges[i].Arguments)
+	}
+	for i := range raw.Queues {
+		raw.Queues[i].Arguments = TableFromJSON(raw.Queues[i].Arguments)
+	}
+
+	*t = Topology(raw)
+	return nil
+}
+
+func (rbm *rbm_pool) InspectQueue(name string) (queue amqp.Queue, err error) {
+	// passive declares close the channel when the queue doesn't exist,
+	// so a dedicated channel is used to keep the main one alive
+	ch, err := rbm.conn.Channel()
+	if err != nil {
+		log.Println("Erro to open inspect Channel in RabbitMQ")
+		return queue, wrapError("InspectQueue", err)
+	}
+	defer ch.Close()
+
+	queue, err = ch.QueueDeclarePassive(name, false, false, false, false, nil)
+	return queue, wrapError("InspectQueue", err)
+}
+
+func (rbm *rbm_pool) ExchangeExists(name string) (bool, error) {
+	ch, err := rbm.conn.Channel()
+	if err != nil {
+		log.Println("Erro to open inspect Channel in RabbitMQ")
+		return false, wrapError("ExchangeExists", err)
+	}
+	defer ch.Close()
+
+	if err := ch.ExchangeDeclarePassive(name, "direct", false, false, false, false, nil); err != nil {
+		if amqpErr, ok := err.(*amqp.Error); ok && amqpErr.Code == amqp.NotFound {
+			return false, nil
+		}
+		return false, wrapError("ExchangeExists", err)
+	}
+
+	return true, nil
+}
+
+func (rbm *rbm_pool) PurgeQueue(name string) (int, error) {
+	// a missing queue closes the channel, so a dedicated one is used as in InspectQueue
+	ch, err := rbm.conn.Channel()
+	if err != nil {
+		log.Println("Erro to open purge Channel in RabbitMQ")
+		return 0, wrapError("PurgeQueue", err)
+	}
+	defer ch.Close()
+
+	count, err := ch.QueuePurge(name, false)
+	if err != nil {
+		log.Println("Erro to QueuePurge in RabbitMQ")
+		return count, wrapError("PurgeQueue", err)
+	}
+
+	return count, nil
+}
+
+func (rbm *rbm_pool) GetMessage(queue string) (msg amqp.Delivery, ok bool, err error) {
+	ch, err := rbm.getChannel()
+	if err != nil {
+		return msg, false, err
+	}
+
+	msg, ok, err = ch.Get(queue, false)
+	if err != nil {
+		log.Println("Erro to Get message in RabbitMQ")
+	}
+
+	return msg, ok, wrapError("GetMessage", err)
+}
+
+// getChannel returns the channel of GetMessage, opened on first use and after it is
+// closed. It outlives each call so the deliveries can still be acked, and it is not the
+// main channel so a missing queue doesn't close the channel of producers and consumers.
+func (rbm *rbm_pool) getChannel() (*amqp.Channel, error) {
+	rbm.getLock.Lock()
+	defer rbm.getLock.Unlock()
+
+	if rbm.get != nil && !rbm.get.IsClosed() {
+		return rbm.get, nil
+	}
+
+	ch, err := rbm.conn.Channel()
+	if err != nil {
+		log.Println("Erro to open get Channel in RabbitMQ")
+		return nil, wrapError("GetMessage", err)
+	}
+
+	rbm.get = ch
+	return ch, nil
+}
@@ -0,0 +1,64 @@
+package redisdb
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+type memory_client struct {
+	items      map[string]memoryItem
+	modifyLock sync.RWMutex
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemory returns a process local RedisClientInterface, useful for
+// development and single instance services without a Redis server
+func NewMemory() RedisClientInterface {
+	return &memory_client{items: map[string]memoryItem{}}
+}
+
+func (mc *memory_client) Start(ctx context.Context) error { return nil }
+
+func (mc *memory_client) ReadData(ctx context.Context, key string) (data []byte, err error) {
+	mc.modifyLock.RLock()
+	item, ok := mc.items[key]
+	mc.modifyLock.RUnlock()
+
+	if !ok || time.Now().After(item.expires) {
+		// as the Redis client, so errors.Is(err, redis.Nil) works with both
+		return nil, cerrors.Wrap(cerrors.NotFound, "redisdb.ReadData", redis.Nil)
+	}
+
+	return item.data, nil
+}
+
+func (mc *memory_client) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool) {
+	if timer <= 0 {
+		timer = time.Duration(15 * time.Minute)
+	}
+
+	mc.modifyLock.Lock()
+	defer mc.modifyLock.Unlock()
+
+	now := time.Now()
+	for k, item := range mc.items {
+		if now.After(item.expires) {
+			delete(mc.items, k)
+		}
+	}
+
+	mc.items[key] = memoryItem{
+		data:    append([]byte(nil), data...),
+		expires: now.Add(timer),
+	}
+
+	return true
+}
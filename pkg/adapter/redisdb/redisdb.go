@@ -10,10 +10,16 @@ import (
 	"time"
 
 	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/lifecycle"
 	"github.com/go-redis/redis/v8"
 )
 
 type RedisClientInterface interface {
+	// ReadData returns the value of key. A missing key returns an error of kind NotFound
+	// wrapping redis.Nil, match it with cerrors.Is(err, cerrors.NotFound) or
+	// errors.Is(err, redis.Nil), err == redis.Nil doesn't match.
 	ReadData(ctx context.Context, key string) (data []byte, err error)
 	SaveData(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool)
 }
@@ -79,6 +85,11 @@ func New(conf *config.Config) RedisClientInterface {
 		rdb: redis.NewClient(opt),
 	}
 
+	// go-redis connects on first use anyway
+	if lifecycle.LazyConnect(conf) {
+		return rc
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*12)
 	defer cancel()
 
@@ -91,21 +102,39 @@ func New(conf *config.Config) RedisClientInterface {
 	return rc
 }
 
+// Start checks the connection, in lazy mode (SRV_LAZY_CONNECT) New doesn't. It is not
+// part of the interface, reach it as a lifecycle.Starter.
+func (rs *redis_client) Start(ctx context.Context) error {
+	if err := rs.rdb.Ping(ctx).Err(); err != nil {
+		log.Println("Erro ao conectar no Redis")
+		return cerrors.Wrap(cerrors.Unavailable, "redisdb.Start", err)
+	}
+	return nil
+}
+
 func (rs *redis_client) ReadData(ctx context.Context, key string) (data []byte, err error) {
+	end := hooks.Begin(ctx, "redisdb", "ReadData", map[string]interface{}{"key": key})
+	defer func() { end(err) }()
 
 	rs.modifyLock.Lock()
 	defer rs.modifyLock.Unlock()
 
 	data, err = rs.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, cerrors.Wrap(cerrors.NotFound, "redisdb.ReadData", err)
+	}
 	if err != nil {
 		log.Println(err.Error())
-		return
+		return nil, cerrors.Wrap(cerrors.Unavailable, "redisdb.ReadData", err)
 	}
 
 	return
 }
 
 func (rs *redis_client) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) (ok bool) {
+	var err error
+	end := hooks.Begin(ctx, "redisdb", "SaveData", map[string]interface{}{"key": key, "size": len(data)})
+	defer func() { end(err) }()
 
 	rs.modifyLock.Lock()
 	defer rs.modifyLock.Unlock()
@@ -114,10 +143,12 @@ func (rs *redis_client) SaveData(ctx context.Context, key string, data []byte, t
 		timer = time.Duration(15 * time.Minute)
 	}
 
-	result := rs.rdb.Set(ctx, key, data, timer)
-	if result.Val() == "1" {
-		ok = true
+	// SET replies "OK", a failure is reported only through the error
+	if err = rs.rdb.Set(ctx, key, data, timer).Err(); err != nil {
+		log.Println(err.Error())
+		err = cerrors.Wrap(cerrors.Unavailable, "redisdb.SaveData", err)
+		return false
 	}
 
-	return
+	return true
 }
@@ -0,0 +1,348 @@
+// Package ssh runs commands and transfers files on remote hosts over SSH, for
+// provisioning services. Host keys are always verified against a known_hosts file
+// and one connection per host is kept and reused by the sessions.
+//
+//	sh, err := ssh.New(conf)
+//	res, err := sh.Run(ctx, "10.0.0.5:22", "systemctl restart app")
+//	err = sh.Upload(ctx, "10.0.0.5:22", "/etc/app/config.json", bytes.NewReader(data), 0o640)
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	xssh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const (
+	DEFAULT_SSH_TIMEOUT = 10 // seconds
+	DEFAULT_PORT        = "22"
+)
+
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+type SSHInterface interface {
+	// Run executes cmd on host ("host" or "host:port"). A command exiting with a non zero
+	// status is not an error, check Result.ExitCode. When ctx is done the session is closed.
+	Run(ctx context.Context, host, cmd string) (*Result, error)
+	// Upload writes r to remotePath with mode, replacing it
+	Upload(ctx context.Context, host, remotePath string, r io.Reader, mode os.FileMode) error
+	// Download copies remotePath to w
+	Download(ctx context.Context, host, remotePath string, w io.Writer) error
+	// Close closes every pooled connection
+	Close() error
+}
+
+type ssh_pool struct {
+	conf *xssh.ClientConfig
+
+	mu      sync.Mutex
+	clients map[string]*xssh.Client
+}
+
+// New returns a client configured by SRV_SSH_USER, SRV_SSH_KEY_FILE, SRV_SSH_PASSPHRASE,
+// SRV_SSH_PASSWORD, SRV_SSH_KNOWN_HOSTS and SRV_SSH_TIMEOUT
+func New(conf *config.Config) (SSHInterface, error) {
+	if conf.SSHConfig == nil {
+		conf.SSHConfig = &config.SSHConfig{}
+	}
+
+	SRV_SSH_USER := os.Getenv("SRV_SSH_USER")
+	if SRV_SSH_USER != "" {
+		conf.SSH_USER = SRV_SSH_USER
+	}
+
+	SRV_SSH_KEY_FILE := os.Getenv("SRV_SSH_KEY_FILE")
+	if SRV_SSH_KEY_FILE != "" {
+		conf.SSH_KEY_FILE = SRV_SSH_KEY_FILE
+	}
+
+	SRV_SSH_PASSPHRASE := os.Getenv("SRV_SSH_PASSPHRASE")
+	if SRV_SSH_PASSPHRASE != "" {
+		conf.SSH_PASSPHRASE = SRV_SSH_PASSPHRASE
+	}
+
+	SRV_SSH_PASSWORD := os.Getenv("SRV_SSH_PASSWORD")
+	if SRV_SSH_PASSWORD != "" {
+		conf.SSH_PASSWORD = SRV_SSH_PASSWORD
+	}
+
+	SRV_SSH_KNOWN_HOSTS := os.Getenv("SRV_SSH_KNOWN_HOSTS")
+	if SRV_SSH_KNOWN_HOSTS != "" {
+		conf.SSH_KNOWN_HOSTS = SRV_SSH_KNOWN_HOSTS
+	}
+
+	SRV_SSH_TIMEOUT := os.Getenv("SRV_SSH_TIMEOUT")
+	if SRV_SSH_TIMEOUT != "" {
+		conf.SSH_TIMEOUT, _ = strconv.Atoi(SRV_SSH_TIMEOUT)
+	}
+	if conf.SSH_TIMEOUT <= 0 {
+		conf.SSH_TIMEOUT = DEFAULT_SSH_TIMEOUT
+	}
+
+	if conf.SSH_USER == "" || conf.SSH_KNOWN_HOSTS == "" {
+		return nil, cerrors.New(cerrors.Invalid, "ssh: SRV_SSH_USER and SRV_SSH_KNOWN_HOSTS are required")
+	}
+
+	hostKeys, err := knownhosts.New(conf.SSH_KNOWN_HOSTS)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "ssh: known hosts", err)
+	}
+
+	var auth []xssh.AuthMethod
+	if conf.SSH_KEY_FILE != "" {
+		pem, err := os.ReadFile(conf.SSH_KEY_FILE)
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "ssh: key file", err)
+		}
+
+		var signer xssh.Signer
+		if conf.SSH_PASSPHRASE != "" {
+			signer, err = xssh.ParsePrivateKeyWithPassphrase(pem, []byte(conf.SSH_PASSPHRASE))
+		} else {
+			signer, err = xssh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "ssh: key file", err)
+		}
+		auth = append(auth, xssh.PublicKeys(signer))
+	}
+	if conf.SSH_PASSWORD != "" {
+		auth = append(auth, xssh.Password(conf.SSH_PASSWORD))
+	}
+	if len(auth) == 0 {
+		return nil, cerrors.New(cerrors.Invalid, "ssh: SRV_SSH_KEY_FILE or SRV_SSH_PASSWORD is required")
+	}
+
+	return &ssh_pool{
+		conf: &xssh.ClientConfig{
+			User:            conf.SSH_USER,
+			Auth:            auth,
+			HostKeyCallback: hostKeys,
+			Timeout:         time.Duration(conf.SSH_TIMEOUT) * time.Second,
+		},
+		clients: map[string]*xssh.Client{},
+	}, nil
+}
+
+func (sp *ssh_pool) Run(ctx context.Context, host, cmd string) (res *Result, err error) {
+	end := hooks.Begin(ctx, "ssh", "Run", map[string]interface{}{"host": host})
+	defer func() { end(err) }()
+
+	var stdout, stderr bytes.Buffer
+	code, err := sp.session(ctx, host, cmd, nil, &stdout, &stderr)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Stdout: stdout.Bytes(), Stderr: stderr.Bytes(), ExitCode: code}, nil
+}
+
+func (sp *ssh_pool) Upload(ctx context.Context, host, remotePath string, r io.Reader, mode os.FileMode) (err error) {
+	end := hooks.Begin(ctx, "ssh", "Upload", map[string]interface{}{"host": host, "path": remotePath})
+	defer func() { end(err) }()
+
+	// written to a temporary file first so readers never see a partial file, with a random
+	// suffix so concurrent uploads of the same path don't write the same file
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	tmp := quote(remotePath + ".tmp-" + hex.EncodeToString(suffix))
+	cmd := fmt.Sprintf("{ cat > %s && chmod %o %s && mv -f %s %s; } || { rm -f %s; exit 1; }",
+		tmp, mode.Perm(), tmp, tmp, quote(remotePath), tmp)
+
+	var stderr bytes.Buffer
+	code, err := sp.session(ctx, host, cmd, r, io.Discard, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		return cerrors.New(cerrors.Invalid, fmt.Sprintf("ssh: upload %s: exit %d: %s", remotePath, code, strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+func (sp *ssh_pool) Download(ctx context.Context, host, remotePath string, w io.Writer) (err error) {
+	end := hooks.Begin(ctx, "ssh", "Download", map[string]interface{}{"host": host, "path": remotePath})
+	defer func() { end(err) }()
+
+	var stderr bytes.Buffer
+	code, err := sp.session(ctx, host, "cat "+quote(remotePath), nil, w, &stderr)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		kind := cerrors.Invalid
+		if strings.Contains(stderr.String(), "No such file") {
+			kind = cerrors.NotFound
+		}
+		return cerrors.New(kind, fmt.Sprintf("ssh: download %s: exit %d: %s", remotePath, code, strings.TrimSpace(stderr.String())))
+	}
+	return nil
+}
+
+func (sp *ssh_pool) Close() error {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	var errs []error
+	for host, client := range sp.clients {
+		if err := client.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(sp.clients, host)
+	}
+	return errors.Join(errs...)
+}
+
+// session runs cmd in a new session of the pooled connection of host and returns its exit code
+func (sp *ssh_pool) session(ctx context.Context, host, cmd string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	client, err := sp.client(ctx, host)
+	if err != nil {
+		return 0, err
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		// the connection is broken, dial again on the next call
+		sp.drop(host, client)
+		return 0, cerrors.Wrap(cerrors.Unavailable, "ssh: session", err)
+	}
+	defer session.Close()
+
+	session.Stdin = stdin
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() { done <- session.Run(cmd) }()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		session.Signal(xssh.SIGKILL)
+		session.Close()
+		return 0, cerrors.Wrap(cerrors.Unavailable, "ssh: run", ctx.Err())
+	}
+
+	var exitErr *xssh.ExitError
+	switch {
+	case err == nil:
+		return 0, nil
+	case errors.As(err, &exitErr):
+		return exitErr.ExitStatus(), nil
+	default:
+		sp.drop(host, client)
+		return 0, cerrors.Wrap(cerrors.Unavailable, "ssh: run", err)
+	}
+}
+
+func (sp *ssh_pool) client(ctx context.Context, host string) (*xssh.Client, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, DEFAULT_PORT)
+	}
+
+	sp.mu.Lock()
+	client, ok := sp.clients[host]
+	sp.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// dialed without the lock, so a slow host doesn't block the sessions of the others
+	client, err := sp.dial(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if existing, ok := sp.clients[host]; ok {
+		// dialed concurrently, keep the first connection
+		client.Close()
+		return existing, nil
+	}
+	sp.clients[host] = client
+	return client, nil
+}
+
+// dial connects to host and runs the handshake within the Timeout and the deadline of ctx
+func (sp *ssh_pool) dial(ctx context.Context, host string) (*xssh.Client, error) {
+	dialer := net.Dialer{Timeout: sp.conf.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "ssh: dial "+host, err)
+	}
+
+	deadline := time.Now().Add(sp.conf.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// a cancelled ctx interrupts the handshake
+	handshake, watched := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-handshake:
+		}
+	}()
+
+	c, chans, reqs, err := xssh.NewClientConn(conn, host, sp.conf)
+	close(handshake)
+	<-watched
+	if err == nil && ctx.Err() != nil {
+		c.Close()
+		err = ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) {
+			return nil, cerrors.Wrap(cerrors.Invalid, "ssh: host key of "+host, err)
+		}
+		return nil, cerrors.Wrap(cerrors.Unavailable, "ssh: handshake "+host, err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return xssh.NewClient(c, chans, reqs), nil
+}
+
+func (sp *ssh_pool) drop(host string, client *xssh.Client) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	for h, c := range sp.clients {
+		if c == client {
+			delete(sp.clients, h)
+		}
+	}
+	client.Close()
+}
+
+// quote returns s as a single quoted shell word
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,333 @@
+// Package archiver consumes a queue and writes the messages in batches of
+// gzip compressed NDJSON files, partitioned by date and hour, to a Store.
+// Every day partition has a manifest.json indexing its files.
+//
+//	{prefix}/dt=2023-07-01/hour=13/20230701T130501Z-<id>.ndjson.gz
+//	{prefix}/dt=2023-07-01/manifest.json
+//
+// Several replicas may archive the same queue when the Store is a VersionedStore,
+// otherwise concurrent manifest updates from other processes can lose entries.
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/bufpool"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DEFAULT_MAX_BATCH = 1000
+	DEFAULT_MAX_WAIT  = time.Minute
+	MANIFEST_NAME     = "manifest.json"
+
+	// manifestAttempts bounds the retries of a manifest update losing the race to another writer
+	manifestAttempts = 10
+)
+
+type Config struct {
+	Queue    string
+	Consumer string
+	Prefix   string        // path prefix inside the Store, ex: "archive/audit"
+	MaxBatch int           // messages per file
+	MaxWait  time.Duration // max time a message waits before its batch is written
+}
+
+// Record is one NDJSON line. Body holds the message as JSON when it is valid JSON,
+// otherwise BodyRaw holds the bytes (base64 encoded by encoding/json).
+type Record struct {
+	ReceivedAt  time.Time              `json:"received_at"`
+	Exchange    string                 `json:"exchange"`
+	RoutingKey  string                 `json:"routing_key"`
+	MessageID   string                 `json:"message_id,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	Headers     map[string]interface{} `json:"headers,omitempty"`
+	Body        json.RawMessage        `json:"body,omitempty"`
+	BodyRaw     []byte                 `json:"body_raw,omitempty"`
+}
+
+// Data returns the original message body
+func (r *Record) Data() []byte {
+	if r.BodyRaw != nil {
+		return r.BodyRaw
+	}
+	return r.Body
+}
+
+type Manifest struct {
+	Files []ManifestFile `json:"files"`
+}
+
+type ManifestFile struct {
+	Name  string    `json:"name"`
+	Count int       `json:"count"`
+	Bytes int       `json:"bytes"`
+	First time.Time `json:"first"`
+	Last  time.Time `json:"last"`
+}
+
+type Archiver struct {
+	rbm   rabbitmq.RabbitInterface
+	store Store
+	conf  Config
+	clock clock.Clock
+
+	incoming     chan *amqp.Delivery
+	manifestLock sync.Mutex
+}
+
+func New(rbm rabbitmq.RabbitInterface, store Store, conf Config) *Archiver {
+	if conf.MaxBatch <= 0 {
+		conf.MaxBatch = DEFAULT_MAX_BATCH
+	}
+	if conf.MaxWait <= 0 {
+		conf.MaxWait = DEFAULT_MAX_WAIT
+	}
+
+	return &Archiver{
+		rbm:      rbm,
+		store:    store,
+		conf:     conf,
+		clock:    clock.New(),
+		incoming: make(chan *amqp.Delivery, conf.MaxBatch),
+	}
+}
+
+// SetClock replaces the Clock used for batching and partitioning, mainly for tests
+func (a *Archiver) SetClock(c clock.Clock) {
+	a.clock = c
+}
+
+// Start consumes the queue and writes batches until ctx is done, then cancels the
+// consumer and flushes the pending batch, with the messages still buffered, before returning
+func (a *Archiver) Start(ctx context.Context) error {
+	stopped := make(chan struct{})
+	defer close(stopped)
+
+	cc := &rabbitmq.ConsumerConfig{
+		Queue:    a.conf.Queue,
+		Consumer: a.conf.Consumer,
+	}
+	a.rbm.Consumer(cc, func(msg *amqp.Delivery) {
+		select {
+		case a.incoming <- msg:
+		case <-stopped:
+			msg.Nack(false, true)
+		}
+	})
+
+	batch := make([]*amqp.Delivery, 0, a.conf.MaxBatch)
+	ticker := a.clock.NewTicker(a.conf.MaxWait)
+	defer ticker.Stop()
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+		if err := a.write(ctx, batch); err != nil {
+			log.Println("Erro to archive batch, requeueing:", err.Error())
+			for _, msg := range batch {
+				msg.Nack(false, true)
+			}
+		} else {
+			// acked one by one, the channel is shared with other consumers
+			for _, msg := range batch {
+				msg.Ack(false)
+			}
+		}
+		batch = batch[:0]
+	}
+
+	add := func(ctx context.Context, msg *amqp.Delivery) {
+		batch = append(batch, msg)
+		if len(batch) >= a.conf.MaxBatch {
+			flush(ctx)
+		}
+	}
+
+	for {
+		select {
+		case msg := <-a.incoming:
+			add(ctx, msg)
+		case <-ticker.C():
+			flush(ctx)
+		case <-ctx.Done():
+			// the broker stops delivering once the consumer is cancelled, the callbacks
+			// blocked on incoming return as the deliveries in flight are collected
+			cancelled := make(chan struct{})
+			go func() {
+				defer close(cancelled)
+				cctx, cancel := context.WithTimeout(context.Background(), a.conf.MaxWait)
+				defer cancel()
+				if err := a.rbm.CancelConsumer(cctx, cc.Consumer); err != nil {
+					log.Println("Erro to cancel archiver consumer:", err.Error())
+				}
+			}()
+
+			for collecting := true; collecting; {
+				select {
+				case msg := <-a.incoming:
+					add(context.Background(), msg)
+				case <-cancelled:
+					collecting = false
+				}
+			}
+			for buffered := true; buffered; {
+				select {
+				case msg := <-a.incoming:
+					add(context.Background(), msg)
+				default:
+					buffered = false
+				}
+			}
+
+			flush(context.Background())
+			return ctx.Err()
+		}
+	}
+}
+
+func (a *Archiver) write(ctx context.Context, batch []*amqp.Delivery) error {
+	now := a.clock.Now().UTC()
+
+	buf := bufpool.Get()
+	defer bufpool.Put(buf)
+
+	gz := bufpool.GetGzipWriter(buf)
+	defer bufpool.PutGzipWriter(gz)
+	enc := json.NewEncoder(gz)
+
+	entry := ManifestFile{Count: len(batch)}
+	for _, msg := range batch {
+		rec := Record{
+			ReceivedAt:  msg.Timestamp,
+			Exchange:    msg.Exchange,
+			RoutingKey:  msg.RoutingKey,
+			MessageID:   msg.MessageId,
+			ContentType: msg.ContentType,
+			Headers:     msg.Headers,
+		}
+		if rec.ReceivedAt.IsZero() {
+			rec.ReceivedAt = now
+		}
+		if json.Valid(msg.Body) {
+			rec.Body = msg.Body
+		} else {
+			rec.BodyRaw = msg.Body
+		}
+
+		if entry.First.IsZero() || rec.ReceivedAt.Before(entry.First) {
+			entry.First = rec.ReceivedAt
+		}
+		if rec.ReceivedAt.After(entry.Last) {
+			entry.Last = rec.ReceivedAt
+		}
+
+		if err := enc.Encode(&rec); err != nil {
+			return err
+		}
+	}
+
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	entry.Name = fmt.Sprintf("%s/hour=%02d/%s-%s.ndjson.gz",
+		DayPrefix(a.conf.Prefix, now), now.Hour(), now.Format("20060102T150405Z"), envelope.NewID()[:8])
+	entry.Bytes = buf.Len()
+
+	if err := a.store.Put(ctx, entry.Name, buf.Bytes(), "application/x-ndjson+gzip"); err != nil {
+		return err
+	}
+
+	return a.appendManifest(ctx, now, entry)
+}
+
+func (a *Archiver) appendManifest(ctx context.Context, day time.Time, entry ManifestFile) error {
+	a.manifestLock.Lock()
+	defer a.manifestLock.Unlock()
+
+	name := DayPrefix(a.conf.Prefix, day) + "/" + MANIFEST_NAME
+
+	vs, ok := a.store.(VersionedStore)
+	if !ok {
+		manifest, err := ReadManifest(ctx, a.store, name)
+		if err != nil {
+			return err
+		}
+		return a.putManifest(ctx, name, manifest, entry, "", nil)
+	}
+
+	// read, append and write only if no other writer changed the manifest meanwhile
+	for attempt := 1; ; attempt++ {
+		data, version, err := vs.GetVersion(ctx, name)
+		if err != nil && !cerrors.Is(err, cerrors.NotFound) {
+			return err
+		}
+		manifest, err := decodeManifest(data)
+		if err != nil {
+			return err
+		}
+
+		err = a.putManifest(ctx, name, manifest, entry, version, vs)
+		if !cerrors.Is(err, cerrors.Conflict) || attempt >= manifestAttempts {
+			return err
+		}
+	}
+}
+
+// putManifest appends entry to manifest and writes it, conditionally when vs isn't nil
+func (a *Archiver) putManifest(ctx context.Context, name string, manifest *Manifest, entry ManifestFile, version string, vs VersionedStore) error {
+	manifest.Files = append(manifest.Files, entry)
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	if vs != nil {
+		return vs.PutIfMatch(ctx, name, data, "application/json", version)
+	}
+	return a.store.Put(ctx, name, data, "application/json")
+}
+
+// DayPrefix returns the partition path of a day, ex: "archive/audit/dt=2023-07-01"
+func DayPrefix(prefix string, day time.Time) string {
+	partition := "dt=" + day.UTC().Format("2006-01-02")
+	if prefix == "" {
+		return partition
+	}
+	return prefix + "/" + partition
+}
+
+// ReadManifest loads a manifest, returning an empty one when it doesn't exist yet
+func ReadManifest(ctx context.Context, store Store, name string) (*Manifest, error) {
+	data, err := store.Get(ctx, name)
+	if cerrors.Is(err, cerrors.NotFound) {
+		return &Manifest{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeManifest(data)
+}
+
+func decodeManifest(data []byte) (*Manifest, error) {
+	manifest := &Manifest{}
+	if data == nil {
+		return manifest, nil
+	}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "archiver.ReadManifest", err)
+	}
+	return manifest, nil
+}
@@ -0,0 +1,195 @@
+package archiver
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const HEADER_REPLAYED_FROM = "x-replayed-from"
+
+type ReplayConfig struct {
+	Prefix string    // same Prefix of the Archiver Config
+	From   time.Time // inclusive
+	To     time.Time // exclusive
+	// Exchange overrides the archived exchange when set
+	Exchange string
+	// RoutingKey rewrites the archived routing key, returning "" skips the record
+	RoutingKey func(rec *Record) string
+	// RatePerSecond limits the published messages, 0 is unlimited
+	RatePerSecond int
+	// DryRun reads and counts the records without publishing
+	DryRun bool
+}
+
+type ReplayResult struct {
+	Files     int
+	Published int
+	Skipped   int
+}
+
+type Replayer struct {
+	rbm   rabbitmq.RabbitInterface
+	store Store
+	clock clock.Clock
+}
+
+// NewReplayer returns a Replayer republishing the files written by an Archiver to the same store
+func NewReplayer(rbm rabbitmq.RabbitInterface, store Store) *Replayer {
+	return &Replayer{rbm: rbm, store: store, clock: clock.New()}
+}
+
+// SetClock replaces the Clock used for rate limiting, mainly for tests
+func (r *Replayer) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Replay publishes, in archive order, every record received in [From, To).
+// Day manifests are used to skip files outside the range without reading them.
+func (r *Replayer) Replay(ctx context.Context, conf ReplayConfig) (res ReplayResult, err error) {
+	end := hooks.Begin(ctx, "archiver", "Replay", map[string]interface{}{
+		"prefix": conf.Prefix, "from": conf.From, "to": conf.To,
+	})
+	defer func() { end(err) }()
+
+	if conf.From.IsZero() || !conf.To.After(conf.From) {
+		return res, cerrors.New(cerrors.Invalid, "archiver: replay range requires From before To")
+	}
+
+	var interval time.Duration
+	if conf.RatePerSecond > 0 {
+		interval = time.Second / time.Duration(conf.RatePerSecond)
+	}
+	next := r.clock.Now()
+
+	for day := conf.From.UTC().Truncate(24 * time.Hour); day.Before(conf.To); day = day.Add(24 * time.Hour) {
+		manifest, err := ReadManifest(ctx, r.store, DayPrefix(conf.Prefix, day)+"/"+MANIFEST_NAME)
+		if err != nil {
+			return res, err
+		}
+
+		for _, file := range manifest.Files {
+			if file.Last.Before(conf.From) || !file.First.Before(conf.To) {
+				continue
+			}
+
+			records, err := r.read(ctx, file.Name)
+			if err != nil {
+				return res, err
+			}
+			res.Files++
+
+			for i := range records {
+				rec := &records[i]
+				if rec.ReceivedAt.Before(conf.From) || !rec.ReceivedAt.Before(conf.To) {
+					continue
+				}
+
+				pc := &rabbitmq.ProducerConfig{Exchange: rec.Exchange, Key: rec.RoutingKey}
+				if conf.Exchange != "" {
+					pc.Exchange = conf.Exchange
+				}
+				if conf.RoutingKey != nil {
+					pc.Key = conf.RoutingKey(rec)
+					if pc.Key == "" {
+						res.Skipped++
+						continue
+					}
+				}
+
+				if conf.DryRun {
+					res.Published++
+					continue
+				}
+
+				if interval > 0 {
+					if wait := next.Sub(r.clock.Now()); wait > 0 {
+						select {
+						case <-r.clock.After(wait):
+						case <-ctx.Done():
+							return res, ctx.Err()
+						}
+					}
+					next = maxTime(next, r.clock.Now()).Add(interval)
+				} else if err := ctx.Err(); err != nil {
+					return res, err
+				}
+
+				// nested headers, ex: x-death, are decoded as maps that AMQP rejects
+				headers := rabbitmq.TableFromJSON(rec.Headers)
+				if headers == nil {
+					headers = amqp.Table{}
+				}
+				headers[HEADER_REPLAYED_FROM] = file.Name
+
+				if err := r.rbm.Producer(ctx, pc, &rabbitmq.Message{
+					Data:        rec.Data(),
+					ContentType: rec.ContentType,
+					MessageID:   rec.MessageID,
+					Timestamp:   rec.ReceivedAt,
+					Headers:     headers,
+				}); err != nil {
+					return res, err
+				}
+				res.Published++
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// read returns the records of an archive file
+func (r *Replayer) read(ctx context.Context, name string) ([]Record, error) {
+	ctx, cancel := deadline.Apply(ctx, deadline.Download)
+	defer cancel()
+
+	data, err := r.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "archiver.Replay: "+name, err)
+	}
+	defer gz.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		// numbers are kept as json.Number so integer headers are published as int64
+		var rec Record
+		dec := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+		dec.UseNumber()
+		if err := dec.Decode(&rec); err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "archiver.Replay: "+name, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "archiver.Replay: "+name, err)
+	}
+	return records, nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
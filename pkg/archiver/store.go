@@ -0,0 +1,130 @@
+package archiver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Store is where archive files are written, usually a blob container.
+// Put must not retain data after returning, it comes from a pooled buffer.
+// Get must return an error of kind cerrors.NotFound for missing names.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// VersionedStore is a Store with conditional writes, ex: blob ETags. The manifests are
+// updated through it when available, so several replicas can archive the same partition.
+type VersionedStore interface {
+	Store
+	// GetVersion returns the data of name and its version, or an error of kind NotFound
+	GetVersion(ctx context.Context, name string) ([]byte, string, error)
+	// PutIfMatch writes data only when the version of name is still version, "" meaning
+	// that name must not exist, and returns an error of kind Conflict otherwise
+	PutIfMatch(ctx context.Context, name string, data []byte, contentType, version string) error
+}
+
+// lockStale is the age of a lock file left by a crashed process
+const lockStale = 30 * time.Second
+
+type dirStore struct {
+	root string
+}
+
+// NewDirStore returns a Store writing files below a local directory
+func NewDirStore(root string) Store {
+	return &dirStore{root: root}
+}
+
+func (ds *dirStore) Put(ctx context.Context, name string, data []byte, contentType string) error {
+	path := filepath.Join(ds.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (ds *dirStore) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(ds.root, filepath.FromSlash(name)))
+	if os.IsNotExist(err) {
+		return nil, cerrors.Wrap(cerrors.NotFound, "archiver.Get", err)
+	}
+	return data, err
+}
+
+// GetVersion uses the SHA-256 of the file as version
+func (ds *dirStore) GetVersion(ctx context.Context, name string) ([]byte, string, error) {
+	data, err := ds.Get(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, version(data), nil
+}
+
+// PutIfMatch holds a lock file next to name, so it is safe between processes of the same host
+func (ds *dirStore) PutIfMatch(ctx context.Context, name string, data []byte, contentType, expected string) error {
+	path := filepath.Join(ds.root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(ctx, path+".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		if expected != "" {
+			return cerrors.New(cerrors.Conflict, "archiver: "+name+" was deleted")
+		}
+	case err != nil:
+		return err
+	case version(current) != expected:
+		return cerrors.New(cerrors.Conflict, "archiver: "+name+" was changed")
+	}
+
+	return ds.Put(ctx, name, data, contentType)
+}
+
+func version(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// lockFile creates path exclusively, waiting until ctx is done while it exists
+func lockFile(ctx context.Context, path string) (func(), error) {
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStale {
+			os.Remove(path)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, cerrors.Wrap(cerrors.Unavailable, "archiver.lock", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
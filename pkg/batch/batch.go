@@ -0,0 +1,181 @@
+// Package batch runs long jobs over a Source in chunks, processing the items of each
+// chunk in parallel on a workerpool and checkpointing the cursor, so a job restarted
+// after a crash resumes after the last checkpoint:
+//
+//	src := &batch.SQLSource[Customer]{DB: db, Query: query, Scan: scanCustomer}
+//	r := batch.New[Customer](batch.Job[Customer]{Name: "reindex-2023-07", Source: src, Process: reindex}, checkpoints)
+//	cp, err := r.Run(ctx)
+//
+// Items are processed at least once: the items of a chunk interrupted by a crash are
+// processed again on resume, so Process must be idempotent.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/core/workerpool"
+)
+
+const DEFAULT_CHUNK_SIZE = 100
+
+type Job[T any] struct {
+	Name      string // unique, identifies the Checkpoint
+	Source    Source[T]
+	Process   func(ctx context.Context, item T) error
+	ChunkSize int // items per chunk, DEFAULT_CHUNK_SIZE when <= 0
+	Workers   int // items processed in parallel, workerpool.DEFAULT_WORKERS when <= 0
+	// ItemTimeout is the optional deadline of every Process call
+	ItemTimeout time.Duration
+	// CheckpointInterval is the min time between checkpoints, every chunk is checkpointed when <= 0
+	CheckpointInterval time.Duration
+	// MaxFailures is the number of failed items tolerated before the job stops, 0 stops on the first
+	MaxFailures int64
+	// OnFailure is called with every failed item, ex: to write it to a dead letter store
+	OnFailure func(ctx context.Context, item T, err error)
+}
+
+type Runner[T any] struct {
+	job         Job[T]
+	checkpoints Checkpoints
+	clock       clock.Clock
+}
+
+func New[T any](job Job[T], checkpoints Checkpoints) *Runner[T] {
+	if job.ChunkSize <= 0 {
+		job.ChunkSize = DEFAULT_CHUNK_SIZE
+	}
+	return &Runner[T]{job: job, checkpoints: checkpoints, clock: clock.New()}
+}
+
+func (r *Runner[T]) SetClock(c clock.Clock) {
+	r.clock = c
+}
+
+// Progress returns the last Checkpoint of the job, an error of kind NotFound before its first run
+func (r *Runner[T]) Progress(ctx context.Context) (*Checkpoint, error) {
+	return r.checkpoints.Load(ctx, r.job.Name)
+}
+
+// Reset deletes the Checkpoint, the next Run starts from the beginning
+func (r *Runner[T]) Reset(ctx context.Context) error {
+	return r.checkpoints.Delete(ctx, r.job.Name)
+}
+
+// Run processes the items after the last Checkpoint until the Source ends, ctx is done
+// or MaxFailures is exceeded. A job already Done returns its Checkpoint without running,
+// call Reset to run it again.
+func (r *Runner[T]) Run(ctx context.Context) (cp *Checkpoint, err error) {
+	end := hooks.Begin(ctx, "batch", "Run", map[string]interface{}{"job": r.job.Name})
+	defer func() { end(err) }()
+
+	cp, err = r.checkpoints.Load(ctx, r.job.Name)
+	switch {
+	case cerrors.Is(err, cerrors.NotFound):
+		cp = &Checkpoint{Job: r.job.Name, StartedAt: r.clock.Now().UTC()}
+	case err != nil:
+		return nil, err
+	case cp.Done:
+		return cp, nil
+	}
+
+	pool := workerpool.New(workerpool.Config{Workers: r.job.Workers, JobTimeout: r.job.ItemTimeout})
+	defer pool.Drain(context.Background())
+
+	lastSave := r.clock.Now()
+	for {
+		if err := ctx.Err(); err != nil {
+			return cp, r.save(cp, err)
+		}
+
+		items, next, err := r.job.Source.Next(ctx, cp.Cursor, r.job.ChunkSize)
+		if err != nil {
+			return cp, r.save(cp, err)
+		}
+
+		// the counters only include checkpointed chunks, a resumed chunk is counted once
+		failed, err := r.chunk(ctx, pool, items)
+		if err != nil {
+			return cp, r.save(cp, err)
+		}
+		if cp.Failed+failed > r.job.MaxFailures {
+			return cp, r.save(cp, cerrors.New(cerrors.Invalid, fmt.Sprintf("batch: %s stopped after %d failed items", r.job.Name, cp.Failed+failed)))
+		}
+
+		cp.Failed += failed
+		cp.Processed += int64(len(items))
+		if next != "" {
+			cp.Cursor = next
+		}
+		cp.Done = len(items) < r.job.ChunkSize || next == ""
+
+		if cp.Done || r.clock.Now().Sub(lastSave) >= r.job.CheckpointInterval {
+			if err := r.save(cp, nil); err != nil {
+				return cp, err
+			}
+			lastSave = r.clock.Now()
+		}
+		if cp.Done {
+			return cp, nil
+		}
+	}
+}
+
+// chunk processes items on pool and returns the number of failures. An error means
+// the chunk wasn't fully processed and must not be checkpointed.
+func (r *Runner[T]) chunk(ctx context.Context, pool *workerpool.Pool, items []T) (int64, error) {
+	var (
+		mu     sync.Mutex
+		failed int64
+		wg     sync.WaitGroup
+	)
+
+	for _, item := range items {
+		item := item
+		wg.Add(1)
+		err := pool.Submit(ctx, func(ctx context.Context) error {
+			defer wg.Done()
+			// a panic is a failure of the item, not of the job
+			if err := async.Safe(func() error { return r.job.Process(ctx, item) }); err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				if r.job.OnFailure != nil {
+					r.job.OnFailure(ctx, item, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			wg.Done()
+			wg.Wait()
+			return failed, err
+		}
+	}
+
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return failed, err
+	}
+	return failed, nil
+}
+
+// save writes cp and returns cause, or the save error when there is no cause
+func (r *Runner[T]) save(cp *Checkpoint, cause error) error {
+	cp.UpdatedAt = r.clock.Now().UTC()
+	// saved even when ctx is done, to keep the progress of an interrupted job
+	if err := r.checkpoints.Save(context.Background(), cp); err != nil {
+		log.Println("Erro to save batch checkpoint:", err.Error())
+		if cause == nil {
+			return err
+		}
+	}
+	return cause
+}
@@ -0,0 +1,105 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/archiver"
+	"github.com/faelp22/go-commons-libs/pkg/kv"
+)
+
+// Checkpoint is the progress of a job, every item up to Cursor has been processed
+type Checkpoint struct {
+	Job       string    `json:"job"`
+	Cursor    string    `json:"cursor"`
+	Processed int64     `json:"processed"`
+	Failed    int64     `json:"failed"`
+	Done      bool      `json:"done"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Checkpoints interface {
+	// Load returns the Checkpoint of job or an error of kind cerrors.NotFound
+	Load(ctx context.Context, job string) (*Checkpoint, error)
+	Save(ctx context.Context, cp *Checkpoint) error
+	Delete(ctx context.Context, job string) error
+}
+
+type kv_checkpoints struct {
+	store  kv.Store
+	prefix string
+}
+
+// NewKVCheckpoints keeps the Checkpoints as JSON under "{prefix}{job}" in store
+func NewKVCheckpoints(store kv.Store, prefix string) Checkpoints {
+	return &kv_checkpoints{store: store, prefix: prefix}
+}
+
+func (kc *kv_checkpoints) Load(ctx context.Context, job string) (*Checkpoint, error) {
+	data, err := kc.store.Get(ctx, kc.prefix+job)
+	if err != nil {
+		return nil, err
+	}
+	return decode(data)
+}
+
+func (kc *kv_checkpoints) Save(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return kc.store.Set(ctx, kc.prefix+cp.Job, data, 0)
+}
+
+func (kc *kv_checkpoints) Delete(ctx context.Context, job string) error {
+	return kc.store.Delete(ctx, kc.prefix+job)
+}
+
+type blob_checkpoints struct {
+	store  archiver.Store
+	prefix string
+}
+
+// NewBlobCheckpoints keeps the Checkpoints as "{prefix}/{job}.json" files in store. The
+// archiver.Store can't delete, Delete overwrites the file with an empty one.
+func NewBlobCheckpoints(store archiver.Store, prefix string) Checkpoints {
+	return &blob_checkpoints{store: store, prefix: prefix}
+}
+
+func (bc *blob_checkpoints) Load(ctx context.Context, job string) (*Checkpoint, error) {
+	data, err := bc.store.Get(ctx, bc.name(job))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, cerrors.New(cerrors.NotFound, "batch: no checkpoint for "+job)
+	}
+	return decode(data)
+}
+
+func (bc *blob_checkpoints) Save(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return bc.store.Put(ctx, bc.name(cp.Job), data, "application/json")
+}
+
+func (bc *blob_checkpoints) Delete(ctx context.Context, job string) error {
+	return bc.store.Put(ctx, bc.name(job), nil, "application/json")
+}
+
+func (bc *blob_checkpoints) name(job string) string {
+	return bc.prefix + "/" + job + ".json"
+}
+
+func decode(data []byte) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "batch.decode", err)
+	}
+	return &cp, nil
+}
@@ -0,0 +1,88 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sort"
+)
+
+// Source reads the items of a job in chunks. Next returns up to size items after cursor
+// ("" for the beginning) and the cursor of the last one; a chunk shorter than size or an
+// empty next cursor ends the job. Cursors are saved in the checkpoints, so they must
+// survive a restart: keys, offsets or names, never in-memory handles.
+type Source[T any] interface {
+	Next(ctx context.Context, cursor string, size int) (items []T, next string, err error)
+}
+
+// SQLSource pages a query by key (keyset pagination), so a resumed job continues after
+// the last checkpointed row. Query receives the cursor as $1 and the chunk size as $2:
+//
+//	SELECT id, email FROM customers WHERE id > $1 ORDER BY id LIMIT $2
+//
+// With an integer key use a cast such as "id > $1::bigint" and a Start of "0".
+type SQLSource[T any] struct {
+	DB    *sql.DB
+	Query string
+	Start string // cursor of the first chunk, the empty string when ""
+	// Scan reads a row and returns its key, the cursor of the next chunk
+	Scan func(rows *sql.Rows) (item T, key string, err error)
+}
+
+func (ss *SQLSource[T]) Next(ctx context.Context, cursor string, size int) ([]T, string, error) {
+	if cursor == "" {
+		cursor = ss.Start
+	}
+
+	rows, err := ss.DB.QueryContext(ctx, ss.Query, cursor, size)
+	if err != nil {
+		log.Println("Erro to query batch chunk in PGSQL")
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var (
+		items []T
+		next  string
+	)
+	for rows.Next() {
+		item, key, err := ss.Scan(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+		next = key
+	}
+	return items, next, rows.Err()
+}
+
+// ListSource iterates over names returned by List, ex: the blobs under a prefix, in
+// lexical order. The listing is read once per Runner and the cursor is the last name.
+type ListSource struct {
+	List func(ctx context.Context) ([]string, error)
+
+	names []string
+}
+
+func (ls *ListSource) Next(ctx context.Context, cursor string, size int) ([]string, string, error) {
+	if ls.names == nil {
+		names, err := ls.List(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		sort.Strings(names)
+		ls.names = append([]string{}, names...)
+	}
+
+	start := sort.Search(len(ls.names), func(i int) bool { return ls.names[i] > cursor })
+	end := start + size
+	if end > len(ls.names) {
+		end = len(ls.names)
+	}
+
+	chunk := ls.names[start:end]
+	if len(chunk) == 0 {
+		return nil, "", nil
+	}
+	return chunk, chunk[len(chunk)-1], nil
+}
@@ -0,0 +1,319 @@
+// Package capture is a debug mode recording the messages published and consumed
+// through RabbitMQ, with sampling and redaction, so production event flows can be
+// inspected and replayed in test environments.
+//
+//	c := capture.New(capture.Config{Size: 1000, Sample: 0.1, RedactHeaders: []string{"authorization"}, RedactFields: []string{"customer.document"}})
+//	rbm = capture.NewRabbitMQ(rbm, c)
+//	...
+//	c.Replay(ctx, testRbm, c.Snapshot())
+//	c.Close(ctx) // writes the messages still queued for the Store
+package capture
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	"github.com/faelp22/go-commons-libs/core/redact"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DEFAULT_SIZE       = 1000
+	DEFAULT_QUEUE_SIZE = 100
+	REDACTED           = "[REDACTED]"
+
+	DirectionPublished = "published"
+	DirectionConsumed  = "consumed"
+)
+
+// Store persists the captured messages, usually a blob container or archiver.NewDirStore
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+}
+
+type Config struct {
+	Size          int      // messages kept in the ring buffer, DEFAULT_SIZE when <= 0
+	Sample        float64  // fraction of the messages captured, every message when <= 0 or >= 1
+	RedactHeaders []string // header names replaced by REDACTED, case insensitive
+	RedactFields  []string // dotted paths of JSON bodies replaced by REDACTED, ex: "customer.document"
+	// Redact masks dotted paths of JSON bodies with the default Redactor, ex: {"customer.email": redact.Mask}
+	Redact map[string]redact.Strategy
+	Store  Store  // optional, every captured message is also written to it
+	Prefix string // name prefix inside the Store
+	// QueueSize bounds the messages waiting to be written to the Store, DEFAULT_QUEUE_SIZE
+	// when <= 0. Messages captured while it is full are not written, see Dropped.
+	QueueSize int
+}
+
+// Captured is a recorded message, already redacted
+type Captured struct {
+	Direction   string                 `json:"direction"`
+	Exchange    string                 `json:"exchange,omitempty"`
+	Key         string                 `json:"key,omitempty"`
+	Queue       string                 `json:"queue,omitempty"`
+	MessageID   string                 `json:"message_id,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	Type        string                 `json:"type,omitempty"`
+	Headers     map[string]interface{} `json:"headers,omitempty"`
+	Body        []byte                 `json:"body"`
+	CapturedAt  time.Time              `json:"captured_at"`
+}
+
+type stored struct {
+	name string
+	data []byte
+}
+
+type Capture struct {
+	conf    Config
+	mu      sync.Mutex
+	ring    []Captured
+	next    int
+	full    bool
+	seq     uint64
+	clock   clock.Clock
+	queue   chan stored // writes to the Store, nil without Store
+	closed  bool
+	written chan struct{} // closed once the queue is drained
+	dropped atomic.Uint64
+}
+
+// New creates a Capture, with a Store it starts the goroutine writing to it: call
+// Close to write the queued messages on shutdown
+func New(conf Config) *Capture {
+	if conf.Size <= 0 {
+		conf.Size = DEFAULT_SIZE
+	}
+	if conf.QueueSize <= 0 {
+		conf.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+
+	c := &Capture{conf: conf, ring: make([]Captured, conf.Size), clock: clock.New()}
+	if conf.Store != nil {
+		c.queue = make(chan stored, conf.QueueSize)
+		c.written = make(chan struct{})
+		go func() {
+			defer close(c.written)
+			for s := range c.queue {
+				c.put(s)
+			}
+		}()
+	}
+	return c
+}
+
+func (c *Capture) SetClock(cl clock.Clock) {
+	c.clock = cl
+}
+
+// Record redacts and stores m when it is sampled
+func (c *Capture) Record(ctx context.Context, m Captured) {
+	if c.conf.Sample > 0 && c.conf.Sample < 1 && rand.Float64() >= c.conf.Sample {
+		return
+	}
+
+	m.Headers = c.redactHeaders(m.Headers)
+	m.Body = c.redactBody(ctx, m.ContentType, m.Body)
+	if m.CapturedAt.IsZero() {
+		m.CapturedAt = c.clock.Now()
+	}
+
+	c.mu.Lock()
+	c.ring[c.next] = m
+	c.next = (c.next + 1) % len(c.ring)
+	if c.next == 0 {
+		c.full = true
+	}
+	c.seq++
+	seq := c.seq
+	c.mu.Unlock()
+
+	if c.queue == nil {
+		return
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		log.Println("Erro to store captured message")
+		log.Println(err)
+		return
+	}
+	name := fmt.Sprintf("%s/%s/%020d-%d.json", strings.TrimSuffix(c.conf.Prefix, "/"),
+		m.CapturedAt.UTC().Format("2006-01-02"), m.CapturedAt.UnixNano(), seq)
+
+	// the Store is written in the background, capturing must not slow the traffic down
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.queue <- stored{name: strings.TrimPrefix(name, "/"), data: data}:
+	default:
+		if c.dropped.Add(1) == 1 {
+			log.Println("Erro to store captured message, the queue is full")
+		}
+	}
+}
+
+// put writes s to the Store, the context of Record is gone by then
+func (c *Capture) put(s stored) {
+	async.Safe(func() error {
+		ctx, cancel := deadline.Apply(context.Background(), deadline.Upload)
+		defer cancel()
+
+		if err := c.conf.Store.Put(ctx, s.name, s.data, "application/json"); err != nil {
+			log.Println("Erro to store captured message")
+			log.Println(err)
+		}
+		return nil
+	})
+}
+
+// Dropped returns how many captured messages weren't written to the Store because
+// its queue was full
+func (c *Capture) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+// Close stops writing to the Store and waits, until ctx is done, for the queued
+// messages to be written. Messages captured afterwards stay in the ring buffer only.
+func (c *Capture) Close(ctx context.Context) error {
+	if c.queue == nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-c.written:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Snapshot returns the messages in the ring buffer, oldest first
+func (c *Capture) Snapshot() []Captured {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.full {
+		return append([]Captured(nil), c.ring[:c.next]...)
+	}
+	out := make([]Captured, 0, len(c.ring))
+	out = append(out, c.ring[c.next:]...)
+	return append(out, c.ring[:c.next]...)
+}
+
+// Reset empties the ring buffer
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ring = make([]Captured, len(c.ring))
+	c.next = 0
+	c.full = false
+}
+
+// Replay publishes msgs through rbm in order. Consumed messages are published to the
+// default exchange with their queue as routing key, so they reach the same queue.
+func (c *Capture) Replay(ctx context.Context, rbm rabbitmq.RabbitInterface, msgs []Captured) error {
+	for _, m := range msgs {
+		pc := &rabbitmq.ProducerConfig{Exchange: m.Exchange, Key: m.Key}
+		if m.Direction == DirectionConsumed && m.Queue != "" {
+			pc = &rabbitmq.ProducerConfig{Key: m.Queue}
+		}
+
+		err := rbm.Producer(ctx, pc, &rabbitmq.Message{
+			Data:        m.Body,
+			ContentType: m.ContentType,
+			MessageID:   m.MessageID,
+			Type:        m.Type,
+			Headers:     amqp.Table(m.Headers),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Capture) redactHeaders(headers map[string]interface{}) map[string]interface{} {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	out := make(map[string]interface{}, len(headers))
+	for k, v := range headers {
+		out[k] = v
+		for _, name := range c.conf.RedactHeaders {
+			if strings.EqualFold(k, name) {
+				out[k] = REDACTED
+				break
+			}
+		}
+	}
+	return out
+}
+
+// redactBody replaces RedactFields, Redact and the paths registered with redact.Register
+// in JSON bodies. Bodies that aren't JSON are kept as is.
+func (c *Capture) redactBody(ctx context.Context, contentType string, body []byte) []byte {
+	if (len(c.conf.RedactFields) == 0 && len(c.conf.Redact) == 0 && !redact.Default().Registered()) || (contentType != "" && !strings.Contains(contentType, "json")) {
+		return append([]byte(nil), body...)
+	}
+
+	var doc interface{}
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return append([]byte(nil), body...)
+	}
+
+	for _, path := range c.conf.RedactFields {
+		redactPath(doc, strings.Split(path, "."))
+	}
+	if err := redact.Default().Fields(ctx, doc, c.conf.Redact); err != nil {
+		// a body that couldn't be redacted isn't captured
+		return nil
+	}
+
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return append([]byte(nil), body...)
+	}
+	return out
+}
+
+// redactPath walks objects by key and arrays element by element
+func redactPath(doc interface{}, path []string) {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[path[0]]
+		if !ok {
+			return
+		}
+		if len(path) == 1 {
+			v[path[0]] = REDACTED
+			return
+		}
+		redactPath(child, path[1:])
+	case []interface{}:
+		for _, item := range v {
+			redactPath(item, path)
+		}
+	}
+}
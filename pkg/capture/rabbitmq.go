@@ -0,0 +1,60 @@
+package capture
+
+import (
+	"context"
+
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type captureRabbit struct {
+	rabbitmq.RabbitInterface
+	c *Capture
+}
+
+// NewRabbitMQ wraps rbm so the published and consumed messages are recorded in c
+func NewRabbitMQ(rbm rabbitmq.RabbitInterface, c *Capture) rabbitmq.RabbitInterface {
+	return &captureRabbit{RabbitInterface: rbm, c: c}
+}
+
+func (cr *captureRabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	err := cr.RabbitInterface.Producer(ctx, pc, msg)
+	if err == nil {
+		cr.c.Record(ctx, Captured{
+			Direction:   DirectionPublished,
+			Exchange:    pc.Exchange,
+			Key:         pc.Key,
+			MessageID:   msg.MessageID,
+			ContentType: msg.ContentType,
+			Type:        msg.Type,
+			Headers:     msg.Headers,
+			Body:        msg.Data,
+		})
+	}
+	return err
+}
+
+func (cr *captureRabbit) Consumer(conf *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.Consumer(conf, cr.middleware(conf.Queue, callback))
+}
+
+func (cr *captureRabbit) StartConsumer(conf *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.StartConsumer(conf, cr.middleware(conf.Queue, callback))
+}
+
+func (cr *captureRabbit) middleware(queue string, callback func(msg *amqp.Delivery)) func(msg *amqp.Delivery) {
+	return func(msg *amqp.Delivery) {
+		cr.c.Record(context.Background(), Captured{
+			Direction:   DirectionConsumed,
+			Exchange:    msg.Exchange,
+			Key:         msg.RoutingKey,
+			Queue:       queue,
+			MessageID:   msg.MessageId,
+			ContentType: msg.ContentType,
+			Type:        msg.Type,
+			Headers:     msg.Headers,
+			Body:        msg.Body,
+		})
+		callback(msg)
+	}
+}
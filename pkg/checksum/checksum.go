@@ -0,0 +1,156 @@
+// Package checksum adds body checksums to published RabbitMQ messages and
+// verifies them on consumption, detecting corruption introduced by
+// intermediate tooling (shovels, bridges, manual re-publishing).
+package checksum
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/crc32"
+	"log"
+	"sync/atomic"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	CRC32C = "crc32c"
+	SHA256 = "sha256"
+
+	HEADER_ALGORITHM = "x-checksum-alg"
+	HEADER_CHECKSUM  = "x-checksum"
+)
+
+var (
+	ErrMismatch = cerrors.New(cerrors.Invalid, "checksum: body checksum mismatch")
+	ErrMissing  = cerrors.New(cerrors.Invalid, "checksum: message without checksum")
+
+	castagnoli = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// Stats counts the verifications, read them to export metrics
+type Stats struct {
+	Verified   atomic.Int64
+	Mismatches atomic.Int64
+	Missing    atomic.Int64
+}
+
+type Checksum struct {
+	Algorithm string // CRC32C when empty
+	// Required rejects messages without checksum headers, otherwise they pass unverified
+	Required bool
+	// OnMismatch is called for every message failing verification, ex: to log or alert
+	OnMismatch func(msg *amqp.Delivery, err error)
+	Stats      Stats
+}
+
+// Sum computes the hex encoded checksum of data
+func Sum(algorithm string, data []byte) (string, error) {
+	switch algorithm {
+	case CRC32C, "":
+		sum := crc32.Checksum(data, castagnoli)
+		return hex.EncodeToString([]byte{byte(sum >> 24), byte(sum >> 16), byte(sum >> 8), byte(sum)}), nil
+	case SHA256:
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:]), nil
+	default:
+		return "", cerrors.New(cerrors.Invalid, "checksum: unknown algorithm "+algorithm)
+	}
+}
+
+// Sign returns a copy of msg with the checksum headers set
+func (c *Checksum) Sign(msg *rabbitmq.Message) (*rabbitmq.Message, error) {
+	algorithm := c.Algorithm
+	if algorithm == "" {
+		algorithm = CRC32C
+	}
+
+	sum, err := Sum(algorithm, msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := *msg
+	signed.Headers = amqp.Table{}
+	for k, v := range msg.Headers {
+		signed.Headers[k] = v
+	}
+	signed.Headers[HEADER_ALGORITHM] = algorithm
+	signed.Headers[HEADER_CHECKSUM] = sum
+
+	return &signed, nil
+}
+
+// Verify checks the body of msg against its checksum headers
+func (c *Checksum) Verify(msg *amqp.Delivery) error {
+	expected, _ := msg.Headers[HEADER_CHECKSUM].(string)
+	algorithm, _ := msg.Headers[HEADER_ALGORITHM].(string)
+
+	if expected == "" {
+		c.Stats.Missing.Add(1)
+		if c.Required {
+			return ErrMissing
+		}
+		return nil
+	}
+
+	sum, err := Sum(algorithm, msg.Body)
+	if err != nil {
+		c.Stats.Mismatches.Add(1)
+		return err
+	}
+
+	if sum != expected {
+		c.Stats.Mismatches.Add(1)
+		return ErrMismatch
+	}
+
+	c.Stats.Verified.Add(1)
+	return nil
+}
+
+// Middleware wraps a consumer callback verifying the checksum first.
+// Failing messages are rejected without requeue so they reach the dead letter exchange.
+func (c *Checksum) Middleware(callback func(msg *amqp.Delivery)) func(msg *amqp.Delivery) {
+	return func(msg *amqp.Delivery) {
+		if err := c.Verify(msg); err != nil {
+			log.Println("Rejecting message", msg.MessageId, "on", msg.RoutingKey+":", err.Error())
+			if c.OnMismatch != nil {
+				c.OnMismatch(msg, err)
+			}
+			msg.Nack(false, false)
+			return
+		}
+		callback(msg)
+	}
+}
+
+type checksumRabbit struct {
+	rabbitmq.RabbitInterface
+	c *Checksum
+}
+
+// NewRabbitMQ wraps a RabbitInterface signing every published message and
+// verifying every consumed one
+func NewRabbitMQ(rbm rabbitmq.RabbitInterface, c *Checksum) rabbitmq.RabbitInterface {
+	return &checksumRabbit{RabbitInterface: rbm, c: c}
+}
+
+func (cr *checksumRabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	signed, err := cr.c.Sign(msg)
+	if err != nil {
+		return err
+	}
+	return cr.RabbitInterface.Producer(ctx, pc, signed)
+}
+
+func (cr *checksumRabbit) Consumer(cc *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.Consumer(cc, cr.c.Middleware(callback))
+}
+
+func (cr *checksumRabbit) StartConsumer(cc *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.StartConsumer(cc, cr.c.Middleware(callback))
+}
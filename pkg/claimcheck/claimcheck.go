@@ -0,0 +1,196 @@
+// Package claimcheck implements the claim-check pattern for RabbitMQ: bodies
+// bigger than a threshold are uploaded to a Store and only a reference travels
+// through the broker. Consumers transparently download the payload back.
+//
+// Stored payloads are not deleted on consumption since a message can be
+// routed to several queues, expire them with the retention of the Store.
+package claimcheck
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DEFAULT_THRESHOLD = 256 << 10 // 256KB
+
+	CONTENT_TYPE                 = "application/vnd.claim-check+json"
+	HEADER_CLAIM_CHECK           = "x-claim-check"
+	HEADER_ORIGINAL_CONTENT_TYPE = "x-original-content-type"
+)
+
+// Store holds the offloaded payloads, usually a blob container.
+// Get must return an error of kind cerrors.NotFound for missing names.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+type Config struct {
+	Threshold int    // bodies bigger than Threshold bytes are offloaded
+	Prefix    string // name prefix inside the Store, ex: "claim-check/orders"
+}
+
+// Reference is the body published in place of an offloaded payload
+type Reference struct {
+	Name        string `json:"name"`
+	Size        int    `json:"size"`
+	ContentType string `json:"content_type,omitempty"`
+	SHA256      string `json:"sha256,omitempty"` // hex, checked by Resolve when present
+}
+
+type ClaimCheck struct {
+	store Store
+	conf  Config
+}
+
+func New(store Store, conf Config) *ClaimCheck {
+	if conf.Threshold <= 0 {
+		conf.Threshold = DEFAULT_THRESHOLD
+	}
+	return &ClaimCheck{store: store, conf: conf}
+}
+
+// Offload returns msg unchanged when it is small enough, otherwise it uploads the body
+// and returns a copy of msg carrying a Reference
+func (cc *ClaimCheck) Offload(ctx context.Context, msg *rabbitmq.Message) (*rabbitmq.Message, error) {
+	if len(msg.Data) <= cc.conf.Threshold {
+		return msg, nil
+	}
+
+	name := time.Now().UTC().Format("2006/01/02/") + envelope.NewID()
+	if cc.conf.Prefix != "" {
+		name = cc.conf.Prefix + "/" + name
+	}
+
+	uctx, cancel := deadline.Apply(ctx, deadline.Upload)
+	defer cancel()
+
+	if err := cc.store.Put(uctx, name, msg.Data, msg.ContentType); err != nil {
+		log.Println("Erro to upload claim-check payload")
+		return nil, err
+	}
+
+	sum := sha256.Sum256(msg.Data)
+	ref, err := json.Marshal(&Reference{Name: name, Size: len(msg.Data), ContentType: msg.ContentType, SHA256: hex.EncodeToString(sum[:])})
+	if err != nil {
+		return nil, err
+	}
+
+	offloaded := *msg
+	offloaded.Data = ref
+	offloaded.ContentType = CONTENT_TYPE
+	offloaded.Headers = amqp.Table{}
+	for k, v := range msg.Headers {
+		offloaded.Headers[k] = v
+	}
+	offloaded.Headers[HEADER_CLAIM_CHECK] = name
+	offloaded.Headers[HEADER_ORIGINAL_CONTENT_TYPE] = msg.ContentType
+
+	return &offloaded, nil
+}
+
+// Resolve downloads the payload of a claim-check message into its Body and restores
+// the original ContentType. Other messages are left untouched. References outside the
+// Prefix or whose payload doesn't match their size and checksum return an error of kind
+// Invalid, a producer can't make consumers read other names of the Store.
+func (cc *ClaimCheck) Resolve(ctx context.Context, msg *amqp.Delivery) error {
+	if _, ok := msg.Headers[HEADER_CLAIM_CHECK]; !ok {
+		return nil
+	}
+
+	ref := &Reference{}
+	if err := json.Unmarshal(msg.Body, ref); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "claimcheck.Resolve", err)
+	}
+	if !cc.owns(ref.Name) {
+		return cerrors.New(cerrors.Invalid, "claimcheck: reference outside the prefix "+ref.Name)
+	}
+
+	dctx, cancel := deadline.Apply(ctx, deadline.Download)
+	defer cancel()
+
+	data, err := cc.store.Get(dctx, ref.Name)
+	if err != nil {
+		log.Println("Erro to download claim-check payload", ref.Name)
+		return err
+	}
+
+	if len(data) != ref.Size {
+		return cerrors.New(cerrors.Invalid, "claimcheck: size mismatch of "+ref.Name)
+	}
+	if ref.SHA256 != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), ref.SHA256) {
+			return cerrors.New(cerrors.Invalid, "claimcheck: checksum mismatch of "+ref.Name)
+		}
+	}
+
+	msg.Body = data
+	msg.ContentType = ref.ContentType
+	delete(msg.Headers, HEADER_CLAIM_CHECK)
+	delete(msg.Headers, HEADER_ORIGINAL_CONTENT_TYPE)
+
+	return nil
+}
+
+// owns reports whether name is a clean name under the Prefix, as written by Offload
+func (cc *ClaimCheck) owns(name string) bool {
+	if name == "" || path.Clean(name) != name || strings.HasPrefix(name, "/") || strings.HasPrefix(name, "..") {
+		return false
+	}
+	return cc.conf.Prefix == "" || strings.HasPrefix(name, cc.conf.Prefix+"/")
+}
+
+// Middleware wraps a consumer callback resolving claim-checks before calling it.
+// Messages whose payload can't be downloaded are nacked with requeue when the
+// failure is transient and rejected otherwise.
+func (cc *ClaimCheck) Middleware(callback func(msg *amqp.Delivery)) func(msg *amqp.Delivery) {
+	return func(msg *amqp.Delivery) {
+		if err := cc.Resolve(context.Background(), msg); err != nil {
+			log.Println("Erro to resolve claim-check:", err.Error())
+			msg.Nack(false, cerrors.Is(err, cerrors.Unavailable))
+			return
+		}
+		callback(msg)
+	}
+}
+
+type claimCheckRabbit struct {
+	rabbitmq.RabbitInterface
+	cc *ClaimCheck
+}
+
+// NewRabbitMQ wraps a RabbitInterface so Producer offloads large bodies and
+// Consumer/StartConsumer resolve them before calling the callback
+func NewRabbitMQ(rbm rabbitmq.RabbitInterface, cc *ClaimCheck) rabbitmq.RabbitInterface {
+	return &claimCheckRabbit{RabbitInterface: rbm, cc: cc}
+}
+
+func (cr *claimCheckRabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	offloaded, err := cr.cc.Offload(ctx, msg)
+	if err != nil {
+		return err
+	}
+	return cr.RabbitInterface.Producer(ctx, pc, offloaded)
+}
+
+func (cr *claimCheckRabbit) Consumer(conf *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.Consumer(conf, cr.cc.Middleware(callback))
+}
+
+func (cr *claimCheckRabbit) StartConsumer(conf *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	cr.RabbitInterface.StartConsumer(conf, cr.cc.Middleware(callback))
+}
@@ -0,0 +1,306 @@
+// Package contracts holds the example events of each routing key so producers and
+// consumers can verify, in their own test suites, that they still agree:
+//
+//	reg, _ := contracts.LoadDir("contracts")
+//
+//	// producer test
+//	contractstest.MustVerifyProduced(t, reg, "order.created", env)
+//
+//	// consumer test
+//	contractstest.MustVerifyConsumer[OrderCreated](t, reg, "order.created")
+//
+// A contract file is a JSON Contract, ex: contracts/order.created.json.
+package contracts
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/schemaregistry"
+)
+
+// Contract describes the events published with a routing key
+type Contract struct {
+	RoutingKey    string            `json:"routing_key"`
+	EventType     string            `json:"event_type"`
+	SchemaVersion string            `json:"schema_version,omitempty"`
+	Examples      []json.RawMessage `json:"examples"`
+	// Schema is an optional JSON Schema of the payload, checked with schemaregistry.JSONValidator.
+	// Without it payloads must have every field of the first example, with the same JSON types.
+	Schema json.RawMessage `json:"schema,omitempty"`
+}
+
+type Registry struct {
+	mu        sync.RWMutex
+	contracts map[string]*Contract
+}
+
+func NewRegistry() *Registry {
+	return &Registry{contracts: map[string]*Contract{}}
+}
+
+// Register adds c, replacing the contract of the same routing key
+func (r *Registry) Register(c Contract) error {
+	if c.RoutingKey == "" || c.EventType == "" || len(c.Examples) == 0 {
+		return cerrors.New(cerrors.Invalid, "contracts: routing key, event type and one example are required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.contracts[c.RoutingKey] = &c
+	return nil
+}
+
+// Get returns the contract of a routing key or an error of kind NotFound
+func (r *Registry) Get(routingKey string) (*Contract, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.contracts[routingKey]
+	if !ok {
+		return nil, cerrors.New(cerrors.NotFound, "contracts: no contract for "+routingKey)
+	}
+	return c, nil
+}
+
+// RoutingKeys returns the registered routing keys, sorted
+func (r *Registry) RoutingKeys() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.contracts))
+	for k := range r.contracts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadDir registers every *.json contract file of dir
+func LoadDir(dir string) (*Registry, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "contracts.LoadDir", err)
+	}
+
+	r := NewRegistry()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "contracts.LoadDir", err)
+		}
+
+		var c Contract
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "contracts.LoadDir: "+filepath.Base(file), err)
+		}
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// VerifyProduced checks an Envelope published with routingKey against its contract
+func VerifyProduced(r *Registry, routingKey string, env *envelope.Envelope) error {
+	c, err := r.Get(routingKey)
+	if err != nil {
+		return err
+	}
+
+	if env.Type != c.EventType {
+		return cerrors.New(cerrors.Invalid, fmt.Sprintf("contracts: %s: event type %q, expected %q", routingKey, env.Type, c.EventType))
+	}
+	if c.SchemaVersion != "" && env.SchemaVersion != c.SchemaVersion {
+		return cerrors.New(cerrors.Invalid, fmt.Sprintf("contracts: %s: schema version %q, expected %q", routingKey, env.SchemaVersion, c.SchemaVersion))
+	}
+
+	if len(c.Schema) > 0 {
+		return schemaregistry.JSONValidator{}.Validate(&schemaregistry.Schema{
+			Schema: string(c.Schema), SchemaType: schemaregistry.SCHEMA_TYPE_JSON,
+		}, env.Payload)
+	}
+
+	var example, payload interface{}
+	if err := json.Unmarshal(c.Examples[0], &example); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "contracts: bad example of "+routingKey, err)
+	}
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "contracts: "+routingKey, err)
+	}
+	if err := conforms("$", example, payload); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "contracts: "+routingKey, err)
+	}
+	return nil
+}
+
+// VerifyConsumer decodes every example of routingKey into T and checks that the examples
+// have every field T requires: the fields without omitempty that aren't pointers. Fields
+// of the examples that T doesn't know are ignored, as the consumer does.
+func VerifyConsumer[T any](r *Registry, routingKey string) error {
+	c, err := r.Get(routingKey)
+	if err != nil {
+		return err
+	}
+
+	for i, example := range c.Examples {
+		var payload T
+		if err := json.Unmarshal(example, &payload); err != nil {
+			return cerrors.Wrap(cerrors.Invalid, fmt.Sprintf("contracts: %s example %d", routingKey, i), err)
+		}
+
+		var doc interface{}
+		if err := json.Unmarshal(example, &doc); err != nil {
+			return cerrors.Wrap(cerrors.Invalid, fmt.Sprintf("contracts: %s example %d", routingKey, i), err)
+		}
+		if err := required("$", reflect.TypeOf(payload), doc); err != nil {
+			return cerrors.Wrap(cerrors.Invalid, fmt.Sprintf("contracts: %s example %d", routingKey, i), err)
+		}
+	}
+	return nil
+}
+
+// required checks that value, decoded from JSON, has the required fields of t
+func required(path string, t reflect.Type, value interface{}) error {
+	if t == nil || value == nil {
+		return nil
+	}
+	t = indirect(t)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil // decoding into T already checked the type
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if !f.IsExported() || tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+			if f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct {
+				// embedded struct fields are promoted
+				if err := required(path, f.Type, value); err != nil {
+					return err
+				}
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			field, ok := lookup(obj, name)
+			if !ok {
+				if strings.Contains(opts, "omitempty") || f.Type.Kind() == reflect.Pointer {
+					continue
+				}
+				return fmt.Errorf("%s.%s: missing", path, name)
+			}
+			if err := required(path+"."+name, f.Type, field); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		arr, _ := value.([]interface{})
+		for i, item := range arr {
+			if err := required(fmt.Sprintf("%s[%d]", path, i), t.Elem(), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// lookup finds a field as encoding/json does, preferring an exact match
+func lookup(obj map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := obj[name]; ok {
+		return v, true
+	}
+	for k, v := range obj {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// conforms checks that payload has every field of example with the same JSON type.
+// Extra fields are allowed, consumers must ignore them.
+func conforms(path string, example, payload interface{}) error {
+	if example == nil {
+		return nil
+	}
+
+	switch ex := example.(type) {
+	case map[string]interface{}:
+		obj, ok := payload.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object", path)
+		}
+		keys := make([]string, 0, len(ex))
+		for k := range ex {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			v, ok := obj[k]
+			if !ok {
+				return fmt.Errorf("%s.%s: missing", path, k)
+			}
+			if err := conforms(path+"."+k, ex[k], v); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		arr, ok := payload.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array", path)
+		}
+		if len(ex) > 0 {
+			for i, item := range arr {
+				if err := conforms(fmt.Sprintf("%s[%d]", path, i), ex[0], item); err != nil {
+					return err
+				}
+			}
+		}
+	default:
+		// null is accepted for any scalar, fields can be optional
+		if payload != nil && typeName(example) != typeName(payload) {
+			return fmt.Errorf("%s: expected %s, got %s", path, typeName(example), typeName(payload))
+		}
+	}
+	return nil
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
@@ -0,0 +1,26 @@
+// Package contractstest wraps the contracts checks for test suites, so the testing
+// package isn't linked into the services importing contracts
+package contractstest
+
+import (
+	"testing"
+
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	"github.com/faelp22/go-commons-libs/pkg/contracts"
+)
+
+// MustVerifyProduced fails tb when contracts.VerifyProduced fails
+func MustVerifyProduced(tb testing.TB, r *contracts.Registry, routingKey string, env *envelope.Envelope) {
+	tb.Helper()
+	if err := contracts.VerifyProduced(r, routingKey, env); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// MustVerifyConsumer fails tb when contracts.VerifyConsumer fails
+func MustVerifyConsumer[T any](tb testing.TB, r *contracts.Registry, routingKey string) {
+	tb.Helper()
+	if err := contracts.VerifyConsumer[T](r, routingKey); err != nil {
+		tb.Fatal(err)
+	}
+}
@@ -0,0 +1,150 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/pkg/messaging"
+)
+
+const DEFAULT_LOCK_TTL = 5 * time.Minute
+
+// Job is an event published on a Schedule
+type Job struct {
+	Name      string // unique, part of the lock key
+	Spec      string // cron expression, see Parse
+	Topic     string
+	EventType string // ex: "nightly.reconciliation.start"
+	// Payload builds the payload for the activation time, the Tick is published when nil
+	Payload func(at time.Time) (interface{}, error)
+}
+
+// Tick is the default payload of a Job
+type Tick struct {
+	Job         string    `json:"job"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+type Config struct {
+	Source   string         // Envelope source, ex: the service name
+	Location *time.Location // schedules are evaluated in Location, UTC when nil
+	Locker   Locker         // optional, without it every instance emits every tick
+	LockTTL  time.Duration  // DEFAULT_LOCK_TTL when <= 0, must be longer than the clock skew between instances
+}
+
+type job struct {
+	Job
+	schedule *Schedule
+}
+
+type Emitter struct {
+	pub   messaging.Publisher
+	conf  Config
+	mu    sync.Mutex
+	jobs  []job
+	clock clock.Clock
+}
+
+func NewEmitter(pub messaging.Publisher, conf Config) *Emitter {
+	if conf.Location == nil {
+		conf.Location = time.UTC
+	}
+	if conf.LockTTL <= 0 {
+		conf.LockTTL = DEFAULT_LOCK_TTL
+	}
+	return &Emitter{pub: pub, conf: conf, clock: clock.New()}
+}
+
+func (e *Emitter) SetClock(c clock.Clock) {
+	e.clock = c
+}
+
+// Add registers j, returning an error of kind Invalid for a bad Spec
+func (e *Emitter) Add(j Job) error {
+	if j.Name == "" || j.Topic == "" || j.EventType == "" {
+		return cerrors.New(cerrors.Invalid, "cron: job name, topic and event type are required")
+	}
+
+	schedule, err := Parse(j.Spec)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.jobs = append(e.jobs, job{Job: j, schedule: schedule})
+	return nil
+}
+
+// Start runs every job until ctx is done. Activations missed while the process was
+// down are not emitted.
+func (e *Emitter) Start(ctx context.Context) {
+	e.mu.Lock()
+	jobs := append([]job(nil), e.jobs...)
+	e.mu.Unlock()
+
+	for _, j := range jobs {
+		j := j
+		async.Go(func() error {
+			e.run(ctx, j)
+			return nil
+		})
+	}
+}
+
+func (e *Emitter) run(ctx context.Context, j job) {
+	for {
+		now := e.clock.Now().In(e.conf.Location)
+		next := j.schedule.Next(now)
+		if next.IsZero() {
+			log.Printf("Cron job %s has no next activation", j.Name)
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-e.clock.After(next.Sub(now)):
+		}
+
+		if err := e.Emit(ctx, j.Job, next); err != nil {
+			log.Printf("Erro to emit cron job %s", j.Name)
+			log.Println(err)
+		}
+	}
+}
+
+// Emit publishes the event of j for the activation at, once across the instances
+// sharing the Locker
+func (e *Emitter) Emit(ctx context.Context, j Job, at time.Time) (err error) {
+	end := hooks.Begin(ctx, "cron", "Emit", map[string]interface{}{
+		"job": j.Name, "at": at,
+	})
+	defer func() { end(err) }()
+
+	if e.conf.Locker != nil {
+		key := fmt.Sprintf("cron:%s:%d", j.Name, at.Unix())
+		ok, err := e.conf.Locker.TryLock(ctx, key, e.conf.LockTTL)
+		if err != nil || !ok {
+			// another instance emitted it
+			return err
+		}
+	}
+
+	var payload interface{} = Tick{Job: j.Name, ScheduledAt: at}
+	if j.Payload != nil {
+		if payload, err = j.Payload(at); err != nil {
+			return err
+		}
+	}
+
+	_, err = messaging.PublishEvent(ctx, e.pub, j.Topic, j.EventType, e.conf.Source, payload)
+	return err
+}
@@ -0,0 +1,66 @@
+package cron
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+// Locker acquires keys shared by every instance. TryLock returns false when another
+// instance holds key, the lock is released when ttl expires.
+type Locker interface {
+	TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+type redisLocker struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedisLocker returns a Locker backed by SET NX on Redis
+func NewRedisLocker(rdb redis.UniversalClient) Locker {
+	return &redisLocker{rdb: rdb}
+}
+
+func (rl *redisLocker) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	ok, err := rl.rdb.SetNX(ctx, key, "1", ttl).Result()
+	if err != nil {
+		return false, cerrors.Wrap(cerrors.Unavailable, "cron.TryLock", err)
+	}
+	return ok, nil
+}
+
+type memoryLocker struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+	clock clock.Clock
+}
+
+// NewMemoryLocker returns a Locker local to the process, for single instance services and tests
+func NewMemoryLocker(c clock.Clock) Locker {
+	if c == nil {
+		c = clock.New()
+	}
+	return &memoryLocker{until: map[string]time.Time{}, clock: c}
+}
+
+func (ml *memoryLocker) TryLock(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := ml.clock.Now()
+	for k, until := range ml.until {
+		if !now.Before(until) {
+			delete(ml.until, k)
+		}
+	}
+
+	if _, held := ml.until[key]; held {
+		return false, nil
+	}
+	ml.until[key] = now.Add(ttl)
+	return true, nil
+}
@@ -0,0 +1,198 @@
+package dbx
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Named converts :name parameters to $n placeholders, taking their values from a
+// map[string]interface{} or a struct (see the field mapping of Select). Slices are
+// expanded like In. Casts such as "::text" and quoted text are left untouched.
+func Named(query string, arg interface{}) (string, []interface{}, error) {
+	lookup, err := lookupOf(arg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		b    strings.Builder
+		args []interface{}
+	)
+	err = scan(query, func(part string, placeholder bool) error {
+		if !placeholder || !strings.HasPrefix(part, ":") {
+			b.WriteString(part)
+			return nil
+		}
+
+		value, ok := lookup(part[1:])
+		if !ok {
+			return cerrors.New(cerrors.Invalid, "dbx: no value for parameter "+part)
+		}
+		args = bind(&b, args, value)
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return b.String(), args, nil
+}
+
+// In renumbers the $n placeholders of query expanding every slice argument into a list,
+// ex: In("id IN ($1)", []int{1, 2}) returns "id IN ($1, $2)". []byte and driver.Valuer
+// arguments such as pq.Array are kept as a single value.
+func In(query string, args ...interface{}) (string, []interface{}, error) {
+	expand := false
+	for _, a := range args {
+		if isList(a) {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return query, args, nil
+	}
+
+	var (
+		b   strings.Builder
+		out []interface{}
+	)
+	err := scan(query, func(part string, placeholder bool) error {
+		if !placeholder || !strings.HasPrefix(part, "$") {
+			b.WriteString(part)
+			return nil
+		}
+
+		n, err := strconv.Atoi(part[1:])
+		if err != nil || n < 1 || n > len(args) {
+			return cerrors.New(cerrors.Invalid, "dbx: no argument for "+part)
+		}
+		out = bind(&b, out, args[n-1])
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return b.String(), out, nil
+}
+
+// bind writes the placeholders of value, one per element of a list
+func bind(b *strings.Builder, args []interface{}, value interface{}) []interface{} {
+	if !isList(value) {
+		args = append(args, value)
+		b.WriteString("$" + strconv.Itoa(len(args)))
+		return args
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Len() == 0 {
+		// an empty list matches nothing: "id IN (NULL)"
+		b.WriteString("NULL")
+		return args
+	}
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		args = append(args, v.Index(i).Interface())
+		b.WriteString("$" + strconv.Itoa(len(args)))
+	}
+	return args
+}
+
+func isList(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if _, ok := value.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(value)
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+// scan splits query into text and placeholders ($1, :name), skipping quoted text,
+// quoted identifiers, comments and "::" casts
+func scan(query string, fn func(part string, placeholder bool) error) error {
+	start := 0
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(query[i+1:], c)
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 1
+			}
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end
+			}
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			i++
+		case c == ':' || c == '$':
+			j := i + 1
+			for j < len(query) && isIdent(query[j], c == '$') {
+				j++
+			}
+			if j == i+1 {
+				continue
+			}
+			if err := fn(query[start:i], false); err != nil {
+				return err
+			}
+			if err := fn(query[i:j], true); err != nil {
+				return err
+			}
+			start = j
+			i = j - 1
+		}
+	}
+	return fn(query[start:], false)
+}
+
+func isIdent(c byte, digitsOnly bool) bool {
+	if c >= '0' && c <= '9' {
+		return true
+	}
+	return !digitsOnly && (c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'))
+}
+
+// lookupOf returns the parameter values of a map or struct
+func lookupOf(arg interface{}) (func(name string) (interface{}, bool), error) {
+	if m, ok := arg.(map[string]interface{}); ok {
+		return func(name string) (interface{}, bool) {
+			v, ok := m[name]
+			return v, ok
+		}, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, cerrors.New(cerrors.Invalid, "dbx: named parameters need a map or a struct")
+	}
+
+	fields := fieldsOf(v.Type())
+	return func(name string) (interface{}, bool) {
+		index, ok := fields[strings.ToLower(name)]
+		if !ok {
+			return nil, false
+		}
+		f, err := v.FieldByIndexErr(index)
+		if err != nil {
+			// a field of a nil embedded pointer
+			return nil, true
+		}
+		return f.Interface(), true
+	}, nil
+}
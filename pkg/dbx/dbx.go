@@ -0,0 +1,164 @@
+// Package dbx is a thin layer over database/sql scanning rows into typed values,
+// binding named parameters and expanding slices into IN lists:
+//
+//	users, err := dbx.Select[User](ctx, db, `SELECT id, name FROM users WHERE id = ANY($1) OR id IN ($2)`, pq.Array(ids), ids)
+//	user, err := dbx.Get[User](ctx, db, `SELECT id, name FROM users WHERE id = $1`, id)
+//	n, err := dbx.NamedExec(ctx, db, `UPDATE users SET name = :name WHERE id = :id`, user)
+//
+// Struct fields are matched to columns by their db tag, or by their name in snake_case.
+// Every query is reported to hooks with the component "dbx".
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+)
+
+// Querier is implemented by *sql.DB, *sql.Tx and *sql.Conn
+type Querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Select returns every row of query scanned into T, a struct or a single column type
+func Select[T any](ctx context.Context, q Querier, query string, args ...interface{}) (items []T, err error) {
+	query, args, err = In(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := map[string]interface{}{"query": summary(query)}
+	end := hooks.Begin(ctx, "dbx", "Select", attrs)
+	defer func() { end(err) }()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items, err = scanAll[T](rows)
+	attrs["rows"] = len(items)
+	return items, err
+}
+
+// Get returns the first row of query scanned into T, or an error of kind NotFound
+func Get[T any](ctx context.Context, q Querier, query string, args ...interface{}) (item T, err error) {
+	query, args, err = In(query, args...)
+	if err != nil {
+		return item, err
+	}
+
+	end := hooks.Begin(ctx, "dbx", "Get", map[string]interface{}{"query": summary(query)})
+	defer func() {
+		if cerrors.Is(err, cerrors.NotFound) {
+			end(nil)
+			return
+		}
+		end(err)
+	}()
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return item, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return item, err
+		}
+		return item, cerrors.Wrap(cerrors.NotFound, "dbx.Get", sql.ErrNoRows)
+	}
+	if err := scanRow(rows, &item); err != nil {
+		return item, err
+	}
+	return item, rows.Close()
+}
+
+// Exec runs query and returns the number of affected rows
+func Exec(ctx context.Context, q Querier, query string, args ...interface{}) (affected int64, err error) {
+	query, args, err = In(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	attrs := map[string]interface{}{"query": summary(query)}
+	end := hooks.Begin(ctx, "dbx", "Exec", attrs)
+	defer func() { end(err) }()
+
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	affected, err = result.RowsAffected()
+	attrs["rows"] = affected
+	return affected, err
+}
+
+// NamedSelect is Select with :name parameters taken from arg, see Named
+func NamedSelect[T any](ctx context.Context, q Querier, query string, arg interface{}) ([]T, error) {
+	query, args, err := Named(query, arg)
+	if err != nil {
+		return nil, err
+	}
+	return Select[T](ctx, q, query, args...)
+}
+
+// NamedGet is Get with :name parameters taken from arg, see Named
+func NamedGet[T any](ctx context.Context, q Querier, query string, arg interface{}) (T, error) {
+	query, args, err := Named(query, arg)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return Get[T](ctx, q, query, args...)
+}
+
+// NamedExec is Exec with :name parameters taken from arg, see Named
+func NamedExec(ctx context.Context, q Querier, query string, arg interface{}) (int64, error) {
+	query, args, err := Named(query, arg)
+	if err != nil {
+		return 0, err
+	}
+	return Exec(ctx, q, query, args...)
+}
+
+// Tx runs fn in a transaction committed when fn returns nil and rolled back otherwise
+func Tx(ctx context.Context, db *sql.DB, opts *sql.TxOptions, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, rbErr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// summary returns the first line of query, trimmed for the hooks attrs
+func summary(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.IndexByte(query, '\n'); i >= 0 {
+		query = query[:i]
+	}
+	if len(query) > 120 {
+		query = query[:120]
+	}
+	return query
+}
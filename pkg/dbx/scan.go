@@ -0,0 +1,133 @@
+package dbx
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+var (
+	fieldCache  sync.Map // reflect.Type -> map[string][]int
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	timeType    = reflect.TypeOf(time.Time{})
+)
+
+func scanAll[T any](rows *sql.Rows) ([]T, error) {
+	var items []T
+	for rows.Next() {
+		var item T
+		if err := scanRow(rows, &item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// scanRow scans the current row into dest, a pointer to a struct or to a single column type
+func scanRow(rows *sql.Rows, dest interface{}) error {
+	v := reflect.ValueOf(dest).Elem()
+	if !isStruct(v.Type()) {
+		return rows.Scan(dest)
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	fields := fieldsOf(v.Type())
+	targets := make([]interface{}, len(columns))
+	for i, column := range columns {
+		index, ok := fields[strings.ToLower(column)]
+		if !ok {
+			return cerrors.New(cerrors.Invalid, "dbx: no field of "+v.Type().String()+" for column "+column)
+		}
+		targets[i] = fieldByIndexAlloc(v, index).Addr().Interface()
+	}
+	return rows.Scan(targets...)
+}
+
+// isStruct reports whether t is scanned field by field, structs implementing
+// sql.Scanner and time.Time are scanned as a single column
+func isStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(scannerType)
+}
+
+// fieldsOf maps the lower cased column names of the fields of t to their index,
+// promoting the fields of embedded structs
+func fieldsOf(t reflect.Type) map[string][]int {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("db")
+			if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+				continue
+			}
+
+			path := append(append([]int{}, index...), i)
+			ft := f.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if f.Anonymous && tag == "" && isStruct(ft) {
+				walk(ft, path)
+				continue
+			}
+
+			name := tag
+			if name == "" {
+				name = snakeCase(f.Name)
+			}
+			// fields of the outer struct win over promoted ones
+			if _, ok := fields[strings.ToLower(name)]; !ok || len(path) == 1 {
+				fields[strings.ToLower(name)] = path
+			}
+		}
+	}
+	walk(t, nil)
+
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// fieldByIndexAlloc is FieldByIndex allocating nil embedded pointers on the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// snakeCase converts a Go field name, ex: "CreatedAt" -> "created_at", "UserID" -> "user_id"
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
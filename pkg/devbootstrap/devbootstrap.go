@@ -0,0 +1,77 @@
+// Package devbootstrap creates, idempotently, the RabbitMQ topology and the blob
+// containers a service needs against the local RabbitMQ and Azurite, when APP_ENV=dev,
+// so a fresh checkout works without manual setup:
+//
+//	t, _ := rabbitmq.LoadTopology("topology.json")
+//	err := devbootstrap.Run(ctx, devbootstrap.Config{RabbitMQ: rbm, Topology: t, Containers: []string{"uploads"}})
+//
+// with a topology.json such as (the integer arguments are declared as int64):
+//
+//	{
+//	  "exchanges": [{"name": "orders", "kind": "topic", "durable": true}],
+//	  "queues": [{
+//	    "name": "orders.created",
+//	    "durable": true,
+//	    "arguments": {"x-message-ttl": 86400000, "x-max-length": 100000, "x-dead-letter-exchange": "orders.dlx"},
+//	    "binds": [{"exchange_name": "orders", "binding_key": "order.created"}]
+//	  }]
+//	}
+package devbootstrap
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+type Config struct {
+	RabbitMQ rabbitmq.RabbitInterface // optional
+	Topology *rabbitmq.Topology
+	// Containers are created in the storage of SRV_AZURE_STORAGE_CONNECTION_STRING
+	Containers []string
+}
+
+// Enabled reports whether APP_ENV is dev or development
+func Enabled() bool {
+	env := strings.ToLower(os.Getenv("APP_ENV"))
+	return env == "dev" || env == "development"
+}
+
+// Run bootstraps the environment when Enabled, otherwise it does nothing
+func Run(ctx context.Context, conf Config) error {
+	if !Enabled() {
+		return nil
+	}
+
+	var errs []error
+
+	if conf.RabbitMQ != nil && conf.Topology != nil {
+		if err := rabbitmq.DeclareOnStart(conf.RabbitMQ, conf.Topology)(ctx); err != nil {
+			errs = append(errs, err)
+		} else {
+			log.Printf("Dev bootstrap: declared %d exchange(s) and %d queue(s) in RabbitMQ",
+				len(conf.Topology.Exchanges), len(conf.Topology.Queues))
+		}
+	}
+
+	if len(conf.Containers) > 0 {
+		account, err := ParseConnectionString(os.Getenv("SRV_AZURE_STORAGE_CONNECTION_STRING"))
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, name := range conf.Containers {
+				if err := account.CreateContainer(ctx, name); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				log.Printf("Dev bootstrap: container %s ready", name)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,141 @@
+package devbootstrap
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const storageVersion = "2021-08-06"
+
+// Account is a storage account reached with Shared Key authentication
+type Account struct {
+	Name     string
+	Key      []byte
+	Endpoint string // blob endpoint, ex: "http://127.0.0.1:10000/devstoreaccount1"
+	http     *http.Client
+}
+
+// ParseConnectionString parses an Azure storage connection string. Without BlobEndpoint
+// the endpoint is derived from DefaultEndpointsProtocol, AccountName and EndpointSuffix.
+func ParseConnectionString(cs string) (*Account, error) {
+	values := map[string]string{}
+	for _, part := range strings.Split(cs, ";") {
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+
+	if values["AccountName"] == "" || values["AccountKey"] == "" {
+		return nil, cerrors.New(cerrors.Invalid, "devbootstrap: SRV_AZURE_STORAGE_CONNECTION_STRING needs AccountName and AccountKey")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(values["AccountKey"])
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "devbootstrap: bad AccountKey", err)
+	}
+
+	endpoint := values["BlobEndpoint"]
+	if endpoint == "" {
+		protocol, suffix := values["DefaultEndpointsProtocol"], values["EndpointSuffix"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, values["AccountName"], suffix)
+	}
+
+	return &Account{
+		Name:     values["AccountName"],
+		Key:      key,
+		Endpoint: strings.TrimSuffix(endpoint, "/"),
+		http:     &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// CreateContainer creates a private container, an existing container is not an error
+func (a *Account) CreateContainer(ctx context.Context, name string) error {
+	u, err := url.Parse(a.Endpoint + "/" + name + "?restype=container")
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "devbootstrap.CreateContainer", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), nil)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "devbootstrap.CreateContainer", err)
+	}
+	req.Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	req.Header.Set("x-ms-version", storageVersion)
+	req.Header.Set("Authorization", "SharedKey "+a.Name+":"+a.sign(req))
+
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "devbootstrap.CreateContainer", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusCreated, resp.StatusCode == http.StatusConflict:
+		return nil
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return cerrors.New(cerrors.Unavailable, fmt.Sprintf("devbootstrap: creating container %s: %d %s", name, resp.StatusCode, body))
+	}
+}
+
+// sign computes the Shared Key signature of a request without body
+func (a *Account) sign(req *http.Request) string {
+	var headers []string
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-ms-") {
+			headers = append(headers, lk+":"+strings.TrimSpace(req.Header.Get(k)))
+		}
+	}
+	sort.Strings(headers)
+
+	// the resource starts with the account, followed by the path which for
+	// path-style endpoints (Azurite) repeats it
+	resource := "/" + a.Name + req.URL.EscapedPath()
+	query := req.URL.Query()
+	var params []string
+	for k, v := range query {
+		params = append(params, strings.ToLower(k)+":"+strings.Join(v, ","))
+	}
+	sort.Strings(params)
+	for _, p := range params {
+		resource += "\n" + p
+	}
+
+	toSign := strings.Join([]string{
+		req.Method,
+		"", // Content-Encoding
+		"", // Content-Language
+		"", // Content-Length, empty when 0
+		"", // Content-MD5
+		"", // Content-Type
+		"", // Date, x-ms-date is used
+		"", // If-Modified-Since
+		"", // If-Match
+		"", // If-None-Match
+		"", // If-Unmodified-Since
+		"", // Range
+		strings.Join(headers, "\n"),
+		resource,
+	}, "\n")
+
+	mac := hmac.New(sha256.New, a.Key)
+	mac.Write([]byte(toSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,139 @@
+// Package docs renders HTML templates to PDF and streams the result to a store,
+// returning a temporary link to it, for invoices and reports.
+//
+//	gen := docs.New(docs.NewWkhtmltopdf(""), uploader, signer, docs.Config{Prefix: "invoices"})
+//	doc, err := gen.Generate(ctx, "2023/inv-42.pdf", invoiceTmpl, invoice, docs.PageOptions{Size: "A4"})
+//	// doc.URL is valid for Config.LinkTTL
+package docs
+
+import (
+	"context"
+	"html/template"
+	"io"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+)
+
+const (
+	CONTENT_TYPE_PDF = "application/pdf"
+	DEFAULT_LINK_TTL = 15 * time.Minute
+)
+
+type PageOptions struct {
+	Size        string // ex: "A4", "Letter"
+	Landscape   bool
+	MarginMM    int
+	HeaderHTML  string // only supported by wkhtmltopdf
+	FooterHTML  string // only supported by wkhtmltopdf
+	Title       string
+	Grayscale   bool
+	PrintMedia  bool // use the print media type CSS
+	ExtraFlags  []string
+	JavaScript  bool // allow scripts in the page, disabled by default
+	WaitForJSMS int  // time given to scripts before printing
+}
+
+// Renderer converts the HTML read from html into a PDF written to pdf
+type Renderer interface {
+	Render(ctx context.Context, html io.Reader, pdf io.Writer, opts PageOptions) error
+}
+
+// Uploader is where the PDFs are written, usually a blob container.
+// It matches httpupload.Uploader.
+type Uploader interface {
+	Upload(ctx context.Context, name string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, name string) error
+}
+
+// URLSigner returns a temporary link to a stored file, ex: a SAS URL
+type URLSigner interface {
+	SignedURL(ctx context.Context, name string, ttl time.Duration) (string, error)
+}
+
+type Config struct {
+	Prefix  string
+	LinkTTL time.Duration
+}
+
+type Document struct {
+	Name string
+	Size int64
+	// URL is empty when the Generator has no URLSigner
+	URL string
+}
+
+type Generator struct {
+	renderer Renderer
+	up       Uploader
+	signer   URLSigner
+	conf     Config
+}
+
+// New returns a Generator, signer may be nil
+func New(renderer Renderer, up Uploader, signer URLSigner, conf Config) *Generator {
+	if conf.LinkTTL <= 0 {
+		conf.LinkTTL = DEFAULT_LINK_TTL
+	}
+	return &Generator{renderer: renderer, up: up, signer: signer, conf: conf}
+}
+
+// Generate executes tmpl with data, renders the HTML to PDF and uploads it as name.
+// Nothing is buffered: template, renderer and upload are connected by pipes.
+func (g *Generator) Generate(ctx context.Context, name string, tmpl *template.Template, data interface{}, opts PageOptions) (doc *Document, err error) {
+	if g.conf.Prefix != "" {
+		name = g.conf.Prefix + "/" + name
+	}
+
+	end := hooks.Begin(ctx, "docs", "Generate", map[string]interface{}{"name": name})
+	defer func() { end(err) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	htmlR, htmlW := io.Pipe()
+	go func() {
+		htmlW.CloseWithError(tmpl.Execute(htmlW, data))
+	}()
+
+	pdfR, pdfW := io.Pipe()
+	go func() {
+		err := g.renderer.Render(ctx, htmlR, pdfW, opts)
+		// unblock the template when the renderer stopped reading early
+		htmlR.CloseWithError(err)
+		pdfW.CloseWithError(err)
+	}()
+
+	counter := &countingReader{r: pdfR}
+	err = g.up.Upload(ctx, name, counter, CONTENT_TYPE_PDF)
+	pdfR.CloseWithError(err)
+	if err != nil {
+		// the failed upload may have left a partial object behind
+		g.up.Delete(ctx, name)
+		return nil, err
+	}
+	if counter.n == 0 {
+		g.up.Delete(ctx, name)
+		return nil, cerrors.New(cerrors.Invalid, "docs: renderer produced an empty PDF")
+	}
+
+	doc = &Document{Name: name, Size: counter.n}
+	if g.signer != nil {
+		if doc.URL, err = g.signer.SignedURL(ctx, name, g.conf.LinkTTL); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,176 @@
+package docs
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type wkhtmltopdf struct {
+	bin string
+}
+
+// NewWkhtmltopdf returns a Renderer running the wkhtmltopdf binary at bin, looked up
+// in PATH when empty. HTML and PDF are streamed through stdin and stdout.
+func NewWkhtmltopdf(bin string) Renderer {
+	if bin == "" {
+		bin = "wkhtmltopdf"
+	}
+	return &wkhtmltopdf{bin: bin}
+}
+
+func (wk *wkhtmltopdf) Render(ctx context.Context, html io.Reader, pdf io.Writer, opts PageOptions) error {
+	args := []string{"--quiet"}
+	if opts.Size != "" {
+		args = append(args, "--page-size", opts.Size)
+	}
+	if opts.Landscape {
+		args = append(args, "--orientation", "Landscape")
+	}
+	if opts.MarginMM > 0 {
+		m := strconv.Itoa(opts.MarginMM) + "mm"
+		args = append(args, "-T", m, "-B", m, "-L", m, "-R", m)
+	}
+	if opts.Title != "" {
+		args = append(args, "--title", opts.Title)
+	}
+	if opts.Grayscale {
+		args = append(args, "--grayscale")
+	}
+	if opts.PrintMedia {
+		args = append(args, "--print-media-type")
+	}
+	if opts.JavaScript {
+		args = append(args, "--enable-javascript")
+		if opts.WaitForJSMS > 0 {
+			args = append(args, "--javascript-delay", strconv.Itoa(opts.WaitForJSMS))
+		}
+	} else {
+		args = append(args, "--disable-javascript")
+	}
+
+	// header and footer must be files
+	if opts.HeaderHTML != "" || opts.FooterHTML != "" {
+		dir, err := os.MkdirTemp("", "docs-")
+		if err != nil {
+			return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+		}
+		defer os.RemoveAll(dir)
+
+		for _, part := range []struct{ flag, html string }{
+			{"--header-html", opts.HeaderHTML}, {"--footer-html", opts.FooterHTML},
+		} {
+			if part.html == "" {
+				continue
+			}
+			path := filepath.Join(dir, strings.TrimPrefix(part.flag, "--")+".html")
+			if err := os.WriteFile(path, []byte(part.html), 0o600); err != nil {
+				return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+			}
+			args = append(args, part.flag, path)
+		}
+	}
+
+	args = append(args, opts.ExtraFlags...)
+	args = append(args, "-", "-")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, wk.bin, args...)
+	cmd.Stdin = html
+	cmd.Stdout = pdf
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, fmt.Sprintf("docs: wkhtmltopdf: %s", strings.TrimSpace(stderr.String())), err)
+	}
+	return nil
+}
+
+type chromium struct {
+	bin string
+}
+
+// NewChromium returns a Renderer printing with headless Chromium at bin ("chromium" when
+// empty). Page size and margins come from the CSS @page rule of the HTML, Size, MarginMM,
+// HeaderHTML and FooterHTML are ignored. Containers usually need ExtraFlags "--no-sandbox".
+// The HTML is served from a loopback HTTP server, not a file:// URL, so the documents
+// can't read local files through <img> or <iframe src="file:///...">.
+func NewChromium(bin string) Renderer {
+	if bin == "" {
+		bin = "chromium"
+	}
+	return &chromium{bin: bin}
+}
+
+func (ch *chromium) Render(ctx context.Context, html io.Reader, pdf io.Writer, opts PageOptions) error {
+	dir, err := os.MkdirTemp("", "docs-")
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.html")
+	out := filepath.Join(dir, "out.pdf")
+
+	f, err := os.OpenFile(in, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+	}
+	_, err = io.Copy(f, html)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+	}
+
+	args := []string{"--headless", "--disable-gpu", "--no-pdf-header-footer", "--print-to-pdf=" + out}
+	if !opts.JavaScript {
+		args = append(args, "--blink-settings=scriptEnabled=false")
+	} else if opts.WaitForJSMS > 0 {
+		args = append(args, "--virtual-time-budget="+strconv.Itoa(opts.WaitForJSMS))
+	}
+	args = append(args, opts.ExtraFlags...)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "docs.Render", err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		http.ServeFile(w, r, in)
+	})}
+	go srv.Serve(ln)
+	defer srv.Close()
+	args = append(args, "http://"+ln.Addr().String()+"/")
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ch.bin, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, fmt.Sprintf("docs: chromium: %s", strings.TrimSpace(stderr.String())), err)
+	}
+
+	result, err := os.Open(out)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "docs: chromium produced no PDF", err)
+	}
+	defer result.Close()
+
+	_, err = io.Copy(pdf, result)
+	return err
+}
@@ -0,0 +1,166 @@
+// Package export streams records to gzip compressed NDJSON files on a Store, rotating
+// them by size or age and partitioning them by day, for inexpensive analytics exports:
+//
+//	w := export.NewNDJSON(store, export.Config{Prefix: "exports/orders"})
+//	w.Start(ctx)
+//	w.Write(ctx, order)
+//
+//	exports/orders/dt=2023-07-01/part-20230701T130501Z-<id>.ndjson.gz
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/bufpool"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/archiver"
+)
+
+const (
+	DEFAULT_MAX_BYTES = 64 << 20 // uncompressed
+	DEFAULT_MAX_AGE   = 5 * time.Minute
+	CONTENT_TYPE      = "application/x-ndjson+gzip"
+)
+
+// Store receives the rotated files, usually a blob container. Any archiver.Store is a Store.
+// Put must not retain data after returning, it comes from a pooled buffer.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+}
+
+type Config struct {
+	Prefix   string        // path prefix inside the Store
+	MaxBytes int64         // uncompressed bytes per file, DEFAULT_MAX_BYTES when <= 0
+	MaxAge   time.Duration // max time a record waits before its file is written, DEFAULT_MAX_AGE when <= 0
+	// Partition returns the partition of a record time, defaults to archiver.DayPrefix (dt=YYYY-MM-DD)
+	Partition func(prefix string, t time.Time) string
+}
+
+// NDJSON writes records as JSON lines. It is safe for concurrent use.
+type NDJSON struct {
+	store Store
+	conf  Config
+	clock clock.Clock
+
+	mu      sync.Mutex
+	buf     *bytes.Buffer
+	gz      *gzip.Writer
+	raw     int64
+	count   int
+	opened  time.Time
+	rotated chan struct{}
+}
+
+func NewNDJSON(store Store, conf Config) *NDJSON {
+	if conf.MaxBytes <= 0 {
+		conf.MaxBytes = DEFAULT_MAX_BYTES
+	}
+	if conf.MaxAge <= 0 {
+		conf.MaxAge = DEFAULT_MAX_AGE
+	}
+	if conf.Partition == nil {
+		conf.Partition = archiver.DayPrefix
+	}
+	return &NDJSON{store: store, conf: conf, clock: clock.New()}
+}
+
+func (w *NDJSON) SetClock(c clock.Clock) {
+	w.clock = c
+}
+
+// Write appends rec to the current file, writing it to the Store when it reaches MaxBytes
+func (w *NDJSON) Write(ctx context.Context, rec interface{}) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "export.Write", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.gz == nil {
+		w.buf = bufpool.Get()
+		w.gz = bufpool.GetGzipWriter(w.buf)
+		w.opened = w.clock.Now().UTC()
+	}
+
+	if _, err := w.gz.Write(append(line, '\n')); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "export.Write", err)
+	}
+	w.raw += int64(len(line) + 1)
+	w.count++
+
+	if w.raw >= w.conf.MaxBytes {
+		return w.rotate(ctx)
+	}
+	return nil
+}
+
+// Flush writes the current file to the Store, if it has records
+func (w *NDJSON) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate(ctx)
+}
+
+// Start flushes files older than MaxAge until ctx is done, then flushes the last file
+func (w *NDJSON) Start(ctx context.Context) {
+	async.Go(func() error {
+		ticker := w.clock.NewTicker(w.conf.MaxAge / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				if err := w.Flush(context.Background()); err != nil {
+					log.Println("Erro to flush export file:", err.Error())
+				}
+				return nil
+			case <-ticker.C():
+				w.mu.Lock()
+				var err error
+				if w.gz != nil && w.clock.Now().Sub(w.opened) >= w.conf.MaxAge {
+					err = w.rotate(ctx)
+				}
+				w.mu.Unlock()
+				if err != nil {
+					log.Println("Erro to flush export file:", err.Error())
+				}
+			}
+		}
+	})
+}
+
+// rotate must be called with mu held. On failure the records are dropped, keeping them
+// would grow the buffer without bound while the Store is down.
+func (w *NDJSON) rotate(ctx context.Context) error {
+	if w.gz == nil {
+		return nil
+	}
+
+	buf, gz, opened, count := w.buf, w.gz, w.opened, w.count
+	w.buf, w.gz, w.raw, w.count = nil, nil, 0, 0
+	defer bufpool.Put(buf)
+	defer bufpool.PutGzipWriter(gz)
+
+	if err := gz.Close(); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "export.rotate", err)
+	}
+
+	name := fmt.Sprintf("%s/part-%s-%s.ndjson.gz",
+		w.conf.Partition(w.conf.Prefix, opened), opened.Format("20060102T150405Z"), envelope.NewID()[:8])
+	if err := w.store.Put(ctx, name, buf.Bytes(), CONTENT_TYPE); err != nil {
+		return cerrors.Wrap(cerrors.KindOf(err), fmt.Sprintf("export.rotate: %d records lost", count), err)
+	}
+	return nil
+}
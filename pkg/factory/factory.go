@@ -0,0 +1,156 @@
+package factory
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/redisdb"
+)
+
+const (
+	MESSAGING_RABBITMQ = "rabbitmq"
+	CACHE_MEMORY       = "memory"
+	CACHE_REDIS        = "redis"
+)
+
+type MessagingBuilder func(ctx context.Context, conf *config.Config) (rabbitmq.RabbitInterface, error)
+type CacheBuilder func(ctx context.Context, conf *config.Config) (redisdb.RedisClientInterface, error)
+
+// Adapters holds the implementations chosen by the configuration, nil when not configured
+type Adapters struct {
+	Messaging rabbitmq.RabbitInterface
+	Cache     redisdb.RedisClientInterface
+}
+
+var (
+	registryLock sync.RWMutex
+	messaging    = map[string]MessagingBuilder{
+		MESSAGING_RABBITMQ: buildRabbitMQ,
+	}
+	caches = map[string]CacheBuilder{
+		CACHE_MEMORY: buildMemoryCache,
+		CACHE_REDIS:  buildRedisCache,
+	}
+)
+
+// RegisterMessaging adds or replaces a messaging implementation selectable by name
+func RegisterMessaging(name string, builder MessagingBuilder) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	messaging[name] = builder
+}
+
+// RegisterCache adds or replaces a cache implementation selectable by name
+func RegisterCache(name string, builder CacheBuilder) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	caches[name] = builder
+}
+
+// closer is implemented by the adapters holding connections, ex: rabbitmq.RabbitInterface
+type closer interface {
+	Close(ctx context.Context) error
+}
+
+// Close closes the adapters holding connections
+func (a *Adapters) Close(ctx context.Context) error {
+	var errs []error
+	for _, adapter := range []interface{}{a.Cache, a.Messaging} {
+		if c, ok := adapter.(closer); ok {
+			errs = append(errs, c.Close(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Build wires the adapters selected in conf.FactoryConfig, which can be
+// overridden by SRV_FACTORY_STORAGE, SRV_FACTORY_MESSAGING and SRV_FACTORY_CACHE.
+// Empty selections are skipped. When a builder fails the adapters already built are closed.
+func Build(ctx context.Context, conf *config.Config) (*Adapters, error) {
+	if conf.FactoryConfig == nil {
+		conf.FactoryConfig = &config.FactoryConfig{}
+	}
+
+	SRV_FACTORY_STORAGE := os.Getenv("SRV_FACTORY_STORAGE")
+	if SRV_FACTORY_STORAGE != "" {
+		conf.FACTORY_STORAGE = SRV_FACTORY_STORAGE
+	}
+
+	SRV_FACTORY_MESSAGING := os.Getenv("SRV_FACTORY_MESSAGING")
+	if SRV_FACTORY_MESSAGING != "" {
+		conf.FACTORY_MESSAGING = SRV_FACTORY_MESSAGING
+	}
+
+	SRV_FACTORY_CACHE := os.Getenv("SRV_FACTORY_CACHE")
+	if SRV_FACTORY_CACHE != "" {
+		conf.FACTORY_CACHE = SRV_FACTORY_CACHE
+	}
+
+	if conf.FACTORY_STORAGE != "" {
+		return nil, cerrors.New(cerrors.Invalid, "factory: storage adapter "+conf.FACTORY_STORAGE+" is not available")
+	}
+
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	adapters := &Adapters{}
+
+	if conf.FACTORY_MESSAGING != "" {
+		builder, ok := messaging[conf.FACTORY_MESSAGING]
+		if !ok {
+			return nil, cerrors.New(cerrors.Invalid, "factory: unknown messaging adapter "+conf.FACTORY_MESSAGING)
+		}
+
+		rbm, err := builder(ctx, conf)
+		if err != nil {
+			log.Println("Erro to build messaging adapter", conf.FACTORY_MESSAGING)
+			return nil, err
+		}
+		adapters.Messaging = rbm
+	}
+
+	if conf.FACTORY_CACHE != "" {
+		builder, ok := caches[conf.FACTORY_CACHE]
+		if !ok {
+			return nil, cerrors.New(cerrors.Invalid, "factory: unknown cache adapter "+conf.FACTORY_CACHE)
+		}
+
+		cache, err := builder(ctx, conf)
+		if err != nil {
+			log.Println("Erro to build cache adapter", conf.FACTORY_CACHE)
+			// the adapters already built would leak, ex: the RabbitMQ connection
+			return nil, errors.Join(err, adapters.Close(ctx))
+		}
+		adapters.Cache = cache
+	}
+
+	return adapters, nil
+}
+
+func buildRabbitMQ(ctx context.Context, conf *config.Config) (rabbitmq.RabbitInterface, error) {
+	if conf.RMQConfig == nil {
+		conf.RMQConfig = &config.RMQConfig{}
+	}
+	// rabbitmq.New exits without SRV_RMQ_URI, the caller gets an error instead
+	if os.Getenv("SRV_RMQ_URI") == "" {
+		return nil, cerrors.New(cerrors.Invalid, "factory: SRV_RMQ_URI is required by the rabbitmq adapter")
+	}
+	return rabbitmq.New(conf).Connect()
+}
+
+func buildMemoryCache(ctx context.Context, conf *config.Config) (redisdb.RedisClientInterface, error) {
+	return redisdb.NewMemory(), nil
+}
+
+func buildRedisCache(ctx context.Context, conf *config.Config) (redisdb.RedisClientInterface, error) {
+	if conf.RedisDBConfig == nil {
+		conf.RedisDBConfig = &config.RedisDBConfig{}
+	}
+	return redisdb.New(conf), nil
+}
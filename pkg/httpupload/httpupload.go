@@ -0,0 +1,277 @@
+// Package httpupload streams multipart uploads straight into an Uploader, usually a
+// blob container, without temporary files or buffering whole files in memory.
+//
+//	h := httpupload.New(store, httpupload.Config{MaxSize: 50 << 20, AllowedTypes: []string{"image/png", "application/pdf"}})
+//	r.Handle("/files", h).Methods(http.MethodPost)
+package httpupload
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	"github.com/faelp22/go-commons-libs/core/deadline"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/openapi"
+)
+
+const (
+	DEFAULT_MAX_SIZE  = 32 << 20 // 32MB
+	DEFAULT_MAX_FILES = 10
+	sniffLen          = 512
+)
+
+// Uploader stores a stream under name. Upload must read r until EOF or fail.
+type Uploader interface {
+	Upload(ctx context.Context, name string, r io.Reader, contentType string) error
+	Delete(ctx context.Context, name string) error
+}
+
+// Scanner inspects the content of a file while it is uploaded, ex: an antivirus.
+// An error rejects the file, which is deleted from the Uploader.
+type Scanner interface {
+	Scan(ctx context.Context, filename string, r io.Reader) error
+}
+
+type Config struct {
+	MaxSize      int64    // per file, DEFAULT_MAX_SIZE when <= 0
+	MaxFiles     int      // per request, DEFAULT_MAX_FILES when <= 0
+	AllowedTypes []string // sniffed content types allowed, any when empty. "image/*" is accepted.
+	Prefix       string   // name prefix inside the Uploader
+	Scanner      Scanner  // optional
+	// Name builds the name of the stored file, defaults to Prefix/<random id><ext>
+	Name func(r *http.Request, filename string) string
+	// URL returns the link returned for a stored file, ex: a SAS URL. Optional.
+	URL func(ctx context.Context, name string) (string, error)
+}
+
+// File describes a stored file
+type File struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	URL         string `json:"url,omitempty"`
+}
+
+type Handler struct {
+	up   Uploader
+	conf Config
+}
+
+func New(up Uploader, conf Config) *Handler {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = DEFAULT_MAX_SIZE
+	}
+	if conf.MaxFiles <= 0 {
+		conf.MaxFiles = DEFAULT_MAX_FILES
+	}
+	if conf.Name == nil {
+		conf.Name = func(r *http.Request, filename string) string {
+			return path.Join(conf.Prefix, ctxutil.NewID()+strings.ToLower(path.Ext(filename)))
+		}
+	}
+	return &Handler{up: up, conf: conf}
+}
+
+// ServeHTTP stores every file part of the request and answers 201 with the list of File.
+// Errors are answered as problem+json.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	files, err := h.Handle(r)
+	if err != nil {
+		openapi.WriteProblem(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(files)
+}
+
+// Handle stores every file part of the multipart request r. When a file fails, the
+// files already stored by the request are deleted.
+func (h *Handler) Handle(r *http.Request) (files []File, err error) {
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "httpupload", err)
+	}
+
+	defer func() {
+		if err != nil {
+			for _, f := range files {
+				h.up.Delete(context.Background(), f.Name)
+			}
+			files = nil
+		}
+	}()
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, cerrors.Wrap(cerrors.Invalid, "httpupload", err)
+		}
+
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+		if len(files) >= h.conf.MaxFiles {
+			part.Close()
+			return files, cerrors.New(cerrors.Invalid, fmt.Sprintf("httpupload: more than %d files", h.conf.MaxFiles))
+		}
+
+		f, err := h.store(r, part)
+		part.Close()
+		if err != nil {
+			return files, err
+		}
+		files = append(files, *f)
+	}
+}
+
+func (h *Handler) store(r *http.Request, part *multipart.Part) (*File, error) {
+	ctx := r.Context()
+	limited := &limitReader{r: part, left: h.conf.MaxSize}
+	br := bufio.NewReaderSize(limited, sniffLen)
+
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, cerrors.Wrap(cerrors.Invalid, "httpupload", err)
+	}
+
+	contentType := http.DetectContentType(head)
+	if declared := part.Header.Get("Content-Type"); declared != "" && strings.HasPrefix(contentType, "text/plain") {
+		// the sniffer can't tell text formats apart, trust the client only for text formats
+		if mt, _, err := mime.ParseMediaType(declared); err == nil && textual(mt) {
+			contentType = mt
+		}
+	}
+	if !h.allowed(contentType) {
+		return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("httpupload: content type %s not allowed", contentType))
+	}
+
+	f := &File{
+		Field:       part.FormName(),
+		Filename:    path.Base(part.FileName()),
+		Name:        h.conf.Name(r, part.FileName()),
+		ContentType: contentType,
+	}
+
+	var body io.Reader = br
+	scanned := make(chan error, 1)
+	if h.conf.Scanner != nil {
+		pr, pw := io.Pipe()
+		body = io.TeeReader(br, pw)
+		go func() {
+			err := h.conf.Scanner.Scan(ctx, f.Filename, pr)
+			// keep draining so the upload isn't blocked by a scanner that stopped early
+			io.Copy(io.Discard, pr)
+			scanned <- err
+		}()
+		defer pw.Close()
+		body = &closeOnEOF{r: body, w: pw}
+	} else {
+		scanned <- nil
+	}
+
+	uctx, cancel := deadline.Apply(ctx, deadline.Upload)
+	defer cancel()
+
+	if err := h.up.Upload(uctx, f.Name, body, contentType); err != nil {
+		if limited.exceeded {
+			return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("httpupload: file bigger than %d bytes", h.conf.MaxSize))
+		}
+		return nil, err
+	}
+	if limited.exceeded {
+		h.up.Delete(ctx, f.Name)
+		return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("httpupload: file bigger than %d bytes", h.conf.MaxSize))
+	}
+
+	if err := <-scanned; err != nil {
+		h.up.Delete(ctx, f.Name)
+		return nil, cerrors.Wrap(cerrors.Invalid, "httpupload: rejected by scanner", err)
+	}
+
+	f.Size = h.conf.MaxSize - limited.left
+	if h.conf.URL != nil {
+		url, err := h.conf.URL(ctx, f.Name)
+		if err != nil {
+			h.up.Delete(ctx, f.Name)
+			return nil, err
+		}
+		f.URL = url
+	}
+	return f, nil
+}
+
+func (h *Handler) allowed(contentType string) bool {
+	if len(h.conf.AllowedTypes) == 0 {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(contentType)
+	for _, allowed := range h.conf.AllowedTypes {
+		if allowed == mt || strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// textual reports whether mt is a text format, the only ones the sniffer reports as
+// text/plain, so a client can't pass a text file for an image or a PDF
+func textual(mt string) bool {
+	if strings.HasPrefix(mt, "text/") || strings.HasSuffix(mt, "+json") || strings.HasSuffix(mt, "+xml") {
+		return true
+	}
+	switch mt {
+	case "application/json", "application/xml", "application/x-ndjson", "application/yaml", "application/x-yaml", "application/csv":
+		return true
+	}
+	return false
+}
+
+// limitReader fails once more than left bytes are read
+type limitReader struct {
+	r        io.Reader
+	left     int64
+	exceeded bool
+}
+
+func (lr *limitReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.left -= int64(n)
+	if lr.left < 0 {
+		lr.exceeded = true
+		return n, cerrors.New(cerrors.Invalid, "httpupload: file too big")
+	}
+	return n, err
+}
+
+// closeOnEOF closes the scanner pipe when the upload reaches the end of the file
+type closeOnEOF struct {
+	r io.Reader
+	w *io.PipeWriter
+}
+
+func (c *closeOnEOF) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if err == io.EOF {
+		c.w.Close()
+	} else if err != nil {
+		c.w.CloseWithError(err)
+	}
+	return n, err
+}
@@ -0,0 +1,471 @@
+// Package importer is the mirror of export: it reads CSV and XLSX files from a Store,
+// validates every row against a Schema and feeds the valid ones to a Handler, writing
+// the row level errors to a CSV file next to the imported one:
+//
+//	schema := importer.Schema{Columns: []importer.Column{
+//		{Name: "email", Type: importer.String, Required: true},
+//		{Name: "amount", Type: importer.Decimal},
+//		{Name: "due", Type: importer.Date, Layout: "02/01/2006"},
+//	}}
+//	im := importer.New(store, schema, importer.Config{})
+//	res, err := im.Import(ctx, "imports/customers.xlsx", importer.ToQueue(pub, "customers", "customer.imported", "billing"))
+//
+//	imports/customers.xlsx.errors.csv: line,column,value,error
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/pkg/messaging"
+)
+
+const (
+	DEFAULT_MAX_ERRORS = 1000
+	ERRORS_SUFFIX      = ".errors.csv"
+)
+
+// Store holds the imported files and receives the error reports, usually a blob
+// container. Any archiver.Store is a Store.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+// Opener is implemented by the Stores able to stream a file. CSV files are read through
+// it without loading them in memory, XLSX files are always loaded since zip needs random
+// access.
+type Opener interface {
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+}
+
+type Type string
+
+const (
+	String  Type = "string"
+	Int     Type = "int"
+	Float   Type = "float"
+	Decimal Type = "decimal" // kept as its string, ex: "19.90", for money.Parse
+	Bool    Type = "bool"
+	Date    Type = "date" // parsed with Layout, Excel serial numbers are accepted too
+)
+
+type Column struct {
+	Name     string // header name, matched ignoring case and spaces around it
+	Type     Type   // String when ""
+	Required bool
+	Layout   string         // time layout of Date columns, time.RFC3339 or "2006-01-02" when ""
+	Pattern  *regexp.Regexp // optional, checked on the raw value
+	// Validate checks the parsed value, ex: brdoc.ValidCPF
+	Validate func(value interface{}) error
+}
+
+type Schema struct {
+	Columns []Column
+	// Strict rejects files with columns not in the Schema
+	Strict bool
+}
+
+// Row is a valid row, Values holds the parsed value of every Column present in the file
+type Row struct {
+	Line   int // line of a CSV file or row of a XLSX sheet
+	Values map[string]interface{}
+}
+
+// Decode converts the Values of a Row into T, following its json tags
+func Decode[T any](row Row) (T, error) {
+	var v T
+	data, err := json.Marshal(row.Values)
+	if err != nil {
+		return v, cerrors.Wrap(cerrors.Invalid, "importer.Decode", err)
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return v, cerrors.Wrap(cerrors.Invalid, "importer.Decode", err)
+	}
+	return v, nil
+}
+
+// Handler receives every valid Row. An error of kind Unavailable stops the import,
+// other errors are reported for the row.
+type Handler func(ctx context.Context, row Row) error
+
+// ToQueue publishes every Row as an Envelope of eventType whose payload is its Values
+func ToQueue(pub messaging.Publisher, topic, eventType, source string) Handler {
+	return func(ctx context.Context, row Row) error {
+		_, err := messaging.PublishEvent(ctx, pub, topic, eventType, source, row.Values)
+		return err
+	}
+}
+
+type Config struct {
+	Comma     rune   // CSV separator, detected from the header between ',' and ';' when 0
+	Sheet     string // XLSX sheet name, the first sheet when ""
+	MaxErrors int    // row errors before the import stops, DEFAULT_MAX_ERRORS when <= 0
+	// ErrorsName returns the name of the error report, "{name}.errors.csv" when nil
+	ErrorsName func(name string) string
+}
+
+// RowError is a problem of one row, Column is empty for errors of the whole row
+type RowError struct {
+	Line   int    `json:"line"`
+	Column string `json:"column,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Err    string `json:"error"`
+}
+
+type Result struct {
+	Rows    int        `json:"rows"` // data rows read
+	Valid   int        `json:"valid"`
+	Invalid int        `json:"invalid"`
+	Errors  []RowError `json:"errors,omitempty"`
+	// ErrorsName is the report written to the Store, empty when every row was valid
+	ErrorsName string `json:"errors_name,omitempty"`
+}
+
+type Importer struct {
+	store  Store
+	schema Schema
+	conf   Config
+}
+
+func New(store Store, schema Schema, conf Config) *Importer {
+	if conf.MaxErrors <= 0 {
+		conf.MaxErrors = DEFAULT_MAX_ERRORS
+	}
+	if conf.ErrorsName == nil {
+		conf.ErrorsName = func(name string) string { return name + ERRORS_SUFFIX }
+	}
+	return &Importer{store: store, schema: schema, conf: conf}
+}
+
+type rowReader interface {
+	Read() ([]string, error)
+	// Line returns the line (CSV) or row number (XLSX) of the last record read
+	Line() int
+}
+
+type csvRows struct {
+	r    *csv.Reader
+	line int
+}
+
+func (cr *csvRows) Read() ([]string, error) {
+	record, err := cr.r.Read()
+	if pe, ok := err.(*csv.ParseError); ok {
+		cr.line = pe.StartLine
+	} else if err == nil {
+		cr.line, _ = cr.r.FieldPos(0)
+	}
+	return record, err
+}
+
+func (cr *csvRows) Line() int {
+	return cr.line
+}
+
+// Import reads name, a .csv or .xlsx file, and calls handler with every valid row.
+// A file that can't be read or whose header misses a required column returns an error
+// of kind Invalid without calling handler.
+func (im *Importer) Import(ctx context.Context, name string, handler Handler) (res *Result, err error) {
+	end := hooks.Begin(ctx, "importer", "Import", map[string]interface{}{"name": name})
+	defer func() { end(err) }()
+
+	var rows rowReader
+	switch strings.ToLower(path.Ext(name)) {
+	case ".xlsx":
+		data, err := im.store.Get(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		xr, err := newXLSXReader(data, im.conf.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		defer xr.Close()
+		rows = xr
+	case ".csv", ".txt":
+		var r io.Reader
+		if opener, ok := im.store.(Opener); ok {
+			rc, err := opener.Open(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			defer rc.Close()
+			r = rc
+		} else {
+			data, err := im.store.Get(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			r = bytes.NewReader(data)
+		}
+		rows = im.csvReader(r)
+	default:
+		return nil, cerrors.New(cerrors.Invalid, "importer: unsupported file "+name)
+	}
+
+	header, err := rows.Read()
+	if err == io.EOF {
+		return nil, cerrors.New(cerrors.Invalid, "importer: empty file "+name)
+	}
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "importer.Import", err)
+	}
+	columns, err := im.match(header)
+	if err != nil {
+		return nil, err
+	}
+
+	res = &Result{}
+	for {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		line := rows.Line()
+		if err != nil {
+			// a malformed CSV line, the reader continues with the next one
+			if _, ok := err.(*csv.ParseError); ok {
+				res.Rows++
+				im.reject(res, RowError{Line: line, Err: err.Error()})
+				if len(res.Errors) >= im.conf.MaxErrors {
+					break
+				}
+				continue
+			}
+			return res, cerrors.Wrap(cerrors.Invalid, "importer.Import", err)
+		}
+		if blank(record) {
+			continue
+		}
+		res.Rows++
+
+		row, rowErrs := im.parse(line, columns, record)
+		if len(rowErrs) == 0 {
+			if err := handler(ctx, row); err != nil {
+				if cerrors.Is(err, cerrors.Unavailable) {
+					return res, err
+				}
+				rowErrs = append(rowErrs, RowError{Line: line, Err: err.Error()})
+			}
+		}
+
+		if len(rowErrs) > 0 {
+			im.reject(res, rowErrs...)
+			if len(res.Errors) >= im.conf.MaxErrors {
+				break
+			}
+			continue
+		}
+		res.Valid++
+	}
+
+	if len(res.Errors) > 0 {
+		if err := im.report(ctx, name, res); err != nil {
+			return res, err
+		}
+	}
+	if len(res.Errors) >= im.conf.MaxErrors {
+		return res, cerrors.New(cerrors.Invalid, fmt.Sprintf("importer: %s stopped after %d errors", name, len(res.Errors)))
+	}
+	return res, nil
+}
+
+func (im *Importer) csvReader(src io.Reader) rowReader {
+	br := bufio.NewReaderSize(src, 64<<10)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) { // UTF-8 BOM of Excel exports
+		br.Discard(3)
+	}
+
+	comma := im.conf.Comma
+	if comma == 0 {
+		// the header is detected within the buffer, longer headers default to ','
+		head, _ := br.Peek(br.Size())
+		first, _, _ := bytes.Cut(head, []byte("\n"))
+		comma = ','
+		if bytes.Count(first, []byte(";")) > bytes.Count(first, []byte(",")) {
+			comma = ';'
+		}
+	}
+
+	r := csv.NewReader(br)
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.ReuseRecord = true
+	return &csvRows{r: r}
+}
+
+// match returns the Column of every header position, nil for columns not in the Schema
+func (im *Importer) match(header []string) ([]*Column, error) {
+	columns := make([]*Column, len(header))
+	found := map[string]bool{}
+
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		for j := range im.schema.Columns {
+			if strings.EqualFold(h, im.schema.Columns[j].Name) {
+				columns[i] = &im.schema.Columns[j]
+				found[im.schema.Columns[j].Name] = true
+				break
+			}
+		}
+		if columns[i] == nil && im.schema.Strict && h != "" {
+			return nil, cerrors.New(cerrors.Invalid, "importer: unexpected column "+h)
+		}
+	}
+
+	for _, c := range im.schema.Columns {
+		if c.Required && !found[c.Name] {
+			return nil, cerrors.New(cerrors.Invalid, "importer: missing column "+c.Name)
+		}
+	}
+	return columns, nil
+}
+
+func (im *Importer) parse(line int, columns []*Column, record []string) (Row, []RowError) {
+	row := Row{Line: line, Values: map[string]interface{}{}}
+	var errs []RowError
+
+	for i, c := range columns {
+		if c == nil {
+			continue
+		}
+		raw := ""
+		if i < len(record) {
+			raw = strings.TrimSpace(record[i])
+		}
+
+		if raw == "" {
+			if c.Required {
+				errs = append(errs, RowError{Line: line, Column: c.Name, Err: "required"})
+			}
+			continue
+		}
+		if c.Pattern != nil && !c.Pattern.MatchString(raw) {
+			errs = append(errs, RowError{Line: line, Column: c.Name, Value: raw, Err: "doesn't match " + c.Pattern.String()})
+			continue
+		}
+
+		value, err := convert(c, raw)
+		if err == nil && c.Validate != nil {
+			err = c.Validate(value)
+		}
+		if err != nil {
+			errs = append(errs, RowError{Line: line, Column: c.Name, Value: raw, Err: err.Error()})
+			continue
+		}
+		row.Values[c.Name] = value
+	}
+	return row, errs
+}
+
+func convert(c *Column, raw string) (interface{}, error) {
+	switch c.Type {
+	case "", String:
+		return raw, nil
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Float:
+		return strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64)
+	case Decimal:
+		// accepts "1234.50", "1,234.50" and "1.234,50": the last of ',' and '.' is the
+		// decimal separator unless repeated, ex: "1.234.567", and the other groups thousands
+		decimal, group := ",", "."
+		if strings.LastIndex(raw, ".") > strings.LastIndex(raw, ",") {
+			decimal, group = ".", ","
+		}
+		if strings.Count(raw, decimal) > 1 {
+			decimal, group = "", decimal
+		}
+		normalized := strings.ReplaceAll(raw, group, "")
+		if decimal == "," {
+			normalized = strings.Replace(normalized, ",", ".", 1)
+		}
+		if _, ok := new(big.Rat).SetString(normalized); !ok {
+			return nil, cerrors.New(cerrors.Invalid, "not a decimal")
+		}
+		return normalized, nil
+	case Bool:
+		switch strings.ToLower(raw) {
+		case "1", "true", "yes", "sim", "s", "y":
+			return true, nil
+		case "0", "false", "no", "não", "nao", "n":
+			return false, nil
+		}
+		return nil, cerrors.New(cerrors.Invalid, "not a boolean")
+	case Date:
+		return parseDate(raw, c.Layout)
+	}
+	return nil, cerrors.New(cerrors.Invalid, "unknown column type "+string(c.Type))
+}
+
+// parseDate parses raw with layout, or as an Excel serial number (days since 1899-12-30)
+func parseDate(raw, layout string) (time.Time, error) {
+	layouts := []string{layout}
+	if layout == "" {
+		layouts = []string{time.RFC3339, "2006-01-02"}
+	}
+	for _, l := range layouts {
+		if t, err := time.Parse(l, raw); err == nil {
+			return t, nil
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil && serial > 0 && serial < 2958466 {
+		days := math.Floor(serial)
+		seconds := math.Round((serial - days) * 86400)
+		return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second), nil
+	}
+	return time.Time{}, cerrors.New(cerrors.Invalid, "not a date in the layout "+layouts[0])
+}
+
+// reject counts a row as invalid and keeps its errors
+func (im *Importer) reject(res *Result, errs ...RowError) {
+	res.Invalid++
+	res.Errors = append(res.Errors, errs...)
+}
+
+// report writes the errors of res as CSV to the Store
+func (im *Importer) report(ctx context.Context, name string, res *Result) error {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"line", "column", "value", "error"})
+	for _, e := range res.Errors {
+		w.Write([]string{strconv.Itoa(e.Line), e.Column, e.Value, e.Err})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+
+	res.ErrorsName = im.conf.ErrorsName(name)
+	return im.store.Put(ctx, res.ErrorsName, buf.Bytes(), "text/csv")
+}
+
+func blank(record []string) bool {
+	for _, v := range record {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,286 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	maxColumns  = 16384     // XFD, the last column of a worksheet
+	maxPartSize = 256 << 20 // 256MB uncompressed, bounds zip bombs
+)
+
+// xlsxReader reads the cell values of one worksheet row by row. Styles are ignored,
+// so dates come as Excel serial numbers (see parseDate) and numbers unformatted.
+type xlsxReader struct {
+	sheet  io.ReadCloser
+	dec    *xml.Decoder
+	shared []string
+	line   int
+}
+
+func newXLSXReader(data []byte, sheet string) (*xlsxReader, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "importer.xlsx", err)
+	}
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	target, err := sheetPath(files, sheet)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = sharedStrings(f); err != nil {
+			return nil, err
+		}
+	}
+
+	f, ok := files[target]
+	if !ok {
+		return nil, cerrors.New(cerrors.Invalid, "importer: xlsx without "+target)
+	}
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxReader{sheet: rc, dec: xml.NewDecoder(rc), shared: shared}, nil
+}
+
+// Read returns the next row with a value, io.EOF at the end of the sheet
+func (xr *xlsxReader) Read() ([]string, error) {
+	var (
+		row  []string
+		cell struct {
+			col   int
+			typ   string
+			value strings.Builder
+		}
+		inValue bool
+	)
+
+	for {
+		tok, err := xr.dec.Token()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "importer.xlsx", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = []string{}
+				xr.line++
+				for _, a := range t.Attr {
+					if a.Name.Local == "r" {
+						xr.line, _ = strconv.Atoi(a.Value)
+					}
+				}
+			case "c":
+				cell.col, cell.typ = len(row), ""
+				cell.value.Reset()
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "r":
+						cell.col = columnIndex(a.Value)
+					case "t":
+						cell.typ = a.Value
+					}
+				}
+				if cell.col < 0 || cell.col >= maxColumns {
+					return nil, cerrors.New(cerrors.Invalid, "importer: xlsx column out of range in row "+strconv.Itoa(xr.line))
+				}
+			case "v", "t":
+				inValue = true
+			}
+		case xml.CharData:
+			if inValue {
+				cell.value.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				value := cell.value.String()
+				switch cell.typ {
+				case "s":
+					i, err := strconv.Atoi(value)
+					if err != nil || i < 0 || i >= len(xr.shared) {
+						return nil, cerrors.New(cerrors.Invalid, "importer: invalid shared string "+value)
+					}
+					value = xr.shared[i]
+				case "b":
+					value = map[string]string{"1": "true", "0": "false"}[value]
+				}
+				for len(row) < cell.col {
+					row = append(row, "")
+				}
+				row = append(row, value)
+			case "row":
+				if len(row) > 0 {
+					return row, nil
+				}
+			}
+		}
+	}
+}
+
+func (xr *xlsxReader) Line() int {
+	return xr.line
+}
+
+func (xr *xlsxReader) Close() error {
+	return xr.sheet.Close()
+}
+
+// sheetPath resolves the worksheet file of a sheet name, the first sheet when name is ""
+func sheetPath(files map[string]*zip.File, name string) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	}
+	if err := decodeXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+
+	for _, s := range workbook.Sheets {
+		if name != "" && !strings.EqualFold(s.Name, name) {
+			continue
+		}
+		for _, r := range rels.Relationships {
+			if r.ID != s.RID {
+				continue
+			}
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/"), nil
+			}
+			return path.Join("xl", r.Target), nil
+		}
+	}
+	return "", cerrors.New(cerrors.Invalid, "importer: sheet not found "+name)
+}
+
+// sharedStrings concatenates the text runs of every shared string, skipping phonetic hints
+func sharedStrings(f *zip.File) ([]string, error) {
+	rc, err := openPart(f)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var (
+		shared  []string
+		current strings.Builder
+		inText  bool
+		inRPh   bool
+	)
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "importer.xlsx", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "rPh":
+				inRPh = true
+			case "t":
+				inText = !inRPh
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				shared = append(shared, current.String())
+			case "rPh":
+				inRPh = false
+			case "t":
+				inText = false
+			}
+		}
+	}
+}
+
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f, ok := files[name]
+	if !ok {
+		return cerrors.New(cerrors.Invalid, "importer: xlsx without "+name)
+	}
+	rc, err := openPart(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "importer.xlsx", err)
+	}
+	return nil
+}
+
+// openPart opens a file of the xlsx refusing the ones larger than maxPartSize,
+// archive/zip fails the reads past the declared size
+func openPart(f *zip.File) (io.ReadCloser, error) {
+	if f.UncompressedSize64 > maxPartSize {
+		return nil, cerrors.New(cerrors.Invalid, "importer: xlsx part too large "+f.Name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "importer.xlsx", err)
+	}
+	return rc, nil
+}
+
+// columnIndex converts the letters of a cell reference to a column index, ex: "AB12" -> 27.
+// References past XFD return maxColumns.
+func columnIndex(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		if col > maxColumns {
+			return maxColumns
+		}
+	}
+	return col - 1
+}
@@ -0,0 +1,70 @@
+package kv
+
+import (
+	"context"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/crypto"
+)
+
+type encryptedStore struct {
+	store  Store
+	cipher crypto.Cipher
+}
+
+// encryptedCounter is the encryptedStore of a Counter
+type encryptedCounter struct {
+	*encryptedStore
+	counter Counter
+}
+
+// NewEncrypted returns a Store encrypting the values of store with c. The key is
+// authenticated with the value, so a value copied to another key fails to decrypt.
+//
+// When store is a Counter so is the returned Store: counters are not secret, Incr goes
+// to store unencrypted, so read them with Incr(ctx, key, 0) instead of Get.
+func NewEncrypted(store Store, c crypto.Cipher) Store {
+	es := &encryptedStore{store: store, cipher: c}
+	if counter, ok := store.(Counter); ok {
+		return &encryptedCounter{encryptedStore: es, counter: counter}
+	}
+	return es
+}
+
+func (ec *encryptedCounter) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	return ec.counter.Incr(ctx, key, delta)
+}
+
+func (es *encryptedStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := es.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return es.cipher.Decrypt(data, []byte(key))
+}
+
+func (es *encryptedStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	data, err := es.cipher.Encrypt(value, []byte(key))
+	if err != nil {
+		return err
+	}
+	return es.store.Set(ctx, key, data, ttl)
+}
+
+func (es *encryptedStore) Delete(ctx context.Context, key string) error {
+	return es.store.Delete(ctx, key)
+}
+
+func (es *encryptedStore) List(ctx context.Context, prefix string) ([]Entry, error) {
+	entries, err := es.store.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].Value, err = es.cipher.Decrypt(entries[i].Value, []byte(entries[i].Key)); err != nil {
+			return nil, err
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,31 @@
+// Package kv is a small key-value store abstraction for lookup and state needs
+// that don't justify a database adapter. Implementations: in-memory and Redis.
+package kv
+
+import (
+	"context"
+	"time"
+)
+
+type Entry struct {
+	Key   string
+	Value []byte
+}
+
+type Store interface {
+	// Get returns the value of key or an error of kind cerrors.NotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key. A ttl <= 0 never expires.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	// List returns every entry whose key starts with prefix, sorted by key
+	List(ctx context.Context, prefix string) ([]Entry, error)
+}
+
+// Counter is implemented by the stores able to update an integer value atomically,
+// across instances for Redis. Values are stored as decimal strings.
+type Counter interface {
+	// Incr adds delta to the integer value of key, missing keys count as 0, and returns it
+	Incr(ctx context.Context, key string, delta int64) (int64, error)
+}
@@ -0,0 +1,103 @@
+package kv
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type memoryStore struct {
+	mu    sync.RWMutex
+	items map[string]memoryItem
+}
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time // zero never expires
+}
+
+func (mi memoryItem) expired(now time.Time) bool {
+	return !mi.expires.IsZero() && now.After(mi.expires)
+}
+
+// NewMemory returns a process local Store
+func NewMemory() Store {
+	return &memoryStore{items: map[string]memoryItem{}}
+}
+
+func (ms *memoryStore) Get(ctx context.Context, key string) ([]byte, error) {
+	ms.mu.RLock()
+	item, ok := ms.items[key]
+	ms.mu.RUnlock()
+
+	if !ok || item.expired(time.Now()) {
+		return nil, cerrors.New(cerrors.NotFound, "kv: key not found: "+key)
+	}
+	return append([]byte(nil), item.value...), nil
+}
+
+func (ms *memoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	item := memoryItem{value: append([]byte(nil), value...)}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.items[key] = item
+	return nil
+}
+
+func (ms *memoryStore) Delete(ctx context.Context, key string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.items, key)
+	return nil
+}
+
+func (ms *memoryStore) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	var value int64
+	item, ok := ms.items[key]
+	if ok && !item.expired(time.Now()) {
+		v, err := strconv.ParseInt(string(item.value), 10, 64)
+		if err != nil {
+			return 0, cerrors.Wrap(cerrors.Invalid, "kv.Incr", err)
+		}
+		value = v
+	} else {
+		item = memoryItem{}
+	}
+
+	value += delta
+	item.value = []byte(strconv.FormatInt(value, 10))
+	ms.items[key] = item
+	return value, nil
+}
+
+func (ms *memoryStore) List(ctx context.Context, prefix string) ([]Entry, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	var entries []Entry
+	for key, item := range ms.items {
+		if item.expired(now) {
+			delete(ms.items, key)
+			continue
+		}
+		if strings.HasPrefix(key, prefix) {
+			entries = append(entries, Entry{Key: key, Value: append([]byte(nil), item.value...)})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
@@ -0,0 +1,124 @@
+package kv
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+const redisScanCount = 500
+
+type redisStore struct {
+	rdb redis.UniversalClient
+}
+
+// NewRedis returns a Store backed by a Redis client
+func NewRedis(rdb redis.UniversalClient) Store {
+	return &redisStore{rdb: rdb}
+}
+
+func (rs *redisStore) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := rs.rdb.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, cerrors.Wrap(cerrors.NotFound, "kv.Get", err)
+	}
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "kv.Get", err)
+	}
+	return value, nil
+}
+
+func (rs *redisStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl < 0 {
+		ttl = 0
+	}
+	return cerrors.Wrap(cerrors.Unavailable, "kv.Set", rs.rdb.Set(ctx, key, value, ttl).Err())
+}
+
+func (rs *redisStore) Delete(ctx context.Context, key string) error {
+	return cerrors.Wrap(cerrors.Unavailable, "kv.Delete", rs.rdb.Del(ctx, key).Err())
+}
+
+func (rs *redisStore) Incr(ctx context.Context, key string, delta int64) (int64, error) {
+	value, err := rs.rdb.IncrBy(ctx, key, delta).Result()
+	if err != nil {
+		return 0, cerrors.Wrap(cerrors.Unavailable, "kv.Incr", err)
+	}
+	return value, nil
+}
+
+// List scans every master of a cluster client, SCAN only walks the node it is sent to.
+// A key can be returned twice by SCAN, ex: during a rehash, so keys are deduplicated.
+func (rs *redisStore) List(ctx context.Context, prefix string) ([]Entry, error) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	scan := func(ctx context.Context, c redis.Cmdable) error {
+		iter := c.Scan(ctx, 0, escapePattern(prefix)+"*", redisScanCount).Iterator()
+		for iter.Next(ctx) {
+			mu.Lock()
+			seen[iter.Val()] = true
+			mu.Unlock()
+		}
+		return iter.Err()
+	}
+
+	var err error
+	if cluster, ok := rs.rdb.(*redis.ClusterClient); ok {
+		err = cluster.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error { return scan(ctx, c) })
+	} else {
+		err = scan(ctx, rs.rdb)
+	}
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "kv.List", err)
+	}
+
+	keys := make([]string, 0, len(seen))
+	for key := range seen {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entries := make([]Entry, 0, len(keys))
+	for start := 0; start < len(keys); start += redisScanCount {
+		end := start + redisScanCount
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		// pipelined GETs instead of MGET, the keys of a cluster span several slots
+		cmds, err := rs.rdb.Pipelined(ctx, func(p redis.Pipeliner) error {
+			for _, key := range keys[start:end] {
+				p.Get(ctx, key)
+			}
+			return nil
+		})
+		if err != nil && err != redis.Nil {
+			return nil, cerrors.Wrap(cerrors.Unavailable, "kv.List", err)
+		}
+
+		for i, cmd := range cmds {
+			value, err := cmd.(*redis.StringCmd).Bytes()
+			if err == redis.Nil { // expired or deleted between SCAN and GET
+				continue
+			}
+			if err != nil {
+				return nil, cerrors.Wrap(cerrors.Unavailable, "kv.List", err)
+			}
+			entries = append(entries, Entry{Key: keys[start+i], Value: value})
+		}
+	}
+
+	return entries, nil
+}
+
+// escapePattern quotes the glob characters of a SCAN MATCH pattern
+func escapePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,51 @@
+package kv
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const TOKEN_PREFIX = "tok_"
+
+// Tokenizer is the redact.Tokenizer over a Store. It derives the token of a value with
+// HMAC-SHA256, so a value always has the same token, and keeps the value under
+// "{prefix}{token}" to resolve it back. Wrap the store with NewEncrypted, the values are
+// the personal data being protected.
+type Tokenizer struct {
+	store  Store
+	prefix string
+	key    []byte
+}
+
+// NewTokenizer returns a Tokenizer, key must be secret or tokens can be guessed
+func NewTokenizer(store Store, prefix string, key []byte) *Tokenizer {
+	return &Tokenizer{store: store, prefix: prefix, key: key}
+}
+
+func (kt *Tokenizer) Tokenize(ctx context.Context, value string) (string, error) {
+	mac := hmac.New(sha256.New, kt.key)
+	mac.Write([]byte(value))
+	token := TOKEN_PREFIX + hex.EncodeToString(mac.Sum(nil)[:12])
+
+	if err := kt.store.Set(ctx, kt.prefix+token, []byte(value), 0); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Detokenize returns the value of token, or an error of kind NotFound
+func (kt *Tokenizer) Detokenize(ctx context.Context, token string) (string, error) {
+	if !strings.HasPrefix(token, TOKEN_PREFIX) {
+		return "", cerrors.New(cerrors.Invalid, "kv: not a token")
+	}
+	value, err := kt.store.Get(ctx, kt.prefix+token)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
@@ -0,0 +1,128 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 100
+	DEFAULT_BATCH_WAIT = time.Second
+)
+
+// BatchHandler processes a batch. The deliveries are settled together: a nil error
+// acks all of them and an error nacks all of them with the requeue rule of Handler.
+type BatchHandler func(ctx context.Context, batch []Delivery) error
+
+type BatchConfig struct {
+	MaxSize int           // deliveries per batch, DEFAULT_BATCH_SIZE when <= 0
+	MaxWait time.Duration // max time a delivery waits for its batch, DEFAULT_BATCH_WAIT when <= 0
+}
+
+// Batcher aggregates deliveries into batches by size or time window, for downstream
+// systems that are more efficient per batch (bulk inserts, bulk APIs):
+//
+//	b := messaging.NewBatcher(messaging.BatchConfig{MaxSize: 500}, bulkInsert)
+//	b.Start(ctx)
+//	sub.Subscribe(ctx, "orders.index", b.Handler())
+//
+// The broker prefetch must be at least MaxSize, otherwise batches are only
+// flushed by MaxWait.
+type Batcher struct {
+	conf    BatchConfig
+	fn      BatchHandler
+	mu      sync.Mutex
+	batch   []Delivery
+	ctx     context.Context
+	started time.Time
+	clock   clock.Clock
+}
+
+func NewBatcher(conf BatchConfig, fn BatchHandler) *Batcher {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = DEFAULT_BATCH_SIZE
+	}
+	if conf.MaxWait <= 0 {
+		conf.MaxWait = DEFAULT_BATCH_WAIT
+	}
+	return &Batcher{conf: conf, fn: fn, clock: clock.New(), ctx: context.Background()}
+}
+
+func (b *Batcher) SetClock(c clock.Clock) {
+	b.clock = c
+}
+
+// Handler returns the Handler to subscribe with. It defers the settlement of every
+// delivery to its batch and flushes when the batch reaches MaxSize.
+func (b *Batcher) Handler() Handler {
+	return func(ctx context.Context, d Delivery) error {
+		b.mu.Lock()
+		if len(b.batch) == 0 {
+			b.started = b.clock.Now()
+			// the batch handler runs with the context of the first delivery
+			b.ctx = ctx
+		}
+		b.batch = append(b.batch, d)
+		full := len(b.batch) >= b.conf.MaxSize
+		b.mu.Unlock()
+
+		if full {
+			b.Flush()
+		}
+		return ErrDeferred
+	}
+}
+
+// Start flushes the batches older than MaxWait until ctx is done, then flushes the last batch
+func (b *Batcher) Start(ctx context.Context) {
+	async.Go(func() error {
+		ticker := b.clock.NewTicker(b.conf.MaxWait / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				b.Flush()
+				return nil
+			case <-ticker.C():
+				b.mu.Lock()
+				expired := len(b.batch) > 0 && b.clock.Now().Sub(b.started) >= b.conf.MaxWait
+				b.mu.Unlock()
+				if expired {
+					b.Flush()
+				}
+			}
+		}
+	})
+}
+
+// Flush runs the batch handler over the pending deliveries and settles them
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	batch := b.batch
+	ctx := b.ctx
+	b.batch = nil
+	b.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	err := async.Safe(func() error { return b.fn(ctx, batch) })
+	if _, ok := err.(*async.PanicError); ok {
+		// a panicking batch would panic again on redelivery
+		err = cerrors.Wrap(cerrors.Invalid, "messaging.Batch", err)
+	}
+
+	for _, d := range batch {
+		if serr := Settle(d, err); serr != nil {
+			log.Println("Erro to settle batched message:", serr.Error())
+		}
+	}
+}
@@ -0,0 +1,73 @@
+// Package messaging defines broker independent Publisher and Subscriber
+// interfaces over envelope.Envelope, so domain code doesn't depend on a
+// broker client type such as amqp.Delivery.
+package messaging
+
+import (
+	"context"
+	"errors"
+
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type Publisher interface {
+	// Publish sends the Envelope to a topic (routing key, subject...)
+	Publish(ctx context.Context, topic string, env *envelope.Envelope) error
+}
+
+type Subscriber interface {
+	// Subscribe delivers every message of a subscription (queue, consumer group...) to the
+	// Handler. It returns once subscribed, the subscription ends when ctx is done.
+	Subscribe(ctx context.Context, subscription string, handler Handler) error
+}
+
+type Delivery interface {
+	Envelope() *envelope.Envelope
+	Headers() map[string]interface{}
+	// Redelivered reports whether the broker has delivered this message before
+	Redelivered() bool
+	Ack() error
+	Nack(requeue bool) error
+}
+
+// Handler processes a Delivery. When the handler doesn't Ack or Nack it
+// explicitly, a nil error acks the message and an error nacks it, requeueing
+// only when the error is of kind cerrors.Unavailable (a transient failure).
+// Returning ErrDeferred leaves the Delivery unsettled, the handler then owns it.
+type Handler func(ctx context.Context, d Delivery) error
+
+// ErrDeferred is returned by a Handler that will Ack or Nack the Delivery later
+var ErrDeferred = errors.New("messaging: delivery settlement deferred")
+
+// Settle applies the default ack semantics of Handler, used by the implementations
+func Settle(d Delivery, err error) error {
+	if errors.Is(err, ErrDeferred) {
+		return nil
+	}
+	if err == nil {
+		return d.Ack()
+	}
+	return d.Nack(cerrors.Is(err, cerrors.Unavailable))
+}
+
+// PublishEvent wraps payload in a new Envelope and publishes it
+func PublishEvent[T any](ctx context.Context, p Publisher, topic, eventType, source string, payload T) (*envelope.Envelope, error) {
+	env, err := envelope.New(eventType, source, payload)
+	if err != nil {
+		return nil, err
+	}
+	return env, p.Publish(ctx, topic, env)
+}
+
+// HandleEvent adapts a typed function to a Handler, decoding the payload into T.
+// Payloads that can't be decoded are rejected without requeue.
+func HandleEvent[T any](fn func(ctx context.Context, env *envelope.Envelope, payload T) error) Handler {
+	return func(ctx context.Context, d Delivery) error {
+		var payload T
+		if err := d.Envelope().Decode(&payload); err != nil {
+			return err
+		}
+		return fn(ctx, d.Envelope(), payload)
+	}
+}
@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/audit"
+)
+
+// Audit is an audit.AuditInterface and audit.Sink keeping every event in memory
+type Audit struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (a *Audit) Record(ev audit.Event) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, ev)
+}
+
+func (a *Audit) Write(ctx context.Context, events []audit.Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = append(a.events, events...)
+	return nil
+}
+
+func (a *Audit) Flush(ctx context.Context) error { return nil }
+func (a *Audit) Close(ctx context.Context) error { return nil }
+
+// Events returns a copy of the recorded events
+func (a *Audit) Events() []audit.Event {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]audit.Event(nil), a.events...)
+}
@@ -0,0 +1,41 @@
+package mocks
+
+import (
+	"context"
+	"database/sql"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Database is a pgsql.DatabaseInterface returning DB, usually opened with a sqlmock driver
+type Database struct {
+	DB *sql.DB
+}
+
+func (d *Database) GetDB() *sql.DB {
+	return d.DB
+}
+
+func (d *Database) Start(ctx context.Context) error { return nil }
+
+// MongoDB is a mongodb.MongoDBInterface driven by function fields
+type MongoDB struct {
+	GetCollectionFunc       func() (*mongo.Collection, error)
+	GetCollectionByNameFunc func(name string) *mongo.Collection
+}
+
+func (m *MongoDB) GetCollection() (*mongo.Collection, error) {
+	if m.GetCollectionFunc == nil {
+		return nil, nil
+	}
+	return m.GetCollectionFunc()
+}
+
+func (m *MongoDB) Start(ctx context.Context) error { return nil }
+
+func (m *MongoDB) GetCollectionByName(name string) *mongo.Collection {
+	if m.GetCollectionByNameFunc == nil {
+		return nil
+	}
+	return m.GetCollectionByNameFunc(name)
+}
@@ -0,0 +1,23 @@
+// Package mocks provides hand-written test doubles for the adapter and core
+// interfaces of this library, so consuming services don't maintain their own.
+package mocks
+
+import (
+	"github.com/faelp22/go-commons-libs/core/audit"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/mongodb"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/pgsql"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/redisdb"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/ssh"
+)
+
+// compile time checks, keeping the doubles in sync with the interfaces
+var (
+	_ rabbitmq.RabbitInterface     = (*Rabbit)(nil)
+	_ redisdb.RedisClientInterface = (*Redis)(nil)
+	_ pgsql.DatabaseInterface      = (*Database)(nil)
+	_ mongodb.MongoDBInterface     = (*MongoDB)(nil)
+	_ audit.AuditInterface         = (*Audit)(nil)
+	_ audit.Sink                   = (*Audit)(nil)
+	_ ssh.SSHInterface             = (*SSH)(nil)
+)
@@ -0,0 +1,224 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type Published struct {
+	Config  rabbitmq.ProducerConfig
+	Message rabbitmq.Message
+}
+
+// Rabbit is an in-memory RabbitInterface, AdminInterface and SupervisorInterface. Published messages are recorded,
+// consumer callbacks are registered per queue and fed with Deliver.
+// Every *Func field, when set, overrides the default behavior of its method.
+//
+// GetConnect returns an unexported type and is left to the embedded nil interface, calling
+// it panics: use the methods of RabbitInterface, such as CancelConsumer, instead.
+// Delivered messages without an Acknowledger get one whose Ack, Nack and Reject are no-ops.
+type Rabbit struct {
+	rabbitmq.RabbitInterface
+
+	ConnectFunc      func() (rabbitmq.RabbitInterface, error)
+	DeclareFunc      func(cq []rabbitmq.Queue, ce []rabbitmq.Exchange) []error
+	ProducerFunc     func(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error
+	InspectQueueFunc func(name string) (amqp.Queue, error)
+
+	mu        sync.Mutex
+	published []Published
+	queues    map[string]rabbitmq.Queue
+	exchanges map[string]rabbitmq.Exchange
+	consumers map[string][]func(msg *amqp.Delivery)
+	tags      map[string]string // queue by consumer tag
+	messages  map[string][]amqp.Delivery
+}
+
+type noAck struct{}
+
+func (noAck) Ack(tag uint64, multiple bool) error           { return nil }
+func (noAck) Nack(tag uint64, multiple, requeue bool) error { return nil }
+func (noAck) Reject(tag uint64, requeue bool) error         { return nil }
+
+func acknowledged(msg amqp.Delivery) amqp.Delivery {
+	if msg.Acknowledger == nil {
+		msg.Acknowledger = noAck{}
+	}
+	return msg
+}
+
+func NewRabbit() *Rabbit {
+	return &Rabbit{
+		queues:    map[string]rabbitmq.Queue{},
+		exchanges: map[string]rabbitmq.Exchange{},
+		consumers: map[string][]func(msg *amqp.Delivery){},
+		tags:      map[string]string{},
+		messages:  map[string][]amqp.Delivery{},
+	}
+}
+
+func (r *Rabbit) Connect() (rabbitmq.RabbitInterface, error) {
+	if r.ConnectFunc != nil {
+		return r.ConnectFunc()
+	}
+	return r, nil
+}
+
+func (r *Rabbit) SimpleQueueDeclare(sq rabbitmq.Queue) (amqp.Queue, error) {
+	if errs := r.CompleteDeclare([]rabbitmq.Queue{sq}, nil); len(errs) > 0 {
+		return amqp.Queue{}, errs[0]
+	}
+	return amqp.Queue{Name: sq.Name}, nil
+}
+
+func (r *Rabbit) CompleteQueueDeclare(cq []rabbitmq.Queue) []error {
+	return r.CompleteDeclare(cq, nil)
+}
+
+func (r *Rabbit) SimpleExchangeDeclare(se rabbitmq.Exchange) error {
+	if errs := r.CompleteDeclare(nil, []rabbitmq.Exchange{se}); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+func (r *Rabbit) CompleteExchangeDeclare(ce []rabbitmq.Exchange) []error {
+	return r.CompleteDeclare(nil, ce)
+}
+
+func (r *Rabbit) CompleteDeclare(cq []rabbitmq.Queue, ce []rabbitmq.Exchange) []error {
+	if r.DeclareFunc != nil {
+		return r.DeclareFunc(cq, ce)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, exchange := range ce {
+		r.exchanges[exchange.Name] = exchange
+	}
+	for _, queue := range cq {
+		r.queues[queue.Name] = queue
+	}
+	return nil
+}
+
+func (r *Rabbit) InspectQueue(name string) (amqp.Queue, error) {
+	if r.InspectQueueFunc != nil {
+		return r.InspectQueueFunc(name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.queues[name]; !ok {
+		// wrapped as the pool does
+		return amqp.Queue{}, cerrors.Wrap(cerrors.NotFound, "rabbitmq.InspectQueue", &amqp.Error{Code: amqp.NotFound, Reason: "NOT_FOUND - no queue '" + name + "'"})
+	}
+	return amqp.Queue{Name: name, Messages: len(r.messages[name]), Consumers: len(r.consumers[name])}, nil
+}
+
+func (r *Rabbit) ExchangeExists(name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.exchanges[name]
+	return ok, nil
+}
+
+func (r *Rabbit) PurgeQueue(name string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := len(r.messages[name])
+	delete(r.messages, name)
+	return count, nil
+}
+
+// GetMessage pops messages queued with Enqueue
+func (r *Rabbit) GetMessage(queue string) (amqp.Delivery, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	msgs := r.messages[queue]
+	if len(msgs) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	r.messages[queue] = msgs[1:]
+	return acknowledged(msgs[0]), true, nil
+}
+
+func (r *Rabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	if r.ProducerFunc != nil {
+		if err := r.ProducerFunc(ctx, pc, msg); err != nil {
+			return err
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.published = append(r.published, Published{Config: *pc, Message: *msg})
+	return nil
+}
+
+func (r *Rabbit) Consumer(cc *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consumers[cc.Queue] = append(r.consumers[cc.Queue], callback)
+	if cc.Consumer != "" {
+		r.tags[cc.Consumer] = cc.Queue
+	}
+}
+
+func (r *Rabbit) StartConsumer(cc *rabbitmq.ConsumerConfig, callback func(msg *amqp.Delivery)) {
+	r.Consumer(cc, callback)
+}
+
+// Supervise runs register once and waits for stop, the mock never reconnects
+func (r *Rabbit) Supervise(stop <-chan struct{}, register func()) {
+	register()
+	<-stop
+}
+
+func (r *Rabbit) SetClock(c clock.Clock) {}
+
+func (r *Rabbit) Start(ctx context.Context) error { return nil }
+func (r *Rabbit) Drain(ctx context.Context) error { return nil }
+
+// CancelConsumer removes the callbacks of the queue consumed with tag
+func (r *Rabbit) CancelConsumer(ctx context.Context, tag string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if queue, ok := r.tags[tag]; ok {
+		delete(r.consumers, queue)
+		delete(r.tags, tag)
+	}
+	return nil
+}
+func (r *Rabbit) Close(ctx context.Context) error { return nil }
+
+// Published returns a copy of every message sent through Producer
+func (r *Rabbit) Published() []Published {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Published(nil), r.published...)
+}
+
+// Enqueue stores a message to be returned by GetMessage
+func (r *Rabbit) Enqueue(queue string, msg amqp.Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages[queue] = append(r.messages[queue], msg)
+}
+
+// Deliver synchronously calls every callback registered for the queue
+func (r *Rabbit) Deliver(queue string, msg amqp.Delivery) {
+	r.mu.Lock()
+	callbacks := append([]func(msg *amqp.Delivery){}, r.consumers[queue]...)
+	r.mu.Unlock()
+
+	for _, callback := range callbacks {
+		m := acknowledged(msg)
+		callback(&m)
+	}
+}
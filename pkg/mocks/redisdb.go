@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is an in-memory redisdb.RedisClientInterface honoring TTLs
+type Redis struct {
+	mu    sync.Mutex
+	items map[string]redisItem
+}
+
+type redisItem struct {
+	data    []byte
+	expires time.Time
+}
+
+func NewRedis() *Redis {
+	return &Redis{items: map[string]redisItem{}}
+}
+
+func (r *Redis) Start(ctx context.Context) error { return nil }
+
+func (r *Redis) ReadData(ctx context.Context, key string) ([]byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.items[key]
+	if !ok || time.Now().After(item.expires) {
+		delete(r.items, key)
+		return nil, cerrors.Wrap(cerrors.NotFound, "redisdb.ReadData", redis.Nil)
+	}
+	return item.data, nil
+}
+
+func (r *Redis) SaveData(ctx context.Context, key string, data []byte, timer time.Duration) bool {
+	if timer <= 0 {
+		timer = 15 * time.Minute
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[key] = redisItem{data: append([]byte(nil), data...), expires: time.Now().Add(timer)}
+	return true
+}
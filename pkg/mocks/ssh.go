@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/ssh"
+)
+
+// SSHCommand is a command run through the SSH double
+type SSHCommand struct {
+	Host string
+	Cmd  string
+}
+
+// SSH is an ssh.SSHInterface recording commands and keeping uploaded files in memory.
+// Results maps a command to its Result, unknown commands exit with 0 and no output.
+type SSH struct {
+	mu       sync.Mutex
+	Results  map[string]*ssh.Result
+	Commands []SSHCommand
+	Files    map[string][]byte // "host:path"
+}
+
+func NewSSH() *SSH {
+	return &SSH{Results: map[string]*ssh.Result{}, Files: map[string][]byte{}}
+}
+
+func (s *SSH) Run(ctx context.Context, host, cmd string) (*ssh.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Commands = append(s.Commands, SSHCommand{Host: host, Cmd: cmd})
+	if res, ok := s.Results[cmd]; ok {
+		return res, nil
+	}
+	return &ssh.Result{}, nil
+}
+
+func (s *SSH) Upload(ctx context.Context, host, remotePath string, r io.Reader, mode os.FileMode) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Files[host+":"+remotePath] = data
+	return nil
+}
+
+func (s *SSH) Download(ctx context.Context, host, remotePath string, w io.Writer) error {
+	s.mu.Lock()
+	data, ok := s.Files[host+":"+remotePath]
+	s.mu.Unlock()
+
+	if !ok {
+		return cerrors.New(cerrors.NotFound, "ssh: no such file "+remotePath)
+	}
+	_, err := io.Copy(w, bytes.NewReader(data))
+	return err
+}
+
+func (s *SSH) Close() error { return nil }
@@ -0,0 +1,309 @@
+// Package openapi serves an embedded OpenAPI 3 spec, validates incoming requests
+// against it and writes RFC 7807 problem+json errors from the core error kinds:
+//
+//	//go:embed openapi.json
+//	var specJSON []byte
+//
+//	spec, err := openapi.Load(specJSON)
+//	r.Handle("/openapi.json", spec.Handler())
+//	r.Use(spec.Middleware())
+//
+// Only JSON specs are supported. JSON request bodies are validated with the structural
+// subset of JSON Schema of schemaregistry.JSONValidator, their schemas are compiled by Load.
+// Paths the spec doesn't describe, such as the spec itself or health checks, are passed
+// through to the router.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/schemaregistry"
+	"github.com/gorilla/mux"
+)
+
+const DEFAULT_MAX_BODY = 1 << 20 // 1MB, the JSON bodies validated are read in memory
+
+type Parameter struct {
+	Name     string          `json:"name"`
+	In       string          `json:"in"` // "query" | "header" | "path" | "cookie"
+	Required bool            `json:"required"`
+	Schema   json.RawMessage `json:"schema,omitempty"`
+}
+
+type MediaType struct {
+	Schema json.RawMessage `json:"schema,omitempty"`
+
+	compiled *schemaregistry.CompiledJSON
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []Parameter  `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type PathItem struct {
+	Parameters []Parameter           `json:"parameters"`
+	Operations map[string]*Operation `json:"-"` // by upper case method
+}
+
+func (pi *PathItem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	pi.Operations = map[string]*Operation{}
+	for key, value := range raw {
+		switch key {
+		case "parameters":
+			if err := json.Unmarshal(value, &pi.Parameters); err != nil {
+				return err
+			}
+		case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+			op := &Operation{}
+			if err := json.Unmarshal(value, op); err != nil {
+				return err
+			}
+			pi.Operations[strings.ToUpper(key)] = op
+		}
+	}
+	return nil
+}
+
+type Spec struct {
+	OpenAPI    string                     `json:"openapi"`
+	Paths      map[string]*PathItem       `json:"paths"`
+	Components map[string]json.RawMessage `json:"components"`
+
+	raw     []byte
+	routes  []route
+	maxBody int64
+}
+
+type route struct {
+	template string
+	segments []string
+	item     *PathItem
+}
+
+// Load parses a JSON OpenAPI 3 spec
+func Load(data []byte) (*Spec, error) {
+	s := &Spec{raw: data, maxBody: DEFAULT_MAX_BODY}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "openapi.Load", err)
+	}
+	if !strings.HasPrefix(s.OpenAPI, "3.") {
+		return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("openapi: unsupported version %q", s.OpenAPI))
+	}
+
+	var schemas map[string]json.RawMessage
+	if raw, ok := s.Components["schemas"]; ok {
+		if err := json.Unmarshal(raw, &schemas); err != nil {
+			return nil, cerrors.Wrap(cerrors.Invalid, "openapi.Load", err)
+		}
+	}
+	refs := make(map[string]json.RawMessage, len(schemas))
+	for name, schema := range schemas {
+		refs["#/components/schemas/"+name] = schema
+	}
+
+	for template, item := range s.Paths {
+		for _, op := range item.Operations {
+			if op.RequestBody == nil {
+				continue
+			}
+			for contentType, media := range op.RequestBody.Content {
+				if len(media.Schema) == 0 || !strings.Contains(contentType, "json") {
+					continue
+				}
+				compiled, err := schemaregistry.CompileJSON(media.Schema, refs)
+				if err != nil {
+					return nil, err
+				}
+				media.compiled = compiled
+				op.RequestBody.Content[contentType] = media
+			}
+		}
+		s.routes = append(s.routes, route{template: template, segments: split(template), item: item})
+	}
+	return s, nil
+}
+
+// SetMaxBody changes the max size of the JSON bodies validated, DEFAULT_MAX_BODY by default
+func (s *Spec) SetMaxBody(n int64) {
+	s.maxBody = n
+}
+
+// Handler serves the spec as is
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.raw)
+	})
+}
+
+// Middleware rejects with problem+json the requests with a method the spec doesn't
+// describe for their path (405), missing required parameters or with an invalid JSON
+// body (400). Requests to paths not in the spec are passed through.
+func (s *Spec) Middleware() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if item, _ := s.match(r.URL.Path); item == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err := s.Validate(r); err != nil {
+				WriteProblem(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Validate checks r against the spec. JSON bodies are read and replaced, so handlers can
+// read them again, other bodies are left unread.
+func (s *Spec) Validate(r *http.Request) error {
+	item, pathParams := s.match(r.URL.Path)
+	if item == nil {
+		return cerrors.New(cerrors.NotFound, "openapi: path not found")
+	}
+
+	op, ok := item.Operations[r.Method]
+	if !ok {
+		if r.Method == http.MethodOptions || r.Method == http.MethodHead {
+			return nil
+		}
+		return &methodNotAllowed{method: r.Method}
+	}
+
+	for _, p := range append(append([]Parameter(nil), item.Parameters...), op.Parameters...) {
+		if !p.Required {
+			continue
+		}
+		present := false
+		switch p.In {
+		case "query":
+			present = r.URL.Query().Has(p.Name)
+		case "header":
+			present = r.Header.Get(p.Name) != ""
+		case "path":
+			present = pathParams[p.Name] != ""
+		case "cookie":
+			_, err := r.Cookie(p.Name)
+			present = err == nil
+		}
+		if !present {
+			return cerrors.New(cerrors.Invalid, fmt.Sprintf("openapi: missing required %s parameter %q", p.In, p.Name))
+		}
+	}
+
+	if op.RequestBody == nil {
+		return nil
+	}
+	return s.validateBody(r, op.RequestBody)
+}
+
+func (s *Spec) validateBody(r *http.Request, rb *RequestBody) error {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		if rb.Required {
+			return cerrors.New(cerrors.Invalid, "openapi: request body is required")
+		}
+		return nil
+	}
+
+	contentType := strings.TrimSpace(strings.Split(r.Header.Get("Content-Type"), ";")[0])
+	media, ok := rb.Content[contentType]
+	if !ok {
+		return cerrors.New(cerrors.Invalid, fmt.Sprintf("openapi: unsupported content type %q", contentType))
+	}
+	if media.compiled == nil {
+		// not JSON or without schema, ex: a streamed upload
+		return nil
+	}
+
+	if r.ContentLength > s.maxBody {
+		return cerrors.New(cerrors.Invalid, "openapi: request body too large")
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, s.maxBody+1))
+	r.Body.Close()
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "openapi: reading body", err)
+	}
+	if int64(len(body)) > s.maxBody {
+		return cerrors.New(cerrors.Invalid, "openapi: request body too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(body) == 0 {
+		if rb.Required {
+			return cerrors.New(cerrors.Invalid, "openapi: request body is required")
+		}
+		return nil
+	}
+	return media.compiled.Validate(body)
+}
+
+// match finds the PathItem of path, preferring literal segments over templated ones
+func (s *Spec) match(path string) (*PathItem, map[string]string) {
+	segments := split(path)
+
+	var (
+		best       *PathItem
+		bestParams map[string]string
+		bestScore  = -1
+	)
+	for _, rt := range s.routes {
+		if len(rt.segments) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		score := 0
+		matched := true
+		for i, seg := range rt.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				params[seg[1:len(seg)-1]] = segments[i]
+				continue
+			}
+			if seg != segments[i] {
+				matched = false
+				break
+			}
+			score++
+		}
+		if matched && score > bestScore {
+			best, bestParams, bestScore = rt.item, params, score
+		}
+	}
+	return best, bestParams
+}
+
+func split(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// methodNotAllowed has no core error kind, Problem maps it to 405
+type methodNotAllowed struct {
+	method string
+}
+
+func (e *methodNotAllowed) Error() string {
+	return fmt.Sprintf("openapi: method %s not allowed", e.method)
+}
@@ -0,0 +1,61 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/i18n"
+)
+
+const PROBLEM_CONTENT_TYPE = "application/problem+json"
+
+// Problem is an RFC 7807 error response
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewProblem builds the Problem of err. The status comes from the kind of err and
+// the detail from its message, except for unknown errors whose message may leak internals.
+// The title is the "errors.{kind}" message of the default i18n Bundle when there is one.
+func NewProblem(r *http.Request, err error) *Problem {
+	status := cerrors.HTTPStatus(err)
+	p := &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Instance:  r.URL.Path,
+		RequestID: ctxutil.RequestID(r.Context()),
+	}
+
+	if kind := cerrors.KindOf(err); kind != cerrors.Unknown {
+		p.Kind = kind.String()
+		p.Detail = err.Error()
+		// translated with the default i18n Bundle, in the locale of the request
+		p.Title = i18n.Text(r.Context(), "errors."+p.Kind, p.Title)
+	}
+
+	var mna *methodNotAllowed
+	if errors.As(err, &mna) {
+		p.Status = http.StatusMethodNotAllowed
+		p.Title = http.StatusText(p.Status)
+		p.Detail = err.Error()
+	}
+	return p
+}
+
+// WriteProblem writes err as an application/problem+json response
+func WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	p := NewProblem(r, err)
+	w.Header().Set("Content-Type", PROBLEM_CONTENT_TYPE)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
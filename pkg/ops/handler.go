@@ -0,0 +1,81 @@
+package ops
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	"github.com/gorilla/mux"
+)
+
+// RegisterHandlers exposes the ops commands as admin endpoints under prefix, ex: "/admin".
+// Protect the router with authentication before exposing it.
+//
+//	GET  {prefix}/topology/diff
+//	POST {prefix}/topology/declare
+//	POST {prefix}/queues/{name}/purge
+//	POST {prefix}/dlq/{name}/replay?exchange=&key=&limit=
+//
+// The replay answers 400 when the exchange, or the queue of key on the default
+// exchange, doesn't exist.
+func RegisterHandlers(r *mux.Router, prefix string, o OpsInterface, t *rabbitmq.Topology) {
+	s := r.PathPrefix(prefix).Subrouter()
+
+	s.HandleFunc("/topology/diff", func(w http.ResponseWriter, r *http.Request) {
+		diff, err := o.DiffTopology(t)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, diff)
+	}).Methods(http.MethodGet)
+
+	s.HandleFunc("/topology/declare", func(w http.ResponseWriter, r *http.Request) {
+		if errs := o.DeclareTopology(t); len(errs) > 0 {
+			msgs := make([]string, 0, len(errs))
+			for _, err := range errs {
+				msgs = append(msgs, err.Error())
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string][]string{"errors": msgs})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodPost)
+
+	s.HandleFunc("/queues/{name}/purge", func(w http.ResponseWriter, r *http.Request) {
+		count, err := o.PurgeQueue(mux.Vars(r)["name"])
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"purged": count})
+	}).Methods(http.MethodPost)
+
+	s.HandleFunc("/dlq/{name}/replay", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		limit, err := strconv.Atoi(q.Get("limit"))
+		if q.Get("limit") != "" && (err != nil || limit < 0) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		pc := &rabbitmq.ProducerConfig{
+			Exchange: q.Get("exchange"),
+			Key:      q.Get("key"),
+		}
+
+		count, err := o.ReplayDLQ(r.Context(), mux.Vars(r)["name"], pc, limit)
+		if err != nil {
+			writeJSON(w, cerrors.HTTPStatus(err), map[string]interface{}{"replayed": count, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{"replayed": count})
+	}).Methods(http.MethodPost)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
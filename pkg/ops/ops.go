@@ -0,0 +1,160 @@
+package ops
+
+import (
+	"context"
+	"log"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+type OpsInterface interface {
+	// DeclareTopology declares every Exchange, Queue and Bind of the Topology
+	DeclareTopology(t *rabbitmq.Topology) []error
+	// DiffTopology reports the Exchanges and Queues of the Topology missing in RabbitMQ
+	DiffTopology(t *rabbitmq.Topology) (*TopologyDiff, error)
+	// PurgeQueue removes every ready message from a Queue
+	PurgeQueue(name string) (int, error)
+	// ReplayDLQ moves up to limit messages (the ones in the Queue when it starts if
+	// limit <= 0) from a dead letter Queue back to the Exchange and routing key of
+	// ProducerConfig, keeping their headers and properties. A missing Exchange, or a
+	// missing Queue for the default Exchange, returns an error of kind Invalid.
+	ReplayDLQ(ctx context.Context, dlq string, pc *rabbitmq.ProducerConfig, limit int) (int, error)
+}
+
+type TopologyDiff struct {
+	MissingExchanges []string `json:"missing_exchanges"`
+	MissingQueues    []string `json:"missing_queues"`
+}
+
+// InSync reports whether nothing is missing
+func (td *TopologyDiff) InSync() bool {
+	return len(td.MissingExchanges) == 0 && len(td.MissingQueues) == 0
+}
+
+type ops struct {
+	rbm   rabbitmq.RabbitInterface
+	admin rabbitmq.AdminInterface
+}
+
+func New(rbm rabbitmq.RabbitInterface) OpsInterface {
+	return &ops{rbm: rbm, admin: rabbitmq.Admin(rbm)}
+}
+
+func (o *ops) DeclareTopology(t *rabbitmq.Topology) []error {
+	return o.rbm.CompleteDeclare(t.Queues, t.Exchanges)
+}
+
+func (o *ops) DiffTopology(t *rabbitmq.Topology) (*TopologyDiff, error) {
+	diff := &TopologyDiff{}
+
+	for _, exchange := range t.Exchanges {
+		ok, err := o.admin.ExchangeExists(exchange.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			diff.MissingExchanges = append(diff.MissingExchanges, exchange.Name)
+		}
+	}
+
+	for _, queue := range t.Queues {
+		if _, err := o.admin.InspectQueue(queue.Name); err != nil {
+			if cerrors.Is(err, cerrors.NotFound) {
+				diff.MissingQueues = append(diff.MissingQueues, queue.Name)
+				continue
+			}
+			return nil, err
+		}
+	}
+
+	return diff, nil
+}
+
+func (o *ops) PurgeQueue(name string) (int, error) {
+	return o.admin.PurgeQueue(name)
+}
+
+func (o *ops) ReplayDLQ(ctx context.Context, dlq string, pc *rabbitmq.ProducerConfig, limit int) (int, error) {
+	if err := o.validRoute(pc); err != nil {
+		return 0, err
+	}
+
+	// bounded by the messages present now, replayed messages dead-lettered again
+	// would otherwise be replayed forever
+	q, err := o.admin.InspectQueue(dlq)
+	if err != nil {
+		return 0, err
+	}
+	if limit <= 0 || limit > q.Messages {
+		limit = q.Messages
+	}
+
+	count := 0
+	for count < limit {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+
+		msg, ok, err := o.admin.GetMessage(dlq)
+		if err != nil {
+			return count, err
+		}
+		if !ok {
+			break
+		}
+
+		if err := o.rbm.Producer(ctx, pc, &rabbitmq.Message{
+			Data:            msg.Body,
+			ContentType:     msg.ContentType,
+			MessageID:       msg.MessageId,
+			CorrelationID:   msg.CorrelationId,
+			Type:            msg.Type,
+			AppID:           msg.AppId,
+			Timestamp:       msg.Timestamp,
+			Headers:         msg.Headers,
+			DeliveryMode:    msg.DeliveryMode,
+			Priority:        msg.Priority,
+			ContentEncoding: msg.ContentEncoding,
+			ReplyTo:         msg.ReplyTo,
+			Expiration:      msg.Expiration,
+		}); err != nil {
+			msg.Nack(false, true)
+			return count, err
+		}
+
+		if err := msg.Ack(false); err != nil {
+			log.Println("Erro to Ack replayed message in RabbitMQ")
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// validRoute checks that the Exchange of pc exists, or the Queue named by its key for
+// the default Exchange
+func (o *ops) validRoute(pc *rabbitmq.ProducerConfig) error {
+	if pc.Exchange == "" {
+		if pc.Key == "" {
+			return cerrors.New(cerrors.Invalid, "ops: exchange or key is required")
+		}
+		if _, err := o.admin.InspectQueue(pc.Key); err != nil {
+			if cerrors.Is(err, cerrors.NotFound) {
+				return cerrors.New(cerrors.Invalid, "ops: queue not found "+pc.Key)
+			}
+			return err
+		}
+		return nil
+	}
+
+	ok, err := o.admin.ExchangeExists(pc.Exchange)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return cerrors.New(cerrors.Invalid, "ops: exchange not found "+pc.Exchange)
+	}
+	return nil
+}
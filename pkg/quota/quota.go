@@ -0,0 +1,187 @@
+// Package quota tracks the bytes stored per tenant in a kv.Store and enforces
+// per-plan limits, rejecting or only flagging the uploads beyond them.
+//
+//	q := quota.New(kv.NewRedis(rdb), quota.Config{Limit: planLimit})
+//	up = quota.NewUploader(up, q)
+//
+// Usage is updated atomically when the store is a kv.Counter, ex: Redis. With other
+// stores updates are serialized per instance only: concurrent uploads of a tenant on
+// different instances may overshoot the limit by the size of those uploads.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	csync "github.com/faelp22/go-commons-libs/core/sync"
+	"github.com/faelp22/go-commons-libs/pkg/kv"
+)
+
+const keyPrefix = "quota:"
+
+type Mode int
+
+const (
+	// Reject fails the operations beyond the limit with an error of kind Throttled
+	Reject Mode = iota
+	// Flag lets the operations through and calls OnExceeded
+	Flag
+)
+
+type Config struct {
+	// Limit returns the bytes allowed for a tenant, <= 0 is unlimited
+	Limit func(ctx context.Context, tenant string) (int64, error)
+	Mode  Mode
+	// OnExceeded is called when a tenant goes beyond its limit: for every rejected Add in
+	// Reject mode, once by the Add crossing the limit in Flag mode
+	OnExceeded func(ctx context.Context, tenant string, usage, limit int64)
+}
+
+type Quota struct {
+	store kv.Store
+	conf  Config
+	locks *csync.KeyedMutex
+}
+
+func New(store kv.Store, conf Config) *Quota {
+	return &Quota{store: store, conf: conf, locks: csync.NewKeyedMutex()}
+}
+
+// Usage returns the bytes accounted to tenant
+func (q *Quota) Usage(ctx context.Context, tenant string) (int64, error) {
+	// read through the counter, as kv.NewEncrypted doesn't encrypt counters
+	if counter, ok := q.store.(kv.Counter); ok {
+		return counter.Incr(ctx, keyPrefix+tenant, 0)
+	}
+
+	data, err := q.store.Get(ctx, keyPrefix+tenant)
+	if cerrors.Is(err, cerrors.NotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	usage, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, cerrors.Wrap(cerrors.Invalid, "quota.Usage", err)
+	}
+	return usage, nil
+}
+
+// Check reports, without accounting it, whether tenant can store n more bytes
+func (q *Quota) Check(ctx context.Context, tenant string, n int64) error {
+	usage, err := q.Usage(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	_, err = q.check(ctx, tenant, usage+n)
+	return err
+}
+
+// Add accounts n bytes to tenant. In Reject mode nothing is accounted when the
+// new usage would be beyond the limit. Negative n releases bytes.
+func (q *Quota) Add(ctx context.Context, tenant string, n int64) error {
+	if counter, ok := q.store.(kv.Counter); ok {
+		return q.incr(ctx, counter, tenant, n)
+	}
+
+	unlock := q.locks.Lock(tenant)
+	defer unlock()
+
+	usage, err := q.Usage(ctx, tenant)
+	if err != nil {
+		return err
+	}
+
+	before := usage
+	usage += n
+	if usage < 0 {
+		usage = 0
+	}
+	if n > 0 {
+		if err := q.exceeded(ctx, tenant, before, usage); err != nil {
+			return err
+		}
+	}
+
+	return q.store.Set(ctx, keyPrefix+tenant, []byte(strconv.FormatInt(usage, 10)), 0)
+}
+
+// incr accounts n bytes with the atomic counter of the store, taking them back
+// when they are rejected
+func (q *Quota) incr(ctx context.Context, counter kv.Counter, tenant string, n int64) error {
+	usage, err := counter.Incr(ctx, keyPrefix+tenant, n)
+	if err != nil {
+		return err
+	}
+
+	if usage < 0 {
+		// released more than accounted, ex: after a Reset
+		_, err := counter.Incr(ctx, keyPrefix+tenant, -usage)
+		return err
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	if err := q.exceeded(ctx, tenant, usage-n, usage); err != nil {
+		if _, rerr := counter.Incr(ctx, keyPrefix+tenant, -n); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Reset sets the usage of tenant, ex: after recounting from the storage
+func (q *Quota) Reset(ctx context.Context, tenant string, usage int64) error {
+	unlock := q.locks.Lock(tenant)
+	defer unlock()
+
+	if counter, ok := q.store.(kv.Counter); ok {
+		// the bytes accounted meanwhile by other instances are kept
+		current, err := counter.Incr(ctx, keyPrefix+tenant, 0)
+		if err != nil {
+			return err
+		}
+		_, err = counter.Incr(ctx, keyPrefix+tenant, usage-current)
+		return err
+	}
+	return q.store.Set(ctx, keyPrefix+tenant, []byte(strconv.FormatInt(usage, 10)), 0)
+}
+
+// exceeded checks the usage of tenant going from before to after, calling OnExceeded
+func (q *Quota) exceeded(ctx context.Context, tenant string, before, after int64) error {
+	limit, err := q.check(ctx, tenant, after)
+	if limit <= 0 || after <= limit {
+		return err
+	}
+
+	// in Flag mode the usage stays beyond the limit, only the Add crossing it reports it
+	if q.conf.OnExceeded != nil && (err != nil || before <= limit) {
+		q.conf.OnExceeded(ctx, tenant, after, limit)
+	}
+	return err
+}
+
+// check returns the limit of tenant and an error of kind Throttled, in Reject mode,
+// when usage is beyond it
+func (q *Quota) check(ctx context.Context, tenant string, usage int64) (int64, error) {
+	if q.conf.Limit == nil {
+		return 0, nil
+	}
+
+	limit, err := q.conf.Limit(ctx, tenant)
+	if err != nil || limit <= 0 || usage <= limit {
+		return 0, err
+	}
+
+	if q.conf.Mode == Flag {
+		return limit, nil
+	}
+	return limit, cerrors.New(cerrors.Throttled, fmt.Sprintf("quota: tenant %s would use %d of %d bytes", tenant, usage, limit))
+}
@@ -0,0 +1,56 @@
+package quota
+
+import (
+	"context"
+	"io"
+
+	"github.com/faelp22/go-commons-libs/core/tenancy"
+	"github.com/faelp22/go-commons-libs/pkg/httpupload"
+)
+
+type quotaUploader struct {
+	httpupload.Uploader
+	q *Quota
+}
+
+// NewUploader wraps up so uploads are accounted to the tenant of the context (see tenancy).
+// The size is only known at the end, so an upload beyond the limit is deleted afterwards
+// in Reject mode. Uploads without tenant are rejected. Deletes don't know the size
+// of the file, release it with Add and a negative size.
+func NewUploader(up httpupload.Uploader, q *Quota) httpupload.Uploader {
+	return &quotaUploader{Uploader: up, q: q}
+}
+
+func (qu *quotaUploader) Upload(ctx context.Context, name string, r io.Reader, contentType string) error {
+	tenant, err := tenancy.FromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	// fail fast when the tenant is already beyond its limit
+	if err := qu.q.Check(ctx, tenant, 0); err != nil {
+		return err
+	}
+
+	counter := &countingReader{r: r}
+	if err := qu.Uploader.Upload(ctx, name, counter, contentType); err != nil {
+		return err
+	}
+
+	if err := qu.q.Add(ctx, tenant, counter.n); err != nil {
+		qu.Uploader.Delete(ctx, name)
+		return err
+	}
+	return nil
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
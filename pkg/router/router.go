@@ -0,0 +1,298 @@
+// Package router consumes a queue and republishes every message to the exchange and
+// routing key of the first matching rule, declared over headers and JSON payload fields.
+//
+//	{
+//	  "rules": [
+//	    {"name": "br-orders", "when": [{"field": "address.country", "equals": "BR"}], "exchange": "orders.br"},
+//	    {"name": "priority", "when": [{"header": "x-priority", "in": ["high", "urgent"]}], "exchange": "orders", "key": "orders.priority"}
+//	  ],
+//	  "default": {"exchange": "orders", "key": "orders.other"}
+//	}
+//
+// Field conditions take dotted paths of object keys, arrays can't be indexed.
+// Every routed message carries the x-router-hops header, messages routed more than
+// MaxHops times, ex: by routers feeding each other, are dead-lettered.
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/hooks"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+const (
+	DEFAULT_ROUTE    = "default"
+	DEFAULT_MAX_HOPS = 10
+
+	HEADER_HOPS = "x-router-hops"
+)
+
+// Condition matches a header or a dotted path of the JSON body. Values are compared as
+// strings, so 1 matches "1". Without Equals and In the value only has to exist.
+type Condition struct {
+	Header string        `json:"header,omitempty"`
+	Field  string        `json:"field,omitempty"` // dotted path of object keys, ex: "customer.address.country"
+	Equals interface{}   `json:"equals,omitempty"`
+	In     []interface{} `json:"in,omitempty"`
+	Not    bool          `json:"not,omitempty"` // negates the condition
+}
+
+type Route struct {
+	Exchange string `json:"exchange"`
+	Key      string `json:"key,omitempty"` // keeps the original routing key when empty
+}
+
+// Rule routes the messages matching every condition of When
+type Rule struct {
+	Name string      `json:"name"`
+	When []Condition `json:"when"`
+	Route
+}
+
+type Rules struct {
+	Queue    string `json:"queue"`
+	Consumer string `json:"consumer,omitempty"`
+	Rules    []Rule `json:"rules"`
+	// Default receives the messages matching no rule, they are dead-lettered when nil
+	Default *Route `json:"default,omitempty"`
+	// MaxHops is how many routers a message can go through, DEFAULT_MAX_HOPS when <= 0
+	MaxHops int `json:"max_hops,omitempty"`
+}
+
+// LoadRules reads Rules from a JSON file
+func LoadRules(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "router.LoadRules", err)
+	}
+
+	rules := &Rules{}
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "router.LoadRules", err)
+	}
+	return rules, nil
+}
+
+type Router struct {
+	rbm   rabbitmq.RabbitInterface
+	rules Rules
+	mu    sync.Mutex
+	stats map[string]uint64
+}
+
+func New(rbm rabbitmq.RabbitInterface, rules Rules) (*Router, error) {
+	if rules.Queue == "" {
+		return nil, cerrors.New(cerrors.Invalid, "router: queue is required")
+	}
+	for i, rule := range rules.Rules {
+		if rule.Name == "" || rule.Exchange == "" && rule.Key == "" {
+			return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("router: rule %d needs a name and a route", i))
+		}
+		if rule.Route.selfRoute(rules.Queue) {
+			return nil, cerrors.New(cerrors.Invalid, fmt.Sprintf("router: rule %s routes back to %s", rule.Name, rules.Queue))
+		}
+	}
+	if rules.Default != nil && rules.Default.selfRoute(rules.Queue) {
+		return nil, cerrors.New(cerrors.Invalid, "router: default route routes back to "+rules.Queue)
+	}
+	if rules.MaxHops <= 0 {
+		rules.MaxHops = DEFAULT_MAX_HOPS
+	}
+	return &Router{rbm: rbm, rules: rules, stats: map[string]uint64{}}, nil
+}
+
+// selfRoute reports whether the route publishes straight back to queue through the
+// default exchange, routes back through other exchanges are stopped by MaxHops
+func (rt Route) selfRoute(queue string) bool {
+	return rt.Exchange == "" && rt.Key == queue
+}
+
+// Start consumes the queue, registering the consumer again after reconnects, until ctx
+// is done. Messages are acked once the broker confirmed their copy, and dead-lettered
+// when no queue is bound for the route.
+func (r *Router) Start(ctx context.Context) error {
+	return rabbitmq.NewRegistry(r.rbm).Register(&rabbitmq.ConsumerConfig{
+		Queue:    r.rules.Queue,
+		Consumer: r.rules.Consumer,
+	}, func(msg *amqp.Delivery) {
+		r.handle(ctx, msg)
+	}).StartAll(ctx)
+}
+
+// Match returns the name and Route of the first rule matching msg, or false
+func (r *Router) Match(msg *amqp.Delivery) (string, Route, bool) {
+	var body interface{}
+	parsed := false
+
+	for _, rule := range r.rules.Rules {
+		matched := true
+		for _, c := range rule.When {
+			if c.Field != "" && !parsed {
+				// bodies that aren't JSON never match field conditions
+				json.Unmarshal(msg.Body, &body)
+				parsed = true
+			}
+			if !c.matches(msg.Headers, body) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return rule.Name, rule.Route, true
+		}
+	}
+
+	if r.rules.Default != nil {
+		return DEFAULT_ROUTE, *r.rules.Default, true
+	}
+	return "", Route{}, false
+}
+
+// Stats returns how many messages each rule routed
+func (r *Router) Stats() map[string]uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make(map[string]uint64, len(r.stats))
+	for k, v := range r.stats {
+		out[k] = v
+	}
+	return out
+}
+
+func (r *Router) handle(ctx context.Context, msg *amqp.Delivery) {
+	name, route, ok := r.Match(msg)
+	if !ok {
+		log.Println("No route for message, rejecting it")
+		msg.Nack(false, false)
+		return
+	}
+
+	key := route.Key
+	if key == "" {
+		key = msg.RoutingKey
+	}
+
+	hops := hopCount(msg.Headers[HEADER_HOPS])
+	if hops >= r.rules.MaxHops {
+		log.Println("Erro to route message, too many hops:", hops)
+		msg.Nack(false, false)
+		return
+	}
+	headers := amqp.Table{}
+	for k, v := range msg.Headers {
+		headers[k] = v
+	}
+	headers[HEADER_HOPS] = int32(hops + 1)
+
+	var err error
+	end := hooks.Begin(ctx, "router", "Route", map[string]interface{}{
+		"rule": name, "exchange": route.Exchange, "key": key,
+	})
+	defer func() { end(err) }()
+
+	// mandatory, so a route with no queue bound fails instead of dropping the message
+	err = r.rbm.Producer(ctx, &rabbitmq.ProducerConfig{Exchange: route.Exchange, Key: key, Mandatory: true, Confirm: true}, &rabbitmq.Message{
+		Data:          msg.Body,
+		ContentType:   msg.ContentType,
+		MessageID:     msg.MessageId,
+		CorrelationID: msg.CorrelationId,
+		Type:          msg.Type,
+		AppID:         msg.AppId,
+		Timestamp:     msg.Timestamp,
+		Headers:       headers,
+	})
+	if err != nil {
+		log.Println("Erro to route message in RabbitMQ:", err.Error())
+		msg.Nack(false, cerrors.Is(err, cerrors.Unavailable))
+		return
+	}
+
+	r.mu.Lock()
+	r.stats[name]++
+	r.mu.Unlock()
+
+	msg.Ack(false)
+}
+
+func (c Condition) matches(headers amqp.Table, body interface{}) bool {
+	var (
+		value interface{}
+		found bool
+	)
+	if c.Header != "" {
+		value, found = headers[c.Header]
+	} else {
+		value, found = lookup(body, strings.Split(c.Field, "."))
+	}
+
+	matched := found
+	if found && c.Equals != nil {
+		matched = equal(value, c.Equals)
+	}
+	if found && len(c.In) > 0 {
+		matched = false
+		for _, v := range c.In {
+			if equal(value, v) {
+				matched = true
+				break
+			}
+		}
+	}
+
+	if c.Not {
+		return !matched
+	}
+	return matched
+}
+
+// hopCount reads the hops header, whose integer type depends on the publisher
+func hopCount(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+func lookup(doc interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if doc, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func equal(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
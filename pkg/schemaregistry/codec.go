@@ -0,0 +1,91 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/faelp22/go-commons-libs/core/envelope"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Upcaster migrates a payload from version v to v+1
+type Upcaster func(payload json.RawMessage) (json.RawMessage, error)
+
+// Producer validates outgoing envelopes against the latest schema of their subject
+type Producer struct {
+	Registry  Registry
+	Validator Validator // JSONValidator when nil
+	// Subject maps an Envelope to its subject, the envelope Type when nil
+	Subject func(env *envelope.Envelope) string
+}
+
+// Prepare validates the payload and stamps env.SchemaVersion with the schema version
+func (p *Producer) Prepare(ctx context.Context, env *envelope.Envelope) error {
+	schema, err := p.Registry.GetLatest(ctx, subjectOf(p.Subject, env))
+	if err != nil {
+		return err
+	}
+
+	validator := p.Validator
+	if validator == nil {
+		validator = JSONValidator{}
+	}
+	if err := validator.Validate(schema, env.Payload); err != nil {
+		return err
+	}
+
+	env.SchemaVersion = strconv.Itoa(schema.Version)
+	return nil
+}
+
+// Consumer accepts envelopes written with schema versions up to Version,
+// upcasting older payloads with the registered Upcasters
+type Consumer struct {
+	Registry Registry
+	Subject  func(env *envelope.Envelope) string
+	// Version is the schema version the consumer code understands
+	Version int
+	// Upcasters by source version, Upcasters[1] migrates v1 to v2
+	Upcasters map[int]Upcaster
+}
+
+// Negotiate checks the writer schema of env exists and brings its payload to Version.
+// Envelopes written with a newer version are rejected with cerrors.Invalid.
+func (c *Consumer) Negotiate(ctx context.Context, env *envelope.Envelope) error {
+	version, err := strconv.Atoi(env.SchemaVersion)
+	if err != nil {
+		return cerrors.New(cerrors.Invalid, "schemaregistry: envelope without schema version")
+	}
+
+	if version > c.Version {
+		return cerrors.New(cerrors.Invalid, "schemaregistry: schema version "+env.SchemaVersion+
+			" is newer than the supported "+strconv.Itoa(c.Version))
+	}
+
+	if _, err := c.Registry.GetVersion(ctx, subjectOf(c.Subject, env), version); err != nil {
+		return err
+	}
+
+	payload := env.Payload
+	for v := version; v < c.Version; v++ {
+		upcast, ok := c.Upcasters[v]
+		if !ok {
+			return cerrors.New(cerrors.Invalid, "schemaregistry: no upcaster from version "+strconv.Itoa(v))
+		}
+		if payload, err = upcast(payload); err != nil {
+			return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Negotiate", err)
+		}
+	}
+
+	env.Payload = payload
+	env.SchemaVersion = strconv.Itoa(c.Version)
+	return nil
+}
+
+func subjectOf(subject func(env *envelope.Envelope) string, env *envelope.Envelope) string {
+	if subject != nil {
+		return subject(env)
+	}
+	return env.Type
+}
@@ -0,0 +1,170 @@
+package schemaregistry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/config"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/core/rest"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+type confluent struct {
+	baseURL string
+	rest    *rest.Client
+
+	cacheLock sync.RWMutex
+	byID      map[int]*Schema
+	byVersion map[string]*Schema
+}
+
+// NewConfluent returns a Registry client for a Confluent compatible Schema Registry.
+// Configured by SRV_SCHEMA_REGISTRY_URL, SRV_SCHEMA_REGISTRY_USER and SRV_SCHEMA_REGISTRY_PASS.
+// Schemas are immutable so lookups by ID and version are cached forever.
+func NewConfluent(conf *config.Config) (Registry, error) {
+	if conf.SchemaRegistryConfig == nil {
+		conf.SchemaRegistryConfig = &config.SchemaRegistryConfig{}
+	}
+
+	SRV_SCHEMA_REGISTRY_URL := os.Getenv("SRV_SCHEMA_REGISTRY_URL")
+	if SRV_SCHEMA_REGISTRY_URL != "" {
+		conf.SCHEMA_REGISTRY_URL = SRV_SCHEMA_REGISTRY_URL
+	}
+	if conf.SCHEMA_REGISTRY_URL == "" {
+		return nil, cerrors.New(cerrors.Invalid, "schemaregistry: SRV_SCHEMA_REGISTRY_URL is required")
+	}
+
+	SRV_SCHEMA_REGISTRY_USER := os.Getenv("SRV_SCHEMA_REGISTRY_USER")
+	if SRV_SCHEMA_REGISTRY_USER != "" {
+		conf.SCHEMA_REGISTRY_USER = SRV_SCHEMA_REGISTRY_USER
+	}
+
+	SRV_SCHEMA_REGISTRY_PASS := os.Getenv("SRV_SCHEMA_REGISTRY_PASS")
+	if SRV_SCHEMA_REGISTRY_PASS != "" {
+		conf.SCHEMA_REGISTRY_PASS = SRV_SCHEMA_REGISTRY_PASS
+	}
+
+	return &confluent{
+		baseURL: conf.SCHEMA_REGISTRY_URL,
+		rest: &rest.Client{
+			HTTP:        &http.Client{Timeout: 10 * time.Second},
+			Name:        "schemaregistry",
+			ContentType: contentType,
+			Auth:        rest.BasicAuth(conf.SCHEMA_REGISTRY_USER, conf.SCHEMA_REGISTRY_PASS),
+			Message:     message,
+		},
+		byID:      map[int]*Schema{},
+		byVersion: map[string]*Schema{},
+	}, nil
+}
+
+func (c *confluent) Register(ctx context.Context, subject, schemaType, schema string) (*Schema, error) {
+	body := map[string]string{"schema": schema}
+	if schemaType != "" && schemaType != SCHEMA_TYPE_AVRO {
+		body["schemaType"] = schemaType
+	}
+
+	var resp struct {
+		ID int `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject)+"/versions", body, &resp); err != nil {
+		return nil, err
+	}
+
+	// the register endpoint only returns the id, look the version up
+	var found Schema
+	if err := c.do(ctx, http.MethodPost, "/subjects/"+url.PathEscape(subject), body, &found); err != nil {
+		return nil, err
+	}
+	found.ID = resp.ID
+	found.SchemaType = schemaType
+
+	c.remember(&found)
+	return &found, nil
+}
+
+func (c *confluent) GetByID(ctx context.Context, id int) (*Schema, error) {
+	c.cacheLock.RLock()
+	s, ok := c.byID[id]
+	c.cacheLock.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s = &Schema{}
+	if err := c.do(ctx, http.MethodGet, "/schemas/ids/"+strconv.Itoa(id), nil, s); err != nil {
+		return nil, err
+	}
+	s.ID = id
+
+	c.cacheLock.Lock()
+	c.byID[id] = s
+	c.cacheLock.Unlock()
+
+	return s, nil
+}
+
+func (c *confluent) GetLatest(ctx context.Context, subject string) (*Schema, error) {
+	// latest moves, it is never cached
+	s := &Schema{}
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/latest", nil, s); err != nil {
+		return nil, err
+	}
+	c.remember(s)
+	return s, nil
+}
+
+func (c *confluent) GetVersion(ctx context.Context, subject string, version int) (*Schema, error) {
+	key := versionKey(subject, version)
+
+	c.cacheLock.RLock()
+	s, ok := c.byVersion[key]
+	c.cacheLock.RUnlock()
+	if ok {
+		return s, nil
+	}
+
+	s = &Schema{}
+	if err := c.do(ctx, http.MethodGet, "/subjects/"+url.PathEscape(subject)+"/versions/"+strconv.Itoa(version), nil, s); err != nil {
+		return nil, err
+	}
+	c.remember(s)
+	return s, nil
+}
+
+func (c *confluent) remember(s *Schema) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	if s.ID > 0 {
+		c.byID[s.ID] = s
+	}
+	if s.Subject != "" && s.Version > 0 {
+		c.byVersion[versionKey(s.Subject, s.Version)] = s
+	}
+}
+
+func (c *confluent) do(ctx context.Context, method, path string, in, out interface{}) error {
+	return c.rest.Do(ctx, method, c.baseURL+path, in, out)
+}
+
+// message reads the {"error_code", "message"} error bodies of the registry
+func message(body []byte) string {
+	var apiErr struct {
+		ErrorCode int    `json:"error_code"`
+		Message   string `json:"message"`
+	}
+	json.Unmarshal(body, &apiErr)
+	return strconv.Itoa(apiErr.ErrorCode) + " " + apiErr.Message
+}
+
+func versionKey(subject string, version int) string {
+	return subject + "@" + strconv.Itoa(version)
+}
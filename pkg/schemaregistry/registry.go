@@ -0,0 +1,30 @@
+// Package schemaregistry registers and resolves payload schemas, either on a
+// Confluent compatible Schema Registry or on a lightweight Store (ex: a blob
+// container), so payload evolution across teams is controlled.
+package schemaregistry
+
+import (
+	"context"
+)
+
+const (
+	SCHEMA_TYPE_JSON     = "JSON"
+	SCHEMA_TYPE_AVRO     = "AVRO"
+	SCHEMA_TYPE_PROTOBUF = "PROTOBUF"
+)
+
+type Schema struct {
+	ID         int    `json:"id"`
+	Subject    string `json:"subject,omitempty"`
+	Version    int    `json:"version,omitempty"`
+	SchemaType string `json:"schemaType,omitempty"`
+	Schema     string `json:"schema"`
+}
+
+type Registry interface {
+	// Register adds schema under subject, returning the existing Schema when already registered
+	Register(ctx context.Context, subject, schemaType, schema string) (*Schema, error)
+	GetByID(ctx context.Context, id int) (*Schema, error)
+	GetLatest(ctx context.Context, subject string) (*Schema, error)
+	GetVersion(ctx context.Context, subject string, version int) (*Schema, error)
+}
@@ -0,0 +1,153 @@
+package schemaregistry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Store keeps the schemas of the lightweight registry, usually a blob container.
+// Get must return an error of kind cerrors.NotFound for missing names.
+type Store interface {
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+type storeRegistry struct {
+	store Store
+	mu    sync.Mutex
+}
+
+// NewStoreRegistry returns a Registry persisted as JSON files in a Store:
+//
+//	subjects/{subject}/versions/{version}.json
+//	subjects/{subject}/latest.json
+//	ids/{id}.json
+//
+// Subjects are path escaped. IDs are derived from a SHA-256 of the schema, so the same
+// schema always has the same ID, the next free ID is taken on a collision.
+// Registration is serialized per process only, register schemas from a single
+// place (ex: CI) when several instances share the Store.
+func NewStoreRegistry(store Store) Registry {
+	return &storeRegistry{store: store}
+}
+
+func (sr *storeRegistry) Register(ctx context.Context, subject, schemaType, schema string) (*Schema, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	latest, err := sr.GetLatest(ctx, subject)
+	if err != nil && !cerrors.Is(err, cerrors.NotFound) {
+		return nil, err
+	}
+
+	if latest != nil {
+		// look for an already registered identical schema
+		for v := latest.Version; v > 0; v-- {
+			s, err := sr.GetVersion(ctx, subject, v)
+			if err != nil {
+				return nil, err
+			}
+			if s.Schema == schema {
+				return s, nil
+			}
+		}
+	}
+
+	id, taken, err := sr.id(ctx, schemaType, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{
+		ID:         id,
+		Subject:    subject,
+		Version:    1,
+		SchemaType: schemaType,
+		Schema:     schema,
+	}
+	if latest != nil {
+		s.Version = latest.Version + 1
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{subjectPath(subject) + "/versions/" + strconv.Itoa(s.Version) + ".json"}
+	if !taken {
+		names = append(names, "ids/"+strconv.Itoa(s.ID)+".json")
+	}
+	names = append(names, subjectPath(subject)+"/latest.json")
+
+	for _, name := range names {
+		if err := sr.store.Put(ctx, name, data, "application/json"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// id returns the ID of schema, and whether it is already registered under another
+// subject, probing the IDs following its hash until a free one or the same schema
+func (sr *storeRegistry) id(ctx context.Context, schemaType, schema string) (int, bool, error) {
+	sum := sha256.Sum256([]byte(schema))
+	id := int(binary.BigEndian.Uint32(sum[:4]) & 0x7fffffff)
+
+	for {
+		s, err := sr.GetByID(ctx, id)
+		if cerrors.Is(err, cerrors.NotFound) {
+			return id, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		if s.Schema == schema && s.SchemaType == schemaType {
+			return id, true, nil
+		}
+		id = (id + 1) & 0x7fffffff
+	}
+}
+
+// subjectPath escapes subject so it stays a single path segment, ex: "a/b" or ".."
+func subjectPath(subject string) string {
+	escaped := url.PathEscape(subject)
+	if escaped == "" || strings.Trim(escaped, ".") == "" {
+		escaped = strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return "subjects/" + escaped
+}
+
+func (sr *storeRegistry) GetByID(ctx context.Context, id int) (*Schema, error) {
+	return sr.load(ctx, "ids/"+strconv.Itoa(id)+".json")
+}
+
+func (sr *storeRegistry) GetLatest(ctx context.Context, subject string) (*Schema, error) {
+	return sr.load(ctx, subjectPath(subject)+"/latest.json")
+}
+
+func (sr *storeRegistry) GetVersion(ctx context.Context, subject string, version int) (*Schema, error) {
+	return sr.load(ctx, subjectPath(subject)+"/versions/"+strconv.Itoa(version)+".json")
+}
+
+func (sr *storeRegistry) load(ctx context.Context, name string) (*Schema, error) {
+	data, err := sr.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "schemaregistry.load", err)
+	}
+	return s, nil
+}
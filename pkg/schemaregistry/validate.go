@@ -0,0 +1,214 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+type Validator interface {
+	Validate(schema *Schema, payload []byte) error
+}
+
+// JSONValidator checks payloads against the structural subset of JSON Schema:
+// type, required, properties, items and enum. Other keywords, and "$ref" outside
+// CompileJSON, are ignored.
+// Schemas of other types are not validated.
+type JSONValidator struct{}
+
+type jsonSchema struct {
+	Type       interface{}            `json:"type"`
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Items      *jsonSchema            `json:"items"`
+	Enum       []interface{}          `json:"enum"`
+	Ref        string                 `json:"$ref"`
+
+	ref *jsonSchema // target of Ref, linked by CompileJSON
+}
+
+// CompiledJSON is a JSON schema parsed once with its "$ref"s resolved, for schemas
+// validated on every request
+type CompiledJSON struct {
+	root *jsonSchema
+}
+
+// CompileJSON parses schema and links its "$ref"s to the schemas of refs, keyed by the
+// reference as written, ex: "#/components/schemas/Order". References may be recursive.
+func CompileJSON(schema json.RawMessage, refs map[string]json.RawMessage) (*CompiledJSON, error) {
+	root := &jsonSchema{}
+	if err := json.Unmarshal(schema, root); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "schemaregistry.CompileJSON: bad schema", err)
+	}
+
+	targets := map[string]*jsonSchema{}
+	var link func(js *jsonSchema) error
+	link = func(js *jsonSchema) error {
+		if js == nil {
+			return nil
+		}
+		if js.Ref != "" && js.ref == nil {
+			target, ok := targets[js.Ref]
+			if !ok {
+				raw, found := refs[js.Ref]
+				if !found {
+					return cerrors.New(cerrors.Invalid, fmt.Sprintf("schemaregistry: unresolved reference %q", js.Ref))
+				}
+				target = &jsonSchema{}
+				if err := json.Unmarshal(raw, target); err != nil {
+					return cerrors.Wrap(cerrors.Invalid, "schemaregistry.CompileJSON: bad schema "+js.Ref, err)
+				}
+				// registered before linking, so recursive references end here
+				targets[js.Ref] = target
+				if err := link(target); err != nil {
+					return err
+				}
+			}
+			js.ref = target
+		}
+		for _, prop := range js.Properties {
+			if err := link(prop); err != nil {
+				return err
+			}
+		}
+		return link(js.Items)
+	}
+	if err := link(root); err != nil {
+		return nil, err
+	}
+	return &CompiledJSON{root: root}, nil
+}
+
+// Validate checks payload against the compiled schema
+func (c *CompiledJSON) Validate(payload []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Validate", err)
+	}
+
+	if err := c.root.validate("$", value); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Validate", err)
+	}
+	return nil
+}
+
+func (JSONValidator) Validate(schema *Schema, payload []byte) error {
+	if schema.SchemaType != "" && schema.SchemaType != SCHEMA_TYPE_JSON {
+		return nil
+	}
+
+	js := &jsonSchema{}
+	if err := json.Unmarshal([]byte(schema.Schema), js); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Validate: bad schema", err)
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Validate", err)
+	}
+
+	if err := js.validate("$", value); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "schemaregistry.Validate", err)
+	}
+	return nil
+}
+
+func (js *jsonSchema) validate(path string, value interface{}) error {
+	if js == nil {
+		return nil
+	}
+	if js.ref != nil {
+		// the keywords next to a $ref are ignored, as in OpenAPI 3.0
+		return js.ref.validate(path, value)
+	}
+
+	if js.Type != nil && !js.typeMatches(value) {
+		return fmt.Errorf("%s: expected type %v", path, js.Type)
+	}
+
+	if len(js.Enum) > 0 {
+		found := false
+		for _, option := range js.Enum {
+			// both decoded from JSON, so 1 and "1" have different types and don't match
+			if reflect.DeepEqual(option, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value not in enum", path)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range js.Required {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, prop := range js.Properties {
+			if field, ok := v[name]; ok {
+				if err := prop.validate(path+"."+name, field); err != nil {
+					return err
+				}
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := js.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (js *jsonSchema) typeMatches(value interface{}) bool {
+	var types []interface{}
+	switch t := js.Type.(type) {
+	case string:
+		types = []interface{}{t}
+	case []interface{}:
+		types = t
+	default:
+		return true
+	}
+
+	for _, t := range types {
+		switch t {
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if f, ok := value.(float64); ok && f == float64(int64(f)) {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+// Package search defines engine independent full-text search interfaces, so services
+// index and query documents the same way whatever the engine behind them.
+// Implementations: pkg/adapter/azure/cognitivesearch.
+package search
+
+import (
+	"context"
+	"encoding/json"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+// Document is an indexed document, it must hold the key field of the index
+type Document map[string]interface{}
+
+type Request struct {
+	// Text is the full-text query, "*" or empty matches every document
+	Text string
+	// Filter is an OData filter expression, ex: "tenant eq 'acme' and price lt 10"
+	Filter string
+	// Facets are the fields to count values of, ex: "category" or "price,interval:10"
+	Facets  []string
+	OrderBy []string // ex: "price desc"
+	Select  []string
+	Top     int
+	Skip    int
+	// Count asks for the total of matching documents in Result.Count
+	Count bool
+}
+
+type Hit struct {
+	Score    float64
+	Document json.RawMessage
+}
+
+// Decode unmarshals the document of the hit into v
+func (h Hit) Decode(v interface{}) error {
+	if err := json.Unmarshal(h.Document, v); err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "search.Decode", err)
+	}
+	return nil
+}
+
+type FacetValue struct {
+	Value interface{}
+	Count int64
+}
+
+type Result struct {
+	Count  int64 // -1 when Request.Count is false
+	Hits   []Hit
+	Facets map[string][]FacetValue
+}
+
+type Searcher interface {
+	// Index uploads docs, replacing the documents with the same key
+	Index(ctx context.Context, index string, docs []Document) error
+	// Search runs req against index
+	Search(ctx context.Context, index string, req Request) (*Result, error)
+	// Delete removes the documents with the given keys, missing keys are ignored
+	Delete(ctx context.Context, index string, keys []string) error
+}
+
+// Decode unmarshals every hit of r into a T
+func Decode[T any](r *Result) ([]T, error) {
+	out := make([]T, len(r.Hits))
+	for i, hit := range r.Hits {
+		if err := hit.Decode(&out[i]); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
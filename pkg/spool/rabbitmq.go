@@ -0,0 +1,30 @@
+package spool
+
+import (
+	"context"
+
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+type spoolRabbit struct {
+	rabbitmq.RabbitInterface
+	spool *Spool
+}
+
+// NewRabbitMQ wraps rbm so Producer writes to the spool instead of failing when the broker
+// is unreachable. While the spool isn't empty new messages are spooled too, keeping the order.
+func NewRabbitMQ(rbm rabbitmq.RabbitInterface, s *Spool) rabbitmq.RabbitInterface {
+	return &spoolRabbit{RabbitInterface: rbm, spool: s}
+}
+
+func (sr *spoolRabbit) Producer(ctx context.Context, pc *rabbitmq.ProducerConfig, msg *rabbitmq.Message) error {
+	if sr.spool.Len() == 0 {
+		err := sr.RabbitInterface.Producer(ctx, pc, msg)
+		if cerrors.KindOf(err) != cerrors.Unavailable {
+			return err
+		}
+	}
+
+	return sr.spool.Append(Record{Exchange: pc.Exchange, Key: pc.Key, Mandatory: pc.Mandatory, Message: *msg})
+}
@@ -0,0 +1,357 @@
+// Package spool keeps the messages published while RabbitMQ is unreachable in a
+// local durable file and republishes them in order once the broker is back, for
+// services that cannot lose events during broker maintenance.
+//
+//	sp, err := spool.Open("/var/lib/app/rabbitmq.spool")
+//	rbm = spool.NewRabbitMQ(rbm, sp)
+//	sp.Start(ctx, inner, spool.DEFAULT_INTERVAL)
+//
+// The spool is an append-only file of JSON lines synced on every write. Header
+// values go through JSON, so numbers come back as float64. With OpenEncrypted
+// every line is encrypted with core/crypto and base64 encoded.
+//
+// Records the broker refuses for good (NotFound, Invalid or Conflict, ex: a deleted
+// exchange) are moved to "{path}.dead" and lines that can't be decoded to
+// "{path}.corrupt", both in the spool format, so they never block the spool.
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/async"
+	"github.com/faelp22/go-commons-libs/core/clock"
+	"github.com/faelp22/go-commons-libs/core/crypto"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	"github.com/faelp22/go-commons-libs/pkg/adapter/rabbitmq"
+)
+
+const DEFAULT_INTERVAL = 5 * time.Second
+
+const (
+	DEAD_SUFFIX    = ".dead"
+	CORRUPT_SUFFIX = ".corrupt"
+)
+
+// aad binds the encrypted lines to the spool format, not to the file name, so a spool
+// can be renamed or moved
+var aad = []byte("go-commons-libs/spool")
+
+// Record is a spooled publish
+type Record struct {
+	Exchange  string           `json:"exchange"`
+	Key       string           `json:"key"`
+	Mandatory bool             `json:"mandatory,omitempty"`
+	Message   rabbitmq.Message `json:"message"`
+	SpooledAt time.Time        `json:"spooled_at"`
+}
+
+type Spool struct {
+	path    string
+	mu      sync.Mutex // guards the file and count
+	flushMu sync.Mutex // serializes Flush, held while publishing
+	count   int
+	clock   clock.Clock
+	cipher  crypto.Cipher
+}
+
+// Open opens the spool file at path, creating its directory when needed.
+// Records left by a previous run are kept and flushed first.
+func Open(path string) (*Spool, error) {
+	return OpenEncrypted(path, nil)
+}
+
+// OpenEncrypted opens the spool at path encrypting the records with c. Every line must
+// be encrypted, plain lines are moved to "{path}.corrupt" like any line that can't be
+// decoded, so flush a spool opened with Open before encrypting it.
+func OpenEncrypted(path string, c crypto.Cipher) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "spool.Open", err)
+	}
+
+	s := &Spool{path: path, clock: clock.New(), cipher: c}
+	lines, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	s.count = len(lines)
+	return s, nil
+}
+
+func (s *Spool) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Len returns the number of records waiting to be republished
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Append writes rec at the end of the spool and syncs the file
+func (s *Spool) Append(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec.SpooledAt.IsZero() {
+		rec.SpooledAt = s.clock.Now()
+	}
+
+	line, err := s.encode(rec)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "spool.Append", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err == nil {
+		err = f.Sync()
+	}
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "spool.Append", err)
+	}
+
+	s.count++
+	return nil
+}
+
+// Flush republishes the records in order and stops on the first transient failure,
+// keeping it and the following records for the next Flush. Records refused for good
+// are dead-lettered and skipped. It returns how many were republished.
+//
+// The spool is not locked while publishing, Append keeps working during a Flush.
+func (s *Spool) Flush(ctx context.Context, publish func(ctx context.Context, rec Record) error) (int, error) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	if s.count == 0 {
+		s.mu.Unlock()
+		return 0, nil
+	}
+	lines, err := s.read()
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	// done counts the lines removed from the spool: republished, dead-lettered or corrupt
+	sent, done := 0, 0
+	var publishErr error
+	for _, line := range lines {
+		rec, err := s.decode(line)
+		if err != nil {
+			// ex: a partial last line left by a crash, or a line encrypted with another key
+			if publishErr = s.keep(CORRUPT_SUFFIX, line); publishErr != nil {
+				break
+			}
+			log.Println("Erro to read spooled message, moving it to", s.path+CORRUPT_SUFFIX)
+			done++
+			continue
+		}
+
+		if err := publish(ctx, rec); err != nil {
+			if !permanent(err) {
+				publishErr = err
+				break
+			}
+			if publishErr = s.keep(DEAD_SUFFIX, line); publishErr != nil {
+				break
+			}
+			log.Println("Erro to republish spooled message, moving it to", s.path+DEAD_SUFFIX+":", err.Error())
+			done++
+			continue
+		}
+		sent++
+		done++
+	}
+
+	if done > 0 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// only Flush removes lines, the first done lines are still the ones published
+		current, err := s.read()
+		if err == nil {
+			err = s.rewrite(current[done:])
+		}
+		if err != nil {
+			// the published records stay in the file and will be published again
+			return sent, err
+		}
+		s.count = len(current) - done
+	}
+
+	return sent, publishErr
+}
+
+// permanent reports whether the broker refused a record for good, retrying it would
+// block the spool forever
+func permanent(err error) bool {
+	switch cerrors.KindOf(err) {
+	case cerrors.NotFound, cerrors.Invalid, cerrors.Conflict:
+		return true
+	}
+	return false
+}
+
+// Start flushes the spool to rbm every interval until ctx is done.
+// rbm must be the RabbitInterface without the spool wrapper.
+func (s *Spool) Start(ctx context.Context, rbm rabbitmq.RabbitInterface, interval time.Duration) {
+	if interval <= 0 {
+		interval = DEFAULT_INTERVAL
+	}
+
+	publish := func(ctx context.Context, rec Record) error {
+		msg := rec.Message
+		return rbm.Producer(ctx, &rabbitmq.ProducerConfig{
+			Exchange: rec.Exchange, Key: rec.Key, Mandatory: rec.Mandatory,
+		}, &msg)
+	}
+
+	async.Go(func() error {
+		ticker := s.clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C():
+				if s.Len() == 0 {
+					continue
+				}
+				sent, err := s.Flush(ctx, publish)
+				if sent > 0 {
+					log.Printf("Republished %d spooled messages in RabbitMQ", sent)
+				}
+				if err != nil {
+					log.Println("Erro to flush spool in RabbitMQ")
+					log.Println(err)
+				}
+			}
+		}
+	})
+}
+
+// read returns the lines of the spool, without decoding them
+func (s *Spool) read() ([][]byte, error) {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "spool.read", err)
+	}
+	defer f.Close()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 64<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, cerrors.Wrap(cerrors.Unavailable, "spool.read", err)
+	}
+
+	return lines, nil
+}
+
+// rewrite replaces the file with lines through a temporary file and a rename
+func (s *Spool) rewrite(lines [][]byte) error {
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "spool.rewrite", err)
+	}
+
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		if _, err = w.Write(append(line, '\n')); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return cerrors.Wrap(cerrors.Unavailable, "spool.rewrite", err)
+	}
+
+	return nil
+}
+
+// keep appends line to the side file of the spool with the given suffix
+func (s *Spool) keep(suffix string, line []byte) error {
+	f, err := os.OpenFile(s.path+suffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "spool.keep", err)
+	}
+	defer f.Close()
+
+	if _, err = f.Write(append(line, '\n')); err == nil {
+		err = f.Sync()
+	}
+	return cerrors.Wrap(cerrors.Unavailable, "spool.keep", err)
+}
+
+// encode returns the line of rec, without the newline
+func (s *Spool) encode(rec Record) ([]byte, error) {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "spool.encode", err)
+	}
+	if s.cipher == nil {
+		return line, nil
+	}
+
+	sealed, err := s.cipher.Encrypt(line, aad)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func (s *Spool) decode(line []byte) (Record, error) {
+	var rec Record
+	if s.cipher != nil {
+		sealed, err := base64.StdEncoding.DecodeString(string(line))
+		if err != nil {
+			return rec, err
+		}
+		plain, err := s.cipher.Decrypt(sealed, aad)
+		if err != nil {
+			return rec, err
+		}
+		line = plain
+	}
+	err := json.Unmarshal(line, &rec)
+	return rec, err
+}
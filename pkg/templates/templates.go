@@ -0,0 +1,356 @@
+// Package templates loads the html and text templates shared by the email and PDF
+// components from an fs.FS or a blob container, with layouts and partials:
+//
+//	layouts/base.html     {{define "base"}}<html>{{template "content" .}}</html>{{end}}
+//	partials/footer.html  {{define "footer"}}...{{end}}
+//	invoice.html          {{template "base" .}}{{define "content"}}{{t "invoice.title"}}{{end}}
+//	welcome.txt           text/template, for plain text emails
+//
+//	set, err := templates.New(templates.FSSource(embedded), templates.Config{})
+//	err = set.Render(ctx, w, "invoice.html", data)
+//
+// Files ending in .html use html/template, every other one text/template. Layouts and
+// partials are available to every page of the same kind.
+package templates
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/clock"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+)
+
+const (
+	LAYOUTS_DIR  = "layouts"
+	PARTIALS_DIR = "partials"
+)
+
+// Source lists and reads template files, names use "/" separators
+type Source interface {
+	List(ctx context.Context) ([]string, error)
+	Read(ctx context.Context, name string) ([]byte, error)
+}
+
+// Versioner is implemented by Sources that can tell cheaply whether they changed,
+// ex: the ETag of a blob manifest. Without it reloads hash every file.
+type Versioner interface {
+	Version(ctx context.Context) (string, error)
+}
+
+// Translator is the i18n hook behind the "t" template function
+type Translator interface {
+	Translate(ctx context.Context, key string, args ...interface{}) string
+}
+
+type Config struct {
+	// Funcs are added to every template
+	Funcs map[string]interface{}
+	// Translator backs {{t "key" args...}}, which returns the key when nil
+	Translator Translator
+	// Reload is the interval Start checks the Source for changes
+	Reload time.Duration
+}
+
+type fs_source struct {
+	fsys fs.FS
+}
+
+// FSSource returns a Source reading fsys, ex: an embed.FS or os.DirFS
+func FSSource(fsys fs.FS) Source {
+	return &fs_source{fsys: fsys}
+}
+
+func (fss *fs_source) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fss.fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			names = append(names, p)
+		}
+		return nil
+	})
+	return names, err
+}
+
+func (fss *fs_source) Read(ctx context.Context, name string) ([]byte, error) {
+	return fs.ReadFile(fss.fsys, name)
+}
+
+type Set struct {
+	src   Source
+	conf  Config
+	clock clock.Clock
+
+	mu      sync.RWMutex
+	html    map[string]*htmltemplate.Template
+	text    map[string]*texttemplate.Template
+	version string
+}
+
+// New loads every template of src
+func New(src Source, conf Config) (*Set, error) {
+	s := &Set{src: src, conf: conf, clock: clock.New()}
+	if err := s.Reload(context.Background()); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetClock replaces the Clock used by Start, mainly for tests
+func (s *Set) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
+// Render executes the page name with data. ctx is given to the Translator.
+func (s *Set) Render(ctx context.Context, w io.Writer, name string, data interface{}) error {
+	funcs := map[string]interface{}{"t": s.translate(ctx)}
+
+	s.mu.RLock()
+	ht, isHTML := s.html[name]
+	tt, isText := s.text[name]
+	s.mu.RUnlock()
+
+	switch {
+	case isHTML:
+		// the cached template is never executed, so it can always be cloned
+		clone, err := ht.Clone()
+		if err != nil {
+			return cerrors.Wrap(cerrors.Invalid, "templates.Render", err)
+		}
+		return wrapExec(clone.Funcs(funcs).ExecuteTemplate(w, name, data))
+	case isText:
+		clone, err := tt.Clone()
+		if err != nil {
+			return cerrors.Wrap(cerrors.Invalid, "templates.Render", err)
+		}
+		return wrapExec(clone.Funcs(funcs).ExecuteTemplate(w, name, data))
+	}
+	return cerrors.New(cerrors.NotFound, "templates: no template "+name)
+}
+
+// RenderString is Render into a string
+func (s *Set) RenderString(ctx context.Context, name string, data interface{}) (string, error) {
+	var buf bytes.Buffer
+	err := s.Render(ctx, &buf, name, data)
+	return buf.String(), err
+}
+
+// Names returns the renderable pages, sorted
+func (s *Set) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.html)+len(s.text))
+	for name := range s.html {
+		names = append(names, name)
+	}
+	for name := range s.text {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Reload parses the Source again and swaps the templates when every file parses,
+// keeping the previous ones otherwise
+func (s *Set) Reload(ctx context.Context) error {
+	// the version is taken before reading, so a change made while reading is seen by the
+	// next Start tick instead of being recorded as loaded
+	version, versioned := "", false
+	if v, ok := s.src.(Versioner); ok {
+		var err error
+		if version, err = v.Version(ctx); err != nil {
+			return cerrors.Wrap(cerrors.Unavailable, "templates.Reload", err)
+		}
+		versioned = true
+	}
+
+	names, err := s.src.List(ctx)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Unavailable, "templates.Reload", err)
+	}
+	sort.Strings(names)
+
+	files := make(map[string][]byte, len(names))
+	hash := sha256.New()
+	for _, name := range names {
+		data, err := s.src.Read(ctx, name)
+		if err != nil {
+			return cerrors.Wrap(cerrors.Unavailable, "templates.Reload: "+name, err)
+		}
+		files[name] = data
+		hash.Write([]byte(name))
+		hash.Write(data)
+	}
+
+	html, text, err := s.parse(names, files)
+	if err != nil {
+		return err
+	}
+
+	if !versioned {
+		version = hex.EncodeToString(hash.Sum(nil))
+	}
+
+	s.mu.Lock()
+	s.html, s.text, s.version = html, text, version
+	s.mu.Unlock()
+	return nil
+}
+
+// Start reloads the templates every Config.Reload when the Source changed, until ctx is done
+func (s *Set) Start(ctx context.Context) error {
+	if s.conf.Reload <= 0 {
+		return nil
+	}
+
+	ticker := s.clock.NewTicker(s.conf.Reload)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			if v, ok := s.src.(Versioner); ok {
+				version, err := v.Version(ctx)
+				s.mu.RLock()
+				same := err == nil && version == s.version
+				s.mu.RUnlock()
+				if same {
+					continue
+				}
+			}
+			if err := s.Reload(ctx); err != nil {
+				log.Println("Erro to reload templates:", err.Error())
+			}
+		}
+	}
+}
+
+func (s *Set) parse(names []string, files map[string][]byte) (map[string]*htmltemplate.Template, map[string]*texttemplate.Template, error) {
+	funcs := map[string]interface{}{"t": s.translate(context.Background())}
+	for k, v := range s.conf.Funcs {
+		funcs[k] = v
+	}
+
+	var shared, pages []string
+	for _, name := range names {
+		dir := strings.SplitN(name, "/", 2)[0]
+		if dir == LAYOUTS_DIR || dir == PARTIALS_DIR {
+			shared = append(shared, name)
+		} else {
+			pages = append(pages, name)
+		}
+	}
+
+	htmlBase := htmltemplate.New("").Funcs(funcs)
+	textBase := texttemplate.New("").Funcs(funcs)
+	for _, name := range shared {
+		var err error
+		if isHTML(name) {
+			_, err = htmlBase.New(name).Parse(string(files[name]))
+		} else {
+			_, err = textBase.New(name).Parse(string(files[name]))
+		}
+		if err != nil {
+			return nil, nil, cerrors.Wrap(cerrors.Invalid, "templates: "+name, err)
+		}
+	}
+
+	html := map[string]*htmltemplate.Template{}
+	text := map[string]*texttemplate.Template{}
+	for _, name := range pages {
+		if isHTML(name) {
+			t, err := htmlBase.Clone()
+			if err == nil {
+				_, err = t.New(name).Parse(string(files[name]))
+			}
+			if err != nil {
+				return nil, nil, cerrors.Wrap(cerrors.Invalid, "templates: "+name, err)
+			}
+			html[name] = t
+		} else {
+			t, err := textBase.Clone()
+			if err == nil {
+				_, err = t.New(name).Parse(string(files[name]))
+			}
+			if err != nil {
+				return nil, nil, cerrors.Wrap(cerrors.Invalid, "templates: "+name, err)
+			}
+			text[name] = t
+		}
+	}
+
+	return html, text, nil
+}
+
+func (s *Set) translate(ctx context.Context) func(key string, args ...interface{}) string {
+	return func(key string, args ...interface{}) string {
+		if s.conf.Translator == nil {
+			return key
+		}
+		return s.conf.Translator.Translate(ctx, key, args...)
+	}
+}
+
+func isHTML(name string) bool {
+	ext := path.Ext(name)
+	return ext == ".html" || ext == ".htm"
+}
+
+func wrapExec(err error) error {
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "templates.Render", err)
+	}
+	return nil
+}
+
+// Blobs is the part of a blob container client BlobSource needs
+type Blobs interface {
+	List(ctx context.Context, prefix string) ([]string, error)
+	Get(ctx context.Context, name string) ([]byte, error)
+}
+
+type blob_source struct {
+	blobs  Blobs
+	prefix string
+}
+
+// BlobSource returns a Source reading the blobs below prefix, names are relative to it
+func BlobSource(blobs Blobs, prefix string) Source {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &blob_source{blobs: blobs, prefix: prefix}
+}
+
+func (bs *blob_source) List(ctx context.Context) ([]string, error) {
+	names, err := bs.blobs.List(ctx, bs.prefix)
+	if err != nil {
+		return nil, err
+	}
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, bs.prefix)
+	}
+	return names, nil
+}
+
+func (bs *blob_source) Read(ctx context.Context, name string) ([]byte, error) {
+	return bs.blobs.Get(ctx, bs.prefix+name)
+}
@@ -0,0 +1,104 @@
+package testsupport
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// Azurite well-known development account
+const (
+	AZURITE_ACCOUNT_NAME = "devstoreaccount1"
+	AZURITE_ACCOUNT_KEY  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+func RabbitMQ() Spec {
+	return Spec{
+		Image: "rabbitmq:3-management-alpine",
+		Port:  "5672/tcp",
+		Ready: func(ctx context.Context, addr string) error {
+			conn, err := amqp.Dial("amqp://guest:guest@" + addr + "/")
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+		Config: func(addr string) map[string]string {
+			return map[string]string{
+				"SRV_RMQ_URI": "amqp://guest:guest@" + addr + "/",
+			}
+		},
+	}
+}
+
+func Postgres() Spec {
+	dsn := func(addr string) string {
+		host, port, _ := net.SplitHostPort(addr)
+		return fmt.Sprintf("host=%s port=%s user=postgres password=postgres dbname=postgres sslmode=disable", host, port)
+	}
+
+	return Spec{
+		Image: "postgres:15-alpine",
+		Port:  "5432/tcp",
+		Env:   map[string]string{"POSTGRES_PASSWORD": "postgres"},
+		Ready: func(ctx context.Context, addr string) error {
+			db, err := sql.Open("postgres", dsn(addr))
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			return db.PingContext(ctx)
+		},
+		Config: func(addr string) map[string]string {
+			host, port, _ := net.SplitHostPort(addr)
+			return map[string]string{
+				"SRV_DB_HOST": host,
+				"SRV_DB_PORT": port,
+				"SRV_DB_USER": "postgres",
+				"SRV_DB_PASS": "postgres",
+				"SRV_DB_NAME": "postgres",
+			}
+		},
+	}
+}
+
+func Redis() Spec {
+	return Spec{
+		Image: "redis:7-alpine",
+		Port:  "6379/tcp",
+		Ready: func(ctx context.Context, addr string) error {
+			rdb := redis.NewClient(&redis.Options{Addr: addr})
+			defer rdb.Close()
+			return rdb.Ping(ctx).Err()
+		},
+		Config: func(addr string) map[string]string {
+			host, port, _ := net.SplitHostPort(addr)
+			return map[string]string{
+				"SRV_RDB_HOST": host,
+				"SRV_RDB_PORT": port,
+			}
+		},
+	}
+}
+
+// Azurite starts the blob service of the storage emulator. Its Env carries the
+// connection string in SRV_AZURE_STORAGE_CONNECTION_STRING.
+func Azurite() Spec {
+	return Spec{
+		Image: "mcr.microsoft.com/azure-storage/azurite",
+		Port:  "10000/tcp",
+		Args:  []string{"azurite-blob", "--blobHost", "0.0.0.0", "--skipApiVersionCheck"},
+		Config: func(addr string) map[string]string {
+			return map[string]string{
+				"SRV_AZURE_STORAGE_CONNECTION_STRING": fmt.Sprintf(
+					"DefaultEndpointsProtocol=http;AccountName=%s;AccountKey=%s;BlobEndpoint=http://%s/%s;",
+					AZURITE_ACCOUNT_NAME, AZURITE_ACCOUNT_KEY, addr, AZURITE_ACCOUNT_NAME),
+			}
+		},
+	}
+}
@@ -0,0 +1,142 @@
+// Package testsupport starts throwaway dependency containers (RabbitMQ, Azurite,
+// Postgres, Redis) through the docker CLI for integration tests, waits for them to be
+// ready and exposes the SRV_* variables the adapters read their configuration from.
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+const DEFAULT_READY_TIMEOUT = 60 * time.Second
+
+type Spec struct {
+	Image string
+	Port  string // container port, ex: "5672/tcp"
+	Env   map[string]string
+	Args  []string // extra arguments for the image entrypoint
+	// Ready is retried until it returns nil, receiving the mapped host address
+	Ready func(ctx context.Context, addr string) error
+	// Config builds the SRV_* variables from the mapped host address
+	Config func(addr string) map[string]string
+}
+
+type Container struct {
+	ID   string
+	Addr string            // host:port reachable from the tests
+	Env  map[string]string // SRV_* variables to configure the adapters
+}
+
+// Start runs the image detached, maps its port to a random host port
+// and waits until Ready succeeds or ctx / DEFAULT_READY_TIMEOUT expires
+func Start(ctx context.Context, spec Spec) (*Container, error) {
+	args := []string{"run", "-d", "-p", "127.0.0.1::" + spec.Port}
+	for k, v := range spec.Env {
+		args = append(args, "-e", k+"="+v)
+	}
+	args = append(args, spec.Image)
+	args = append(args, spec.Args...)
+
+	id, err := docker(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Container{ID: id}
+
+	mapped, err := docker(ctx, "port", id, spec.Port)
+	if err != nil {
+		c.Terminate(context.Background())
+		return nil, err
+	}
+	// docker port may list ipv4 and ipv6 bindings, one per line
+	c.Addr = strings.TrimSpace(strings.Split(mapped, "\n")[0])
+
+	ctx, cancel := context.WithTimeout(ctx, DEFAULT_READY_TIMEOUT)
+	defer cancel()
+
+	ready := spec.Ready
+	if ready == nil {
+		ready = tcpReady
+	}
+
+	for {
+		if err = ready(ctx, c.Addr); err == nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			c.Terminate(context.Background())
+			return nil, fmt.Errorf("container %s not ready: %w", spec.Image, err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+
+	if spec.Config != nil {
+		c.Env = spec.Config(c.Addr)
+	}
+
+	return c, nil
+}
+
+// Terminate removes the container and its volumes
+func (c *Container) Terminate(ctx context.Context) error {
+	_, err := docker(ctx, "rm", "-f", "-v", c.ID)
+	return err
+}
+
+// Setenv sets the SRV_* variables of the container for the duration of the test
+func (c *Container) Setenv(tb testing.TB) {
+	for k, v := range c.Env {
+		tb.Setenv(k, v)
+	}
+}
+
+// Run starts the container, injects its configuration with Setenv and
+// terminates it when the test finishes. It skips the test when docker is missing.
+func Run(tb testing.TB, spec Spec) *Container {
+	tb.Helper()
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		tb.Skip("docker not available, skipping integration test")
+	}
+
+	c, err := Start(context.Background(), spec)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	tb.Cleanup(func() { c.Terminate(context.Background()) })
+	c.Setenv(tb)
+
+	return c
+}
+
+func docker(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", errors.New("docker " + args[0] + ": " + strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+func tcpReady(ctx context.Context, addr string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
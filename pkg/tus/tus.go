@@ -0,0 +1,339 @@
+// Package tus implements the tus.io 1.0 resumable upload protocol (core, creation and
+// termination extensions) over a BlockStager: every PATCH is staged as a block and
+// the blocks are committed when the upload is complete, so browsers can resume
+// multi-GB uploads. Upload state lives in a kv.Store shared by the instances.
+//
+//	tus.RegisterHandlers(r, "/files", tus.New(stager, kv.NewRedis(rdb), tus.Config{}))
+//
+//	POST   {prefix}/       create an upload (Upload-Length, Upload-Metadata), also without the slash
+//	HEAD   {prefix}/{id}   current Upload-Offset
+//	PATCH  {prefix}/{id}   append a chunk at Upload-Offset, chunked bodies are buffered
+//	DELETE {prefix}/{id}   terminate the upload
+package tus
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faelp22/go-commons-libs/core/ctxutil"
+	cerrors "github.com/faelp22/go-commons-libs/core/errors"
+	csync "github.com/faelp22/go-commons-libs/core/sync"
+	"github.com/faelp22/go-commons-libs/pkg/kv"
+	"github.com/gorilla/mux"
+)
+
+const (
+	TUS_VERSION     = "1.0.0"
+	TUS_EXTENSIONS  = "creation,termination"
+	OFFSET_CONTENT  = "application/offset+octet-stream"
+	DEFAULT_MAX     = 5 << 30 // 5GB
+	DEFAULT_CHUNK   = 100 << 20
+	DEFAULT_EXPIRES = 7 * 24 * time.Hour // uncommitted blocks are discarded by Azure after 7 days
+	statePrefix     = "tus:"
+)
+
+// BlockStager stores a file as a list of blocks, like Azure block blobs.
+// Block IDs of a name always have the same length.
+type BlockStager interface {
+	StageBlock(ctx context.Context, name, blockID string, r io.Reader, size int64) error
+	CommitBlockList(ctx context.Context, name string, blockIDs []string, contentType string) error
+	Delete(ctx context.Context, name string) error
+}
+
+type Config struct {
+	MaxSize  int64         // per upload, DEFAULT_MAX when <= 0
+	MaxChunk int64         // per PATCH, DEFAULT_CHUNK when <= 0
+	Expires  time.Duration // unfinished uploads are forgotten after it, DEFAULT_EXPIRES when <= 0
+	Prefix   string        // name prefix inside the BlockStager
+	// OnComplete is called after the blocks of an upload are committed
+	OnComplete func(ctx context.Context, u *Upload)
+}
+
+// Upload is the state of an upload
+type Upload struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	Blocks   []string          `json:"blocks"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Done     bool              `json:"done"`
+}
+
+type Handler struct {
+	stager BlockStager
+	state  kv.Store
+	conf   Config
+	locks  *csync.KeyedMutex
+}
+
+func New(stager BlockStager, state kv.Store, conf Config) *Handler {
+	if conf.MaxSize <= 0 {
+		conf.MaxSize = DEFAULT_MAX
+	}
+	if conf.MaxChunk <= 0 {
+		conf.MaxChunk = DEFAULT_CHUNK
+	}
+	if conf.Expires <= 0 {
+		conf.Expires = DEFAULT_EXPIRES
+	}
+	return &Handler{stager: stager, state: state, conf: conf, locks: csync.NewKeyedMutex()}
+}
+
+// RegisterHandlers exposes h under prefix. Concurrent PATCH of an upload are serialized
+// per instance, clients must not send them to different instances at once.
+func RegisterHandlers(r *mux.Router, prefix string, h *Handler) {
+	s := r.PathPrefix(prefix).Subrouter()
+	s.Use(h.protocol)
+
+	// the prefix is accepted with and without the trailing slash
+	for _, root := range []string{"", "/"} {
+		s.HandleFunc(root, h.options).Methods(http.MethodOptions)
+		s.HandleFunc(root, h.create).Methods(http.MethodPost)
+	}
+	s.HandleFunc("/{id}", h.head).Methods(http.MethodHead)
+	s.HandleFunc("/{id}", h.patch).Methods(http.MethodPatch)
+	s.HandleFunc("/{id}", h.terminate).Methods(http.MethodDelete)
+}
+
+// protocol checks the Tus-Resumable header and sets it on every response
+func (h *Handler) protocol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", TUS_VERSION)
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != TUS_VERSION {
+			w.Header().Set("Tus-Version", TUS_VERSION)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", TUS_VERSION)
+	w.Header().Set("Tus-Extension", TUS_EXTENSIONS)
+	w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.conf.MaxSize, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if length > h.conf.MaxSize {
+		http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	id := ctxutil.NewID()
+	u := &Upload{
+		ID:       id,
+		Name:     path.Join(h.conf.Prefix, id+strings.ToLower(path.Ext(metadata["filename"]))),
+		Length:   length,
+		Metadata: metadata,
+	}
+
+	if length == 0 {
+		if err := h.complete(r.Context(), u); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	if err := h.save(r.Context(), u); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request) {
+	u, err := h.load(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != OFFSET_CONTENT {
+		http.Error(w, "Content-Type must be "+OFFSET_CONTENT, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	unlock := h.locks.Lock(id)
+	defer unlock()
+
+	u, err := h.load(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset != u.Offset || u.Done {
+		w.WriteHeader(http.StatusConflict)
+		return
+	}
+
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	if size < 0 {
+		// chunked request: the block size must be known to stage it, so the chunk is
+		// buffered, up to MaxChunk
+		limit := h.conf.MaxChunk
+		if left := u.Length - u.Offset; left < limit {
+			limit = left
+		}
+		buf := &bytes.Buffer{}
+		if _, err := io.Copy(buf, io.LimitReader(r.Body, limit+1)); err != nil {
+			http.Error(w, "invalid chunk", http.StatusBadRequest)
+			return
+		}
+		body, size = buf, int64(buf.Len())
+	}
+	if size > h.conf.MaxChunk || u.Offset+size > u.Length {
+		http.Error(w, "invalid chunk size", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if size == 0 {
+		w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(u.Blocks))))
+	if err := h.stager.StageBlock(r.Context(), u.Name, blockID, io.LimitReader(body, size), size); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	u.Blocks = append(u.Blocks, blockID)
+	u.Offset += size
+	if u.Offset == u.Length {
+		if err := h.complete(r.Context(), u); err != nil {
+			writeError(w, err)
+			return
+		}
+	}
+
+	if err := h.save(r.Context(), u); err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	unlock := h.locks.Lock(id)
+	defer unlock()
+
+	u, err := h.load(r.Context(), id)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	if u.Done {
+		if err := h.stager.Delete(r.Context(), u.Name); err != nil && !cerrors.Is(err, cerrors.NotFound) {
+			writeError(w, err)
+			return
+		}
+	}
+	if err := h.state.Delete(r.Context(), statePrefix+id); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) complete(ctx context.Context, u *Upload) error {
+	if err := h.stager.CommitBlockList(ctx, u.Name, u.Blocks, u.Metadata["filetype"]); err != nil {
+		return err
+	}
+	u.Done = true
+	if h.conf.OnComplete != nil {
+		h.conf.OnComplete(ctx, u)
+	}
+	return nil
+}
+
+func (h *Handler) load(ctx context.Context, id string) (*Upload, error) {
+	data, err := h.state.Get(ctx, statePrefix+id)
+	if err != nil {
+		return nil, err
+	}
+	u := &Upload{}
+	if err := json.Unmarshal(data, u); err != nil {
+		return nil, cerrors.Wrap(cerrors.Invalid, "tus.load", err)
+	}
+	return u, nil
+}
+
+func (h *Handler) save(ctx context.Context, u *Upload) error {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return cerrors.Wrap(cerrors.Invalid, "tus.save", err)
+	}
+	return h.state.Set(ctx, statePrefix+u.ID, data, h.conf.Expires)
+}
+
+// parseMetadata decodes "key base64value,key2 base64value2"
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		fields := strings.SplitN(pair, " ", 2)
+		value := ""
+		if len(fields) == 2 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return nil, err
+			}
+			value = string(decoded)
+		}
+		metadata[fields[0]] = value
+	}
+	return metadata, nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := cerrors.HTTPStatus(err)
+	if status == http.StatusNotFound {
+		// tus answers 404 for unknown and expired uploads alike
+		w.WriteHeader(status)
+		return
+	}
+	http.Error(w, err.Error(), status)
+}